@@ -0,0 +1,167 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"golang.org/x/sync/singleflight"
+)
+
+// authCacheMetrics is a prefix used for the auth cache metrics
+const authCacheMetrics = "auth_cache"
+
+// authCacheSweepInterval is the interval between two removals of the expired entries
+const authCacheSweepInterval = time.Minute
+
+// BearerAuthenticator checks a bearer for a node and port, and returns the apiToken sent to the node
+type BearerAuthenticator interface {
+	AuthBearer(apiKey string, nodeId string, port int) (result bool, apiToken string, err error)
+}
+
+type AuthCacheConfig struct {
+	// TTL is how long an accepted bearer is cached, 0 disables the cache
+	TTL time.Duration
+	// NegativeTTL is how long a rejected bearer is cached, 0 disables the negative cache
+	NegativeTTL time.Duration
+	// StaleTTL is how long an expired accepted bearer is still served while it is refreshed
+	// in the background, so requests keep working when the hub is unreachable. 0 disables it
+	StaleTTL time.Duration
+}
+
+type authCacheEntry struct {
+	result   bool
+	apiToken string
+	expires  time.Time
+}
+
+// AuthCache caches the results of AuthBearer and deduplicates the concurrent lookups
+type AuthCache struct {
+	backend Authenticator
+	config  *AuthCacheConfig
+
+	lock      sync.RWMutex
+	entries   map[string]*authCacheEntry
+	lastSweep time.Time
+
+	group singleflight.Group
+}
+
+func NewAuthCache(backend Authenticator, config *AuthCacheConfig) *AuthCache {
+	return &AuthCache{
+		backend:   backend,
+		config:    config,
+		entries:   make(map[string]*authCacheEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+func (c *AuthCache) AuthBearer(apiKey string, nodeId string, port int) (bool, string, error) {
+	return c.authBearerAt(time.Now(), apiKey, nodeId, port)
+}
+
+// cacheKey hashes the API key, so the keys are not kept in memory after they are checked
+func cacheKey(apiKey string, nodeId string, port int) string {
+	sum := sha256.Sum256([]byte(apiKey))
+
+	return fmt.Sprintf("%s/%s/%d", hex.EncodeToString(sum[:16]), nodeId, port)
+}
+
+func (c *AuthCache) authBearerAt(now time.Time, apiKey string, nodeId string, port int) (bool, string, error) {
+	key := cacheKey(apiKey, nodeId, port)
+
+	c.lock.RLock()
+	entry, ok := c.entries[key]
+	c.lock.RUnlock()
+
+	if ok {
+		if now.Before(entry.expires) {
+			metrics.IncrCounter([]string{authCacheMetrics, "hit"}, 1)
+
+			return entry.result, entry.apiToken, nil
+		}
+
+		if entry.result && now.Before(entry.expires.Add(c.config.StaleTTL)) {
+			metrics.IncrCounter([]string{authCacheMetrics, "stale_hit"}, 1)
+
+			go c.lookup(now, key, apiKey, nodeId, port) //nolint:errcheck
+
+			return entry.result, entry.apiToken, nil
+		}
+	}
+
+	metrics.IncrCounter([]string{authCacheMetrics, "miss"}, 1)
+
+	return c.lookup(now, key, apiKey, nodeId, port)
+}
+
+// ValidateBearer is not cached, it is called by the edge node for the apiToken
+func (c *AuthCache) ValidateBearer(bearer string) (bool, error) {
+	return c.backend.ValidateBearer(bearer)
+}
+
+// lookup calls the backend once for all the concurrent requests of the same key
+func (c *AuthCache) lookup(now time.Time, key string, apiKey string, nodeId string, port int) (bool, string, error) {
+	v, err, _ := c.group.Do(key, func() (interface{}, error) {
+		result, apiToken, err := c.backend.AuthBearer(apiKey, nodeId, port)
+		if err != nil {
+			metrics.IncrCounter([]string{authCacheMetrics, "backend_error"}, 1)
+
+			// errors are not cached, a stale entry is kept until it expires
+			return nil, err
+		}
+
+		c.store(now, key, result, apiToken)
+
+		return &authCacheEntry{result: result, apiToken: apiToken}, nil
+	})
+	if err != nil {
+		return false, "", err
+	}
+
+	entry, _ := v.(*authCacheEntry)
+
+	return entry.result, entry.apiToken, nil
+}
+
+func (c *AuthCache) store(now time.Time, key string, result bool, apiToken string) {
+	ttl := c.config.TTL
+	if !result {
+		ttl = c.config.NegativeTTL
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.sweep(now)
+
+	if ttl <= 0 {
+		delete(c.entries, key)
+
+		return
+	}
+
+	c.entries[key] = &authCacheEntry{
+		result:   result,
+		apiToken: apiToken,
+		expires:  now.Add(ttl),
+	}
+	metrics.SetGauge([]string{authCacheMetrics, "entries"}, float32(len(c.entries)))
+}
+
+// sweep removes the entries which can't be served anymore
+func (c *AuthCache) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < authCacheSweepInterval {
+		return
+	}
+	c.lastSweep = now
+
+	for key, entry := range c.entries {
+		if now.After(entry.expires.Add(c.config.StaleTTL)) {
+			delete(c.entries, key)
+		}
+	}
+}
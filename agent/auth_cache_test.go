@@ -0,0 +1,241 @@
+package agent
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testBackend struct {
+	calls  int32
+	result bool
+	err    error
+	// block holds the calls until it is closed
+	block chan struct{}
+}
+
+func (b *testBackend) AuthBearer(apiKey string, nodeId string, port int) (bool, string, error) {
+	atomic.AddInt32(&b.calls, 1)
+
+	if b.block != nil {
+		<-b.block
+	}
+
+	if b.err != nil {
+		return false, "", b.err
+	}
+
+	return b.result, "token-" + apiKey, nil
+}
+
+func (b *testBackend) ValidateBearer(bearer string) (bool, error) {
+	return b.result, nil
+}
+
+func (b *testBackend) callCount() int {
+	return int(atomic.LoadInt32(&b.calls))
+}
+
+func TestAuthCacheTTL(t *testing.T) {
+	backend := &testBackend{result: true}
+	cache := NewAuthCache(backend, &AuthCacheConfig{TTL: time.Minute})
+
+	now := time.Now()
+
+	ok, apiToken, err := cache.authBearerAt(now, "key-1", "node-1", 9527)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "token-key-1", apiToken)
+
+	ok, apiToken, err = cache.authBearerAt(now.Add(30*time.Second), "key-1", "node-1", 9527)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "token-key-1", apiToken)
+	assert.Equal(t, 1, backend.callCount())
+
+	// another node or port is another entry
+	_, _, _ = cache.authBearerAt(now, "key-1", "node-2", 9527)
+	_, _, _ = cache.authBearerAt(now, "key-1", "node-1", 8080)
+	assert.Equal(t, 3, backend.callCount())
+
+	// the entry has expired
+	_, _, _ = cache.authBearerAt(now.Add(2*time.Minute), "key-1", "node-1", 9527)
+	assert.Equal(t, 4, backend.callCount())
+}
+
+func TestAuthCacheNegativeTTL(t *testing.T) {
+	backend := &testBackend{result: false}
+	cache := NewAuthCache(backend, &AuthCacheConfig{TTL: time.Minute, NegativeTTL: 10 * time.Second})
+
+	now := time.Now()
+
+	ok, _, err := cache.authBearerAt(now, "key-1", "node-1", 9527)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, _, _ = cache.authBearerAt(now.Add(5*time.Second), "key-1", "node-1", 9527)
+	assert.False(t, ok)
+	assert.Equal(t, 1, backend.callCount())
+
+	// the rejection has expired, the key is accepted now
+	backend.result = true
+	ok, _, _ = cache.authBearerAt(now.Add(11*time.Second), "key-1", "node-1", 9527)
+	assert.True(t, ok)
+	assert.Equal(t, 2, backend.callCount())
+}
+
+func TestAuthCacheNegativeDisabled(t *testing.T) {
+	backend := &testBackend{result: false}
+	cache := NewAuthCache(backend, &AuthCacheConfig{TTL: time.Minute})
+
+	now := time.Now()
+	_, _, _ = cache.authBearerAt(now, "key-1", "node-1", 9527)
+	_, _, _ = cache.authBearerAt(now, "key-1", "node-1", 9527)
+
+	assert.Equal(t, 2, backend.callCount())
+	assert.Empty(t, cache.entries)
+}
+
+func TestAuthCacheErrorsNotCached(t *testing.T) {
+	backend := &testBackend{err: errors.New("hub unreachable")}
+	cache := NewAuthCache(backend, &AuthCacheConfig{TTL: time.Minute, NegativeTTL: time.Minute})
+
+	now := time.Now()
+
+	_, _, err := cache.authBearerAt(now, "key-1", "node-1", 9527)
+	assert.Error(t, err)
+
+	backend.err = nil
+	backend.result = true
+
+	ok, _, err := cache.authBearerAt(now, "key-1", "node-1", 9527)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 2, backend.callCount())
+}
+
+func TestAuthCacheStaleWhileRefresh(t *testing.T) {
+	backend := &testBackend{result: true}
+	cache := NewAuthCache(backend, &AuthCacheConfig{TTL: time.Minute, StaleTTL: time.Minute})
+
+	now := time.Now()
+	_, _, _ = cache.authBearerAt(now, "key-1", "node-1", 9527)
+
+	// the hub is down, the expired entry is still served and refreshed in the background
+	backend.err = errors.New("hub unreachable")
+
+	ok, apiToken, err := cache.authBearerAt(now.Add(90*time.Second), "key-1", "node-1", 9527)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "token-key-1", apiToken)
+	assert.Eventually(t, func() bool { return backend.callCount() == 2 }, time.Second, time.Millisecond)
+
+	// past the stale TTL, the error of the backend is returned
+	_, _, err = cache.authBearerAt(now.Add(3*time.Minute), "key-1", "node-1", 9527)
+	assert.Error(t, err)
+}
+
+func TestAuthCacheStaleRefreshUpdates(t *testing.T) {
+	backend := &testBackend{result: true}
+	cache := NewAuthCache(backend, &AuthCacheConfig{TTL: time.Minute, StaleTTL: time.Minute})
+
+	now := time.Now()
+	_, _, _ = cache.authBearerAt(now, "key-1", "node-1", 9527)
+
+	// the key was revoked, the stale entry is served once and replaced by the rejection
+	backend.result = false
+
+	stale := now.Add(90 * time.Second)
+	ok, _, _ := cache.authBearerAt(stale, "key-1", "node-1", 9527)
+	assert.True(t, ok)
+
+	assert.Eventually(t, func() bool {
+		cache.lock.RLock()
+		defer cache.lock.RUnlock()
+
+		return len(cache.entries) == 0
+	}, time.Second, time.Millisecond)
+
+	ok, _, _ = cache.authBearerAt(stale, "key-1", "node-1", 9527)
+	assert.False(t, ok)
+}
+
+func TestAuthCacheStaleNotForRejections(t *testing.T) {
+	backend := &testBackend{result: false}
+	cache := NewAuthCache(backend, &AuthCacheConfig{TTL: time.Minute, NegativeTTL: time.Minute, StaleTTL: time.Hour})
+
+	now := time.Now()
+	_, _, _ = cache.authBearerAt(now, "key-1", "node-1", 9527)
+
+	backend.result = true
+	ok, _, _ := cache.authBearerAt(now.Add(2*time.Minute), "key-1", "node-1", 9527)
+	assert.True(t, ok)
+	assert.Equal(t, 2, backend.callCount())
+}
+
+func TestAuthCacheSingleflight(t *testing.T) {
+	backend := &testBackend{result: true, block: make(chan struct{})}
+	cache := NewAuthCache(backend, &AuthCacheConfig{TTL: time.Minute})
+
+	const requests = 10
+
+	var wg sync.WaitGroup
+
+	results := make(chan bool, requests)
+
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			ok, _, _ := cache.AuthBearer("key-1", "node-1", 9527)
+			results <- ok
+		}()
+	}
+
+	// wait for the first call to reach the backend, then let the others join it
+	assert.Eventually(t, func() bool { return backend.callCount() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	close(backend.block)
+
+	wg.Wait()
+	close(results)
+
+	for ok := range results {
+		assert.True(t, ok)
+	}
+
+	assert.Equal(t, 1, backend.callCount())
+}
+
+func TestAuthCacheHashesKeys(t *testing.T) {
+	cache := NewAuthCache(&testBackend{result: true}, &AuthCacheConfig{TTL: time.Minute})
+
+	_, _, _ = cache.AuthBearer("sk-secret", "node-1", 9527)
+
+	require.Len(t, cache.entries, 1)
+
+	for key := range cache.entries {
+		assert.NotContains(t, key, "sk-secret")
+		assert.Equal(t, cacheKey("sk-secret", "node-1", 9527), key)
+	}
+}
+
+func TestAuthCacheSweep(t *testing.T) {
+	cache := NewAuthCache(&testBackend{result: true}, &AuthCacheConfig{TTL: time.Minute, StaleTTL: time.Minute})
+
+	now := time.Now()
+	_, _, _ = cache.authBearerAt(now, "key-1", "node-1", 9527)
+
+	// the entry can't be served anymore and is removed by the next store
+	_, _, _ = cache.authBearerAt(now.Add(5*time.Minute), "key-2", "node-1", 9527)
+
+	assert.Len(t, cache.entries, 1)
+	assert.Contains(t, cache.entries, cacheKey("key-2", "node-1", 9527))
+}
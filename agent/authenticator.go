@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	AuthBackendHub    = "hub"
+	AuthBackendStatic = "static"
+	AuthBackendJWT    = "jwt"
+)
+
+// Authenticator checks the bearers on both sides of the transparent proxy:
+// AuthBearer on the relay for the target node and port, ValidateBearer on the edge node
+// for the apiToken returned by AuthBearer
+type Authenticator interface {
+	BearerAuthenticator
+	ValidateBearer(bearer string) (bool, error)
+}
+
+// HubAuthenticator checks the bearers with the hub api and the app
+type HubAuthenticator struct {
+	authAgent *AuthAgent
+	appAgent  *AppAgent
+}
+
+func NewHubAuthenticator(authAgent *AuthAgent, appAgent *AppAgent) *HubAuthenticator {
+	return &HubAuthenticator{
+		authAgent: authAgent,
+		appAgent:  appAgent,
+	}
+}
+
+func (h *HubAuthenticator) AuthBearer(apiKey string, nodeId string, port int) (bool, string, error) {
+	return h.authAgent.AuthBearer(apiKey, nodeId, port)
+}
+
+func (h *HubAuthenticator) ValidateBearer(bearer string) (bool, error) {
+	return h.appAgent.ValidateApiKey(bearer)
+}
+
+// AuthScope restricts the nodes and ports a bearer can reach, empty lists allow everything
+type AuthScope struct {
+	// NodeIDs are globs on the NodeID, e.g. 16Uiu2HAm*
+	NodeIDs []string `json:"node_ids,omitempty" yaml:"node_ids,omitempty"`
+	Ports   []int    `json:"ports,omitempty" yaml:"ports,omitempty"`
+}
+
+func (s *AuthScope) allows(nodeId string, port int) bool {
+	if len(s.NodeIDs) > 0 {
+		found := false
+		for _, pattern := range s.NodeIDs {
+			if ok, _ := path.Match(pattern, nodeId); ok {
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if len(s.Ports) > 0 {
+		for _, p := range s.Ports {
+			if p == port {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// readAuthFile reads a .json, .yaml or .yml file
+func readAuthFile(filePath string, v interface{}) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case strings.HasSuffix(filePath, ".json"):
+		return json.Unmarshal(data, v)
+	case strings.HasSuffix(filePath, ".yaml"), strings.HasSuffix(filePath, ".yml"):
+		return yaml.Unmarshal(data, v)
+	default:
+		return fmt.Errorf("suffix of %s is neither json, yaml nor yml", filePath)
+	}
+}
@@ -0,0 +1,142 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+// jwtLeeway is the clock skew accepted on the exp and nbf claims
+const jwtLeeway = time.Minute
+
+var jwtAlgorithms = []jose.SignatureAlgorithm{jose.HS256, jose.ES256}
+
+// JWTConfig defines how the tokens are verified
+type JWTConfig struct {
+	// JWKSFile is the local JSON Web Key Set holding the HS256 (oct) and ES256 (EC P-256) keys
+	JWKSFile string
+	// Issuer is the expected iss claim, not checked if empty
+	Issuer string
+	// Audience is the expected aud claim, not checked if empty
+	Audience string
+	// AllowNoExpiry accepts the tokens without an exp claim, they are valid until the key is removed
+	AllowNoExpiry bool
+}
+
+// jwtClaims are the registered claims and the scope of the token
+type jwtClaims struct {
+	jwt.Claims
+	AuthScope
+}
+
+// JWTAuthenticator verifies the bearers as JWTs signed by a key of a local JWKS.
+// The token is forwarded to the edge node which verifies it with the same JWKS.
+type JWTAuthenticator struct {
+	config *JWTConfig
+	keys   *jose.JSONWebKeySet
+}
+
+func NewJWTAuthenticator(config *JWTConfig) (*JWTAuthenticator, error) {
+	data, err := os.ReadFile(config.JWKSFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jwks file: %w", err)
+	}
+
+	keys := &jose.JSONWebKeySet{}
+	if err := json.Unmarshal(data, keys); err != nil {
+		return nil, fmt.Errorf("failed to parse jwks file: %w", err)
+	}
+
+	if len(keys.Keys) == 0 {
+		return nil, errors.New("jwks file has no key")
+	}
+
+	return &JWTAuthenticator{
+		config: config,
+		keys:   keys,
+	}, nil
+}
+
+// verify checks the signature and the registered claims of the token
+func (a *JWTAuthenticator) verify(token string) (*jwtClaims, error) {
+	parsed, err := jwt.ParseSigned(token, jwtAlgorithms)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(parsed.Headers) != 1 {
+		return nil, errors.New("jwt must have a single signature")
+	}
+
+	header := parsed.Headers[0]
+
+	candidates := a.keys.Keys
+	if header.KeyID != "" {
+		candidates = a.keys.Key(header.KeyID)
+	}
+
+	claims := &jwtClaims{}
+	verified := false
+
+	for _, key := range candidates {
+		if key.Algorithm != "" && key.Algorithm != header.Algorithm {
+			continue
+		}
+
+		if err := parsed.Claims(key.Key, claims); err == nil {
+			verified = true
+
+			break
+		}
+	}
+
+	if !verified {
+		return nil, errors.New("jwt signature is not valid")
+	}
+
+	// a leaked token without exp would be valid forever
+	if claims.Expiry == nil && !a.config.AllowNoExpiry {
+		return nil, errors.New("jwt has no exp claim")
+	}
+
+	expected := jwt.Expected{
+		Issuer: a.config.Issuer,
+		Time:   time.Now(),
+	}
+	if a.config.Audience != "" {
+		expected.AnyAudience = jwt.Audience{a.config.Audience}
+	}
+
+	if err := claims.ValidateWithLeeway(expected, jwtLeeway); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (a *JWTAuthenticator) AuthBearer(apiKey string, nodeId string, port int) (bool, string, error) {
+	claims, err := a.verify(apiKey)
+	if err != nil {
+		// an invalid token is a rejected bearer, not a backend failure
+		return false, "", nil
+	}
+
+	if !claims.allows(nodeId, port) {
+		return false, "", nil
+	}
+
+	return true, apiKey, nil
+}
+
+func (a *JWTAuthenticator) ValidateBearer(bearer string) (bool, error) {
+	if _, err := a.verify(bearer); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,208 @@
+package agent
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testJWKS struct {
+	hmacKey []byte
+	ecKey   *ecdsa.PrivateKey
+	path    string
+}
+
+func newTestJWKS(t *testing.T) *testJWKS {
+	t.Helper()
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	keys := &testJWKS{
+		hmacKey: []byte("0123456789abcdef0123456789abcdef"),
+		ecKey:   ecKey,
+		path:    filepath.Join(t.TempDir(), "jwks.json"),
+	}
+
+	data, err := json.Marshal(&jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: keys.hmacKey, KeyID: "hs", Algorithm: string(jose.HS256)},
+		{Key: ecKey.Public(), KeyID: "es", Algorithm: string(jose.ES256)},
+	}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(keys.path, data, 0600))
+
+	return keys
+}
+
+func (k *testJWKS) sign(t *testing.T, alg jose.SignatureAlgorithm, claims interface{}) string {
+	t.Helper()
+
+	var key interface{} = k.hmacKey
+	kid := "hs"
+
+	if alg == jose.ES256 {
+		key, kid = k.ecKey, "es"
+	}
+
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: alg, Key: key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", kid),
+	)
+	require.NoError(t, err)
+
+	token, err := jwt.Signed(signer).Claims(claims).Serialize()
+	require.NoError(t, err)
+
+	return token
+}
+
+func TestJWTAuthenticatorAlgorithms(t *testing.T) {
+	keys := newTestJWKS(t)
+
+	auth, err := NewJWTAuthenticator(&JWTConfig{JWKSFile: keys.path})
+	require.NoError(t, err)
+
+	for _, alg := range jwtAlgorithms {
+		t.Run(string(alg), func(t *testing.T) {
+			token := keys.sign(t, alg, jwt.Claims{Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour))})
+
+			ok, apiToken, err := auth.AuthBearer(token, "QmAbc", 9527)
+			require.NoError(t, err)
+			assert.True(t, ok)
+			// the token itself is forwarded to the edge node
+			assert.Equal(t, token, apiToken)
+
+			ok, err = auth.ValidateBearer(token)
+			require.NoError(t, err)
+			assert.True(t, ok)
+		})
+	}
+}
+
+func TestJWTAuthenticatorTimeClaims(t *testing.T) {
+	keys := newTestJWKS(t)
+
+	auth, err := NewJWTAuthenticator(&JWTConfig{JWKSFile: keys.path})
+	require.NoError(t, err)
+
+	now := time.Now()
+
+	testCases := []struct {
+		name   string
+		claims jwt.Claims
+		result bool
+	}{
+		{"valid", jwt.Claims{NotBefore: jwt.NewNumericDate(now.Add(-time.Hour)), Expiry: jwt.NewNumericDate(now.Add(time.Hour))}, true},
+		{"expired within the leeway", jwt.Claims{Expiry: jwt.NewNumericDate(now.Add(-jwtLeeway / 2))}, true},
+		{"expired past the leeway", jwt.Claims{Expiry: jwt.NewNumericDate(now.Add(-2 * jwtLeeway))}, false},
+		{"not yet valid within the leeway", jwt.Claims{NotBefore: jwt.NewNumericDate(now.Add(jwtLeeway / 2)), Expiry: jwt.NewNumericDate(now.Add(time.Hour))}, true},
+		{"not yet valid past the leeway", jwt.Claims{NotBefore: jwt.NewNumericDate(now.Add(2 * jwtLeeway)), Expiry: jwt.NewNumericDate(now.Add(time.Hour))}, false},
+		{"no expiry", jwt.Claims{NotBefore: jwt.NewNumericDate(now.Add(-time.Hour))}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			token := keys.sign(t, jose.HS256, tc.claims)
+
+			ok, _, err := auth.AuthBearer(token, "QmAbc", 9527)
+			require.NoError(t, err)
+			assert.Equal(t, tc.result, ok)
+
+			ok, err = auth.ValidateBearer(token)
+			assert.Equal(t, tc.result, ok)
+			assert.Equal(t, tc.result, err == nil)
+		})
+	}
+}
+
+func TestJWTAuthenticatorAllowNoExpiry(t *testing.T) {
+	keys := newTestJWKS(t)
+
+	auth, err := NewJWTAuthenticator(&JWTConfig{JWKSFile: keys.path, AllowNoExpiry: true})
+	require.NoError(t, err)
+
+	// the tokens without exp are accepted once the operator opts in
+	ok, _, err := auth.AuthBearer(keys.sign(t, jose.HS256, jwt.Claims{Subject: "alice"}), "QmAbc", 9527)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// the expired tokens are still rejected
+	ok, _, err = auth.AuthBearer(keys.sign(t, jose.HS256, jwt.Claims{Expiry: jwt.NewNumericDate(time.Now().Add(-time.Hour))}), "QmAbc", 9527)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestJWTAuthenticatorClaims(t *testing.T) {
+	keys := newTestJWKS(t)
+
+	auth, err := NewJWTAuthenticator(&JWTConfig{JWKSFile: keys.path, Issuer: "hub", Audience: "edge"})
+	require.NoError(t, err)
+
+	expiry := jwt.NewNumericDate(time.Now().Add(time.Hour))
+
+	scoped := &jwtClaims{
+		Claims:    jwt.Claims{Issuer: "hub", Audience: jwt.Audience{"edge"}, Expiry: expiry},
+		AuthScope: AuthScope{NodeIDs: []string{"16Uiu2HAm*"}, Ports: []int{9527}},
+	}
+	token := keys.sign(t, jose.ES256, scoped)
+
+	ok, _, _ := auth.AuthBearer(token, "16Uiu2HAmabc", 9527)
+	assert.True(t, ok)
+
+	ok, _, _ = auth.AuthBearer(token, "QmAbc", 9527)
+	assert.False(t, ok)
+
+	ok, _, _ = auth.AuthBearer(token, "16Uiu2HAmabc", 8080)
+	assert.False(t, ok)
+
+	// wrong issuer and audience
+	ok, _, _ = auth.AuthBearer(keys.sign(t, jose.HS256, jwt.Claims{Issuer: "other", Audience: jwt.Audience{"edge"}, Expiry: expiry}), "QmAbc", 9527)
+	assert.False(t, ok)
+
+	ok, _, _ = auth.AuthBearer(keys.sign(t, jose.HS256, jwt.Claims{Issuer: "hub", Audience: jwt.Audience{"other"}, Expiry: expiry}), "QmAbc", 9527)
+	assert.False(t, ok)
+}
+
+func TestJWTAuthenticatorSignature(t *testing.T) {
+	keys := newTestJWKS(t)
+
+	auth, err := NewJWTAuthenticator(&JWTConfig{JWKSFile: keys.path})
+	require.NoError(t, err)
+
+	expiry := jwt.NewNumericDate(time.Now().Add(time.Hour))
+
+	// a token signed by a key outside the set
+	other := newTestJWKS(t)
+	ok, _, err := auth.AuthBearer(other.sign(t, jose.ES256, jwt.Claims{Expiry: expiry}), "QmAbc", 9527)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// a tampered token
+	token := keys.sign(t, jose.HS256, jwt.Claims{Subject: "alice", Expiry: expiry})
+	ok, _, _ = auth.AuthBearer(token[:len(token)-2]+"xx", "QmAbc", 9527)
+	assert.False(t, ok)
+
+	ok, _, _ = auth.AuthBearer("not-a-jwt", "QmAbc", 9527)
+	assert.False(t, ok)
+}
+
+func TestJWTAuthenticatorJWKSFile(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(filePath, []byte(`{"keys": []}`), 0600))
+
+	_, err := NewJWTAuthenticator(&JWTConfig{JWKSFile: filePath})
+	assert.ErrorContains(t, err, "no key")
+
+	_, err = NewJWTAuthenticator(&JWTConfig{JWKSFile: filepath.Join(t.TempDir(), "missing.json")})
+	assert.Error(t, err)
+}
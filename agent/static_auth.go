@@ -0,0 +1,87 @@
+package agent
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// StaticKey is an API key of the static keys file
+type StaticKey struct {
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	Key  string `json:"key" yaml:"key"`
+	// ApiToken is sent to the edge node instead of the key, the key is sent if empty
+	ApiToken  string    `json:"api_token,omitempty" yaml:"api_token,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+	AuthScope `json:",inline" yaml:",inline"`
+}
+
+func (k *StaticKey) token() string {
+	if k.ApiToken != "" {
+		return k.ApiToken
+	}
+
+	return k.Key
+}
+
+func (k *StaticKey) expired(now time.Time) bool {
+	return !k.ExpiresAt.IsZero() && now.After(k.ExpiresAt)
+}
+
+type staticKeysFile struct {
+	Keys []*StaticKey `json:"keys" yaml:"keys"`
+}
+
+// StaticAuthenticator checks the bearers against a local API keys file
+type StaticAuthenticator struct {
+	keys []*StaticKey
+}
+
+// NewStaticAuthenticator reads the API keys from a .json, .yaml or .yml file
+func NewStaticAuthenticator(filePath string) (*StaticAuthenticator, error) {
+	file := &staticKeysFile{}
+	if err := readAuthFile(filePath, file); err != nil {
+		return nil, fmt.Errorf("failed to read keys file: %w", err)
+	}
+
+	for i, key := range file.Keys {
+		if key.Key == "" {
+			return nil, fmt.Errorf("keys file: key %d is empty", i)
+		}
+	}
+
+	return &StaticAuthenticator{keys: file.Keys}, nil
+}
+
+// find compares the bearer with every key in constant time
+func (s *StaticAuthenticator) find(bearer string, match func(key *StaticKey) string) *StaticKey {
+	var found *StaticKey
+
+	for _, key := range s.keys {
+		if subtle.ConstantTimeCompare([]byte(match(key)), []byte(bearer)) == 1 && found == nil {
+			found = key
+		}
+	}
+
+	return found
+}
+
+func (s *StaticAuthenticator) AuthBearer(apiKey string, nodeId string, port int) (bool, string, error) {
+	key := s.find(apiKey, func(key *StaticKey) string { return key.Key })
+	if key == nil || key.expired(time.Now()) || !key.allows(nodeId, port) {
+		return false, "", nil
+	}
+
+	return true, key.token(), nil
+}
+
+func (s *StaticAuthenticator) ValidateBearer(bearer string) (bool, error) {
+	if bearer == "" {
+		return false, errors.New("empty bearer")
+	}
+
+	key := s.find(bearer, (*StaticKey).token)
+
+	return key != nil && !key.expired(time.Now()), nil
+}
@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeKeysFile(t *testing.T, name string, content string) string {
+	t.Helper()
+
+	filePath := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(filePath, []byte(content), 0600))
+
+	return filePath
+}
+
+func TestStaticAuthenticator(t *testing.T) {
+	expired := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	valid := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+	static, err := NewStaticAuthenticator(writeKeysFile(t, "keys.yaml", `
+keys:
+  - name: alice
+    key: sk-alice
+    api_token: tk-alice
+    node_ids: ["16Uiu2HAm*"]
+    ports: [9527]
+    expires_at: `+valid+`
+  - name: bob
+    key: sk-bob
+  - name: carol
+    key: sk-carol
+    expires_at: `+expired+`
+`))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		apiKey   string
+		nodeId   string
+		port     int
+		result   bool
+		apiToken string
+	}{
+		{"scoped key", "sk-alice", "16Uiu2HAmabc", 9527, true, "tk-alice"},
+		{"node outside the globs", "sk-alice", "QmAbc", 9527, false, ""},
+		{"port outside the scope", "sk-alice", "16Uiu2HAmabc", 8080, false, ""},
+		{"unscoped key sends itself", "sk-bob", "QmAbc", 8080, true, "sk-bob"},
+		{"expired key", "sk-carol", "QmAbc", 9527, false, ""},
+		{"unknown key", "sk-dave", "QmAbc", 9527, false, ""},
+		{"empty key", "", "QmAbc", 9527, false, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, apiToken, err := static.AuthBearer(tc.apiKey, tc.nodeId, tc.port)
+			require.NoError(t, err)
+			assert.Equal(t, tc.result, result)
+			assert.Equal(t, tc.apiToken, apiToken)
+		})
+	}
+}
+
+func TestStaticAuthenticatorValidateBearer(t *testing.T) {
+	expired := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	static, err := NewStaticAuthenticator(writeKeysFile(t, "keys.json", `{"keys": [
+		{"key": "sk-alice", "api_token": "tk-alice"},
+		{"key": "sk-bob"},
+		{"key": "sk-carol", "expires_at": "`+expired+`"}
+	]}`))
+	require.NoError(t, err)
+
+	// the edge node gets the api token, or the key when there is no token
+	ok, err := static.ValidateBearer("tk-alice")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, _ = static.ValidateBearer("sk-bob")
+	assert.True(t, ok)
+
+	ok, _ = static.ValidateBearer("sk-alice")
+	assert.False(t, ok)
+
+	ok, _ = static.ValidateBearer("sk-carol")
+	assert.False(t, ok)
+
+	_, err = static.ValidateBearer("")
+	assert.Error(t, err)
+}
+
+func TestStaticAuthenticatorFile(t *testing.T) {
+	_, err := NewStaticAuthenticator(writeKeysFile(t, "keys.yaml", "keys:\n  - name: empty\n"))
+	assert.ErrorContains(t, err, "key 0 is empty")
+
+	_, err = NewStaticAuthenticator(writeKeysFile(t, "keys.txt", "keys: []"))
+	assert.Error(t, err)
+
+	_, err = NewStaticAuthenticator(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestAuthScopeGlobs(t *testing.T) {
+	scope := &AuthScope{NodeIDs: []string{"16Uiu2HAm*", "QmExact"}}
+
+	assert.True(t, scope.allows("16Uiu2HAmabc", 1))
+	assert.True(t, scope.allows("QmExact", 1))
+	assert.False(t, scope.allows("QmExact2", 1))
+	assert.False(t, scope.allows("16Uiu2HAk", 1))
+
+	// an empty scope allows everything
+	assert.True(t, (&AuthScope{}).allows("QmAbc", 1))
+}
@@ -6,12 +6,14 @@ import (
 	minerOp "github.com/EdgeMatrixChain/edge-matrix-computing/miner/proto"
 	"net"
 	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/EdgeMatrixChain/edge-matrix-computing/command"
 	"github.com/EdgeMatrixChain/edge-matrix-computing/server"
 	"github.com/EdgeMatrixChain/edge-matrix-computing/server/proto"
-	"github.com/EdgeMatrixChain/edge-matrix-core/core/helper/common"
 
 	"github.com/ryanuber/columnize"
 	"github.com/spf13/cobra"
@@ -34,14 +36,31 @@ const (
 	AllInterfacesBinding IPBinding = "0.0.0.0"
 )
 
+// terminationSignals shut the node down, SIGHUP is not one of them since it reloads the proxy policy
+var terminationSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
 // HandleSignals is a helper method for handling signals sent to the console
 // Like stop, error, etc.
 func HandleSignals(
 	closeFn func(),
 	outputter command.OutputFormatter,
 ) error {
-	signalCh := common.GetTerminationSignalCh()
-	sig := <-signalCh
+	signalCh := make(chan os.Signal, 1)
+	signal.Notify(signalCh, terminationSignals...)
+	defer signal.Stop(signalCh)
+
+	// SIGHUP is caught, so it doesn't terminate the node when no policy is watched
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	defer signal.Stop(hupCh)
+
+	var sig os.Signal
+	for sig == nil {
+		select {
+		case <-hupCh:
+		case sig = <-signalCh:
+		}
+	}
 
 	closeMessage := fmt.Sprintf("\n[SIGNAL] Caught signal: %v\n", sig)
 	closeMessage += "Gracefully shutting down client...\n"
@@ -0,0 +1,80 @@
+//go:build !windows
+
+package helper
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/EdgeMatrixChain/edge-matrix-computing/command"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testOutputter struct {
+	results []command.CommandResult
+}
+
+func (o *testOutputter) SetError(err error) {}
+
+func (o *testOutputter) SetCommandResult(result command.CommandResult) {
+	o.results = append(o.results, result)
+}
+
+func (o *testOutputter) WriteOutput() {}
+
+func (o *testOutputter) WriteCommandResult(result command.CommandResult) {}
+
+func (o *testOutputter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func TestHandleSignalsSIGHUP(t *testing.T) {
+	// the test process is not killed if a signal is sent before HandleSignals catches it
+	caught := make(chan os.Signal, 4)
+	signal.Notify(caught, syscall.SIGHUP, syscall.SIGTERM)
+	defer signal.Stop(caught)
+
+	closed := make(chan struct{})
+	done := make(chan error, 1)
+	outputter := &testOutputter{}
+
+	go func() {
+		done <- HandleSignals(func() { close(closed) }, outputter)
+	}()
+
+	// SIGHUP reloads the policy, the node keeps running
+	for i := 0; i < 3; i++ {
+		time.Sleep(50 * time.Millisecond)
+		require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+	}
+
+	select {
+	case err := <-done:
+		t.Fatalf("the node was shut down by SIGHUP: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	select {
+	case <-closed:
+		t.Fatal("the node was closed by SIGHUP")
+	default:
+	}
+
+	// SIGTERM still shuts it down gracefully
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGTERM))
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the node was not shut down by SIGTERM")
+	}
+
+	<-closed
+	require.Len(t, outputter.results, 1)
+	assert.Contains(t, outputter.results[0].GetOutput(), "terminated")
+}
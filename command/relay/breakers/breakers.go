@@ -0,0 +1,43 @@
+package breakers
+
+import (
+	"context"
+	"github.com/EdgeMatrixChain/edge-matrix-computing/command"
+	"github.com/EdgeMatrixChain/edge-matrix-computing/command/helper"
+	"github.com/EdgeMatrixChain/edge-matrix-computing/server/proto"
+	"github.com/spf13/cobra"
+	empty "google.golang.org/protobuf/types/known/emptypb"
+)
+
+func GetCommand() *cobra.Command {
+	breakersCmd := &cobra.Command{
+		Use:   "breakers",
+		Short: "Returns the circuit breakers of the transparent proxy which are open or have failures",
+		Run:   runCommand,
+	}
+
+	return breakersCmd
+}
+
+func runCommand(cmd *cobra.Command, _ []string) {
+	outputter := command.InitializeOutputter(cmd)
+	defer outputter.WriteOutput()
+
+	breakers, err := getProxyBreakers(helper.GetGRPCAddress(cmd))
+	if err != nil {
+		outputter.SetError(err)
+
+		return
+	}
+
+	outputter.SetCommandResult(newProxyBreakersResult(breakers))
+}
+
+func getProxyBreakers(grpcAddress string) (*proto.ProxyBreakersResponse, error) {
+	client, err := helper.GetSystemClientConnection(grpcAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.ProxyBreakers(context.Background(), &empty.Empty{})
+}
@@ -0,0 +1,86 @@
+package breakers
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/EdgeMatrixChain/edge-matrix-computing/command/helper"
+	"github.com/EdgeMatrixChain/edge-matrix-computing/server/proto"
+)
+
+type Breaker struct {
+	NodeID              string `json:"node_id"`
+	State               string `json:"state"`
+	ConsecutiveFailures int64  `json:"consecutive_failures"`
+	Requests            int64  `json:"requests"`
+	Failures            int64  `json:"failures"`
+	OpenedAt            string `json:"opened_at,omitempty"`
+	RetryAt             string `json:"retry_at,omitempty"`
+}
+
+type ProxyBreakersResult struct {
+	Enabled  bool       `json:"enabled"`
+	Breakers []*Breaker `json:"breakers"`
+}
+
+func newProxyBreakersResult(resp *proto.ProxyBreakersResponse) *ProxyBreakersResult {
+	result := &ProxyBreakersResult{
+		Enabled:  resp.Enabled,
+		Breakers: make([]*Breaker, len(resp.Breakers)),
+	}
+
+	for i, breaker := range resp.Breakers {
+		result.Breakers[i] = &Breaker{
+			NodeID:              breaker.NodeId,
+			State:               breaker.State,
+			ConsecutiveFailures: breaker.ConsecutiveFailures,
+			Requests:            breaker.Requests,
+			Failures:            breaker.Failures,
+		}
+
+		if breaker.OpenedAt != 0 {
+			result.Breakers[i].OpenedAt = time.Unix(breaker.OpenedAt, 0).Format(time.RFC3339)
+			result.Breakers[i].RetryAt = time.Unix(breaker.RetryAt, 0).Format(time.RFC3339)
+		}
+	}
+
+	return result
+}
+
+func (r *ProxyBreakersResult) GetOutput() string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("\n[PROXY BREAKERS]\n")
+
+	if !r.Enabled {
+		buffer.WriteString("The circuit breakers are disabled\n")
+
+		return buffer.String()
+	}
+
+	if len(r.Breakers) == 0 {
+		buffer.WriteString("All the circuits are closed\n")
+
+		return buffer.String()
+	}
+
+	rows := make([]string, len(r.Breakers)+1)
+	rows[0] = "NodeID|State|Consecutive failures|Failures/Requests|Opened at|Retry at"
+	for i, breaker := range r.Breakers {
+		rows[i+1] = fmt.Sprintf("%s|%s|%d|%d/%d|%s|%s", breaker.NodeID, breaker.State, breaker.ConsecutiveFailures,
+			breaker.Failures, breaker.Requests, valueOrNone(breaker.OpenedAt), valueOrNone(breaker.RetryAt))
+	}
+	buffer.WriteString(helper.FormatList(rows))
+	buffer.WriteString("\n")
+
+	return buffer.String()
+}
+
+func valueOrNone(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
@@ -0,0 +1,104 @@
+package policy
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/EdgeMatrixChain/edge-matrix-computing/command/helper"
+	"github.com/EdgeMatrixChain/edge-matrix-computing/server/proto"
+)
+
+type PolicyRule struct {
+	Name     string  `json:"name"`
+	Action   string  `json:"action"`
+	NodeID   string  `json:"node_id"`
+	Ports    []int64 `json:"ports"`
+	Path     string  `json:"path"`
+	Identity string  `json:"identity"`
+}
+
+type ProxyPolicyResult struct {
+	Enabled  bool          `json:"enabled"`
+	File     string        `json:"file"`
+	Default  string        `json:"default"`
+	LoadedAt string        `json:"loaded_at"`
+	Rules    []*PolicyRule `json:"rules"`
+}
+
+func newProxyPolicyResult(policy *proto.ProxyPolicyResponse) *ProxyPolicyResult {
+	result := &ProxyPolicyResult{
+		Enabled: policy.Enabled,
+		File:    policy.File,
+		Default: policy.Default,
+		Rules:   make([]*PolicyRule, len(policy.Rules)),
+	}
+
+	if result.Default == "" {
+		result.Default = "allow"
+	}
+
+	if policy.Enabled {
+		result.LoadedAt = time.Unix(policy.LoadedAt, 0).Format(time.RFC3339)
+	}
+
+	for i, rule := range policy.Rules {
+		result.Rules[i] = &PolicyRule{
+			Name:     rule.Name,
+			Action:   rule.Action,
+			NodeID:   rule.NodeId,
+			Ports:    rule.Ports,
+			Path:     rule.Path,
+			Identity: rule.Identity,
+		}
+	}
+
+	return result
+}
+
+func (r *ProxyPolicyResult) GetOutput() string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("\n[PROXY POLICY]\n")
+
+	if !r.Enabled {
+		buffer.WriteString("No policy file set, all nodes are allowed\n")
+
+		return buffer.String()
+	}
+
+	buffer.WriteString(helper.FormatKV([]string{
+		fmt.Sprintf("File|%s", r.File),
+		fmt.Sprintf("Loaded at|%s", r.LoadedAt),
+		fmt.Sprintf("Default|%s", r.Default),
+		fmt.Sprintf("Number of rules|%d", len(r.Rules)),
+	}))
+	buffer.WriteString("\n")
+
+	if len(r.Rules) > 0 {
+		buffer.WriteString("\n[RULES]\n")
+
+		rows := make([]string, len(r.Rules)+1)
+		rows[0] = "Name|Action|NodeID|Ports|Path|Identity"
+		for i, rule := range r.Rules {
+			name := rule.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i)
+			}
+
+			rows[i+1] = fmt.Sprintf("%s|%s|%s|%v|%s|%s", name, rule.Action, valueOrAny(rule.NodeID), rule.Ports, valueOrAny(rule.Path), valueOrAny(rule.Identity))
+		}
+		buffer.WriteString(helper.FormatList(rows))
+		buffer.WriteString("\n")
+	}
+
+	return buffer.String()
+}
+
+func valueOrAny(value string) string {
+	if value == "" {
+		return "*"
+	}
+
+	return value
+}
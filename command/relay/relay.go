@@ -2,7 +2,9 @@ package relay
 
 import (
 	"github.com/EdgeMatrixChain/edge-matrix-computing/command/helper"
+	"github.com/EdgeMatrixChain/edge-matrix-computing/command/relay/breakers"
 	"github.com/EdgeMatrixChain/edge-matrix-computing/command/relay/list"
+	"github.com/EdgeMatrixChain/edge-matrix-computing/command/relay/policy"
 	"github.com/EdgeMatrixChain/edge-matrix-computing/command/relay/status"
 	"github.com/spf13/cobra"
 )
@@ -26,5 +28,9 @@ func registerSubcommands(baseCmd *cobra.Command) {
 		status.GetCommand(),
 		// relay list
 		list.GetCommand(),
+		// relay policy
+		policy.GetCommand(),
+		// relay breakers
+		breakers.GetCommand(),
 	)
 }
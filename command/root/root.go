@@ -9,6 +9,7 @@ import (
 	"github.com/EdgeMatrixChain/edge-matrix-computing/command/relay"
 	"github.com/EdgeMatrixChain/edge-matrix-computing/command/secrets"
 	"github.com/EdgeMatrixChain/edge-matrix-computing/command/server"
+	"github.com/EdgeMatrixChain/edge-matrix-computing/command/usage"
 	"github.com/EdgeMatrixChain/edge-matrix-computing/command/version"
 	"os"
 
@@ -41,6 +42,7 @@ func (rc *RootCommand) registerSubCommands() {
 		server.GetCommand(),
 		peers.GetCommand(),
 		relay.GetCommand(),
+		usage.GetCommand(),
 		miner.GetCommand(),
 	)
 }
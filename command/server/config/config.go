@@ -13,21 +13,22 @@ import (
 
 // Config defines the server configuration params
 type Config struct {
-	GenesisPath              string     `json:"chain_config" yaml:"chain_config"`
-	SecretsConfigPath        string     `json:"secrets_config" yaml:"secrets_config"`
-	DataDir                  string     `json:"data_dir" yaml:"data_dir"`
-	GRPCAddr                 string     `json:"grpc_addr" yaml:"grpc_addr"`
-	JSONRPCAddr              string     `json:"jsonrpc_addr" yaml:"jsonrpc_addr"`
-	TransparentProxyAddr     string     `json:"transparent_proxy_addr" yaml:"transparent_proxy_addr"`
-	Telemetry                *Telemetry `json:"telemetry" yaml:"telemetry"`
-	Network                  *Network   `json:"network" yaml:"network"`
-	TelePool                 *TelePool  `json:"tele_pool" yaml:"tele_pool"`
-	LogLevel                 string     `json:"log_level" yaml:"log_level"`
-	Headers                  *Headers   `json:"headers" yaml:"headers"`
-	LogFilePath              string     `json:"log_to" yaml:"log_to"`
-	JSONRPCBatchRequestLimit uint64     `json:"json_rpc_batch_request_limit" yaml:"json_rpc_batch_request_limit"`
-	JSONRPCBlockRangeLimit   uint64     `json:"json_rpc_block_range_limit" yaml:"json_rpc_block_range_limit"`
-	JSONLogFormat            bool       `json:"json_log_format" yaml:"json_log_format"`
+	GenesisPath              string      `json:"chain_config" yaml:"chain_config"`
+	SecretsConfigPath        string      `json:"secrets_config" yaml:"secrets_config"`
+	DataDir                  string      `json:"data_dir" yaml:"data_dir"`
+	GRPCAddr                 string      `json:"grpc_addr" yaml:"grpc_addr"`
+	JSONRPCAddr              string      `json:"jsonrpc_addr" yaml:"jsonrpc_addr"`
+	TransparentProxyAddr     string      `json:"transparent_proxy_addr" yaml:"transparent_proxy_addr"`
+	Telemetry                *Telemetry  `json:"telemetry" yaml:"telemetry"`
+	Network                  *Network    `json:"network" yaml:"network"`
+	TelePool                 *TelePool   `json:"tele_pool" yaml:"tele_pool"`
+	ProxyRetry               *ProxyRetry `json:"proxy_retry" yaml:"proxy_retry"`
+	LogLevel                 string      `json:"log_level" yaml:"log_level"`
+	Headers                  *Headers    `json:"headers" yaml:"headers"`
+	LogFilePath              string      `json:"log_to" yaml:"log_to"`
+	JSONRPCBatchRequestLimit uint64      `json:"json_rpc_batch_request_limit" yaml:"json_rpc_batch_request_limit"`
+	JSONRPCBlockRangeLimit   uint64      `json:"json_rpc_block_range_limit" yaml:"json_rpc_block_range_limit"`
+	JSONLogFormat            bool        `json:"json_log_format" yaml:"json_log_format"`
 
 	NumBlockConfirmations uint64 `json:"num_block_confirmations" yaml:"num_block_confirmations"`
 
@@ -41,12 +42,64 @@ type Config struct {
 	AppNoAuth  bool   `json:"app_no_auth,omitempty" yaml:"app_no_auth,omitempty"`
 	AppNoAgent bool   `json:"app_no_agent,omitempty" yaml:"app_no_agent,omitempty"`
 
-	AuthUrl string `json:"auth_url,omitempty" yaml:"auth_url,omitempty"`
+	AuthUrl   string     `json:"auth_url,omitempty" yaml:"auth_url,omitempty"`
+	AuthCache *AuthCache `json:"auth_cache,omitempty" yaml:"auth_cache,omitempty"`
+
+	AuthBackend          string `json:"auth_backend,omitempty" yaml:"auth_backend,omitempty"`
+	AuthKeysFile         string `json:"auth_keys_file,omitempty" yaml:"auth_keys_file,omitempty"`
+	AuthJWKSFile         string `json:"auth_jwks_file,omitempty" yaml:"auth_jwks_file,omitempty"`
+	AuthJWTIssuer        string `json:"auth_jwt_issuer,omitempty" yaml:"auth_jwt_issuer,omitempty"`
+	AuthJWTAudience      string `json:"auth_jwt_audience,omitempty" yaml:"auth_jwt_audience,omitempty"`
+	AuthJWTAllowNoExpiry bool   `json:"auth_jwt_allow_no_expiry,omitempty" yaml:"auth_jwt_allow_no_expiry,omitempty"`
+
+	AppBalancePolicy string   `json:"app_balance_policy,omitempty" yaml:"app_balance_policy,omitempty"`
+	AppPathPrefix    string   `json:"app_path_prefix" yaml:"app_path_prefix"`
+	AppRoutePorts    []string `json:"app_route_ports,omitempty" yaml:"app_route_ports,omitempty"`
+
+	ProxyPolicyFile string `json:"proxy_policy_file,omitempty" yaml:"proxy_policy_file,omitempty"`
+
+	ProxyHostDomain string `json:"proxy_host_domain,omitempty" yaml:"proxy_host_domain,omitempty"`
+
+	ProxyUsageMetering  bool   `json:"proxy_usage_metering,omitempty" yaml:"proxy_usage_metering,omitempty"`
+	ProxyUsageRetention string `json:"proxy_usage_retention,omitempty" yaml:"proxy_usage_retention,omitempty"`
+
+	RateLimit *RateLimit `json:"rate_limit,omitempty" yaml:"rate_limit,omitempty"`
+
+	ProxyTLS *ProxyTLS `json:"proxy_tls,omitempty" yaml:"proxy_tls,omitempty"`
+
+	ProxyTransport *ProxyTransport `json:"proxy_transport,omitempty" yaml:"proxy_transport,omitempty"`
+
+	AccessLog *AccessLog `json:"access_log,omitempty" yaml:"access_log,omitempty"`
+
+	ProxyStreamContentTypes []string `json:"proxy_stream_content_types,omitempty" yaml:"proxy_stream_content_types,omitempty"`
+
+	OpenAIGateway *OpenAIGateway `json:"openai_gateway,omitempty" yaml:"openai_gateway,omitempty"`
+
+	ProxyBreaker *ProxyBreaker `json:"proxy_breaker,omitempty" yaml:"proxy_breaker,omitempty"`
+
+	ProxyJobs *ProxyJobs `json:"proxy_jobs,omitempty" yaml:"proxy_jobs,omitempty"`
+
+	Upstreams []*Upstream `json:"upstreams,omitempty" yaml:"upstreams,omitempty"`
+
+	AppHealth *AppHealth `json:"app_health,omitempty" yaml:"app_health,omitempty"`
+
+	AppConcurrency *AppConcurrency `json:"app_concurrency,omitempty" yaml:"app_concurrency,omitempty"`
+
+	Apps []*App `json:"apps,omitempty" yaml:"apps,omitempty"`
 }
 
 // Telemetry holds the config details for metric services.
 type Telemetry struct {
-	PrometheusAddr string `json:"prometheus_addr" yaml:"prometheus_addr"`
+	PrometheusAddr string   `json:"prometheus_addr" yaml:"prometheus_addr"`
+	Tracing        *Tracing `json:"tracing,omitempty" yaml:"tracing,omitempty"`
+}
+
+// Tracing defines the exporter of the OpenTelemetry spans
+type Tracing struct {
+	Exporter    string  `json:"exporter" yaml:"exporter"`
+	Endpoint    string  `json:"endpoint" yaml:"endpoint"`
+	Insecure    bool    `json:"insecure" yaml:"insecure"`
+	SampleRatio float64 `json:"sample_ratio" yaml:"sample_ratio"`
 }
 
 // Network defines the network configuration params
@@ -68,6 +121,128 @@ type TelePool struct {
 	MaxAccountEnqueued uint64 `json:"max_account_enqueued" yaml:"max_account_enqueued"`
 }
 
+// ProxyRetry defines the retry policy of the transparent proxy
+type ProxyRetry struct {
+	MaxRetries    int    `json:"max_retries" yaml:"max_retries"`
+	Backoff       string `json:"backoff" yaml:"backoff"`
+	Budget        string `json:"budget" yaml:"budget"`
+	MaxBodySize   int64  `json:"max_body_size" yaml:"max_body_size"`
+	NonIdempotent bool   `json:"non_idempotent" yaml:"non_idempotent"`
+	Failover      bool   `json:"failover" yaml:"failover"`
+}
+
+// ProxyBreaker defines the circuit breaker of the nodes in the transparent proxy
+type ProxyBreaker struct {
+	Failures     int     `json:"failures" yaml:"failures"`
+	ErrorRate    float64 `json:"error_rate" yaml:"error_rate"`
+	MinRequests  int     `json:"min_requests" yaml:"min_requests"`
+	Window       string  `json:"window" yaml:"window"`
+	OpenDuration string  `json:"open_duration" yaml:"open_duration"`
+}
+
+// ProxyJobs defines the async job API of the transparent proxy
+type ProxyJobs struct {
+	Enabled              bool     `json:"enabled" yaml:"enabled"`
+	TTL                  string   `json:"ttl" yaml:"ttl"`
+	Timeout              string   `json:"timeout" yaml:"timeout"`
+	Workers              int      `json:"workers" yaml:"workers"`
+	MaxBodySize          int64    `json:"max_body_size" yaml:"max_body_size"`
+	MaxQueued            int      `json:"max_queued" yaml:"max_queued"`
+	CallbackAllowedHosts []string `json:"callback_allowed_hosts" yaml:"callback_allowed_hosts"`
+}
+
+// AuthCache defines the cache of the bearers checked by the auth url
+type AuthCache struct {
+	TTL         string `json:"ttl" yaml:"ttl"`
+	NegativeTTL string `json:"negative_ttl" yaml:"negative_ttl"`
+	StaleTTL    string `json:"stale_ttl" yaml:"stale_ttl"`
+}
+
+// ProxyTLS defines the HTTPS listener of the transparent proxy
+type ProxyTLS struct {
+	CertFile     string `json:"cert_file" yaml:"cert_file"`
+	KeyFile      string `json:"key_file" yaml:"key_file"`
+	ClientCAFile string `json:"client_ca_file" yaml:"client_ca_file"`
+	ClientAuth   string `json:"client_auth" yaml:"client_auth"`
+}
+
+// ProxyTransport defines the pool of libp2p streams from the transparent proxy to the edge nodes
+type ProxyTransport struct {
+	MaxConnsPerNode     int    `json:"max_conns_per_node" yaml:"max_conns_per_node"`
+	MaxIdleConnsPerNode int    `json:"max_idle_conns_per_node" yaml:"max_idle_conns_per_node"`
+	IdleConnTimeout     string `json:"idle_conn_timeout" yaml:"idle_conn_timeout"`
+}
+
+// AccessLog defines the access logs of the transparent proxy and the edge node
+type AccessLog struct {
+	Sink   string `json:"sink" yaml:"sink"`
+	Format string `json:"format" yaml:"format"`
+}
+
+// Upstream declares a service reachable through the transparent forward of the edge node
+type Upstream struct {
+	Name          string            `json:"name" yaml:"name"`
+	Port          int               `json:"port" yaml:"port"`
+	Target        string            `json:"target" yaml:"target"`
+	CAFile        string            `json:"ca_file,omitempty" yaml:"ca_file,omitempty"`
+	PathPrefixes  []string          `json:"path_prefixes,omitempty" yaml:"path_prefixes,omitempty"`
+	Timeout       string            `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	Headers       map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	HealthPath    string            `json:"health_path,omitempty" yaml:"health_path,omitempty"`
+	MaxConcurrent int               `json:"max_concurrent,omitempty" yaml:"max_concurrent,omitempty"`
+	MaxQueue      int               `json:"max_queue,omitempty" yaml:"max_queue,omitempty"`
+	MaxQueueWait  string            `json:"max_queue_wait,omitempty" yaml:"max_queue_wait,omitempty"`
+}
+
+// App declares a local app process launched and supervised by the edge node
+type App struct {
+	Name         string   `json:"name" yaml:"name"`
+	Command      string   `json:"command" yaml:"command"`
+	Args         []string `json:"args,omitempty" yaml:"args,omitempty"`
+	Env          []string `json:"env,omitempty" yaml:"env,omitempty"`
+	Dir          string   `json:"dir,omitempty" yaml:"dir,omitempty"`
+	ReadyURL     string   `json:"ready_url,omitempty" yaml:"ready_url,omitempty"`
+	ReadyTimeout string   `json:"ready_timeout,omitempty" yaml:"ready_timeout,omitempty"`
+	StopTimeout  string   `json:"stop_timeout,omitempty" yaml:"stop_timeout,omitempty"`
+}
+
+// AppConcurrency limits the requests served at once by the upstream services of the edge node
+type AppConcurrency struct {
+	MaxConcurrent int    `json:"max_concurrent" yaml:"max_concurrent"`
+	MaxQueue      int    `json:"max_queue" yaml:"max_queue"`
+	MaxQueueWait  string `json:"max_queue_wait" yaml:"max_queue_wait"`
+}
+
+// AppHealth defines the health checks of the upstream services of the edge node
+type AppHealth struct {
+	Path     string `json:"path" yaml:"path"`
+	Interval string `json:"interval" yaml:"interval"`
+	Timeout  string `json:"timeout" yaml:"timeout"`
+	Failures int    `json:"failures" yaml:"failures"`
+}
+
+// OpenAIGateway defines the OpenAI-compatible gateway of the transparent proxy
+type OpenAIGateway struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// Models are model=appName mappings, a model without mapping is routed to the app of the same name
+	Models []string `json:"models,omitempty" yaml:"models,omitempty"`
+}
+
+// RateLimit defines the rate limits and quotas of the transparent proxy
+type RateLimit struct {
+	Key  *RateLimitRule `json:"key,omitempty" yaml:"key,omitempty"`
+	IP   *RateLimitRule `json:"ip,omitempty" yaml:"ip,omitempty"`
+	Node *RateLimitRule `json:"node,omitempty" yaml:"node,omitempty"`
+}
+
+// RateLimitRule defines the token bucket and the quotas of a limited dimension
+type RateLimitRule struct {
+	Rate         float64 `json:"rate" yaml:"rate"`
+	Burst        int     `json:"burst" yaml:"burst"`
+	DailyQuota   uint64  `json:"daily_quota" yaml:"daily_quota"`
+	MonthlyQuota uint64  `json:"monthly_quota" yaml:"monthly_quota"`
+}
+
 // Headers defines the HTTP response headers required to enable CORS.
 type Headers struct {
 	AccessControlAllowOrigins []string `json:"access_control_allow_origins" yaml:"access_control_allow_origins"`
@@ -82,6 +257,18 @@ const (
 	DefaultJSONRPCBlockRangeLimit uint64 = 1000
 
 	DefaultRunningMode string = "full"
+
+	// DefaultAuthBackend checks the bearers with the hub
+	DefaultAuthBackend string = "hub"
+
+	// DefaultAppBalancePolicy is the policy for picking a node when routing by app name
+	DefaultAppBalancePolicy string = "round-robin"
+
+	// DefaultAppPathPrefix is the path prefix of the routing by app name
+	DefaultAppPathPrefix string = "app"
+
+	// DefaultProxyUsageRetention keeps the usage records for 90 days
+	DefaultProxyUsageRetention string = "2160h"
 )
 
 // DefaultConfig returns the default server configuration
@@ -108,11 +295,60 @@ func DefaultConfig() *Config {
 				network.DefaultRelayLibp2pPort,
 			),
 		},
-		Telemetry: &Telemetry{},
+		Telemetry: &Telemetry{
+			Tracing: &Tracing{
+				SampleRatio: 1,
+			},
+		},
 		TelePool: &TelePool{
 			MaxSlots:           4096,
 			MaxAccountEnqueued: 128,
 		},
+		ProxyTLS: &ProxyTLS{},
+		ProxyTransport: &ProxyTransport{
+			MaxConnsPerNode:     64,
+			MaxIdleConnsPerNode: 16,
+			IdleConnTimeout:     "90s",
+		},
+		AccessLog: &AccessLog{
+			Sink:   "stdout",
+			Format: "json",
+		},
+		OpenAIGateway: &OpenAIGateway{},
+		ProxyBreaker: &ProxyBreaker{
+			Failures:     5,
+			ErrorRate:    0.5,
+			MinRequests:  20,
+			Window:       "1m",
+			OpenDuration: "30s",
+		},
+		AppConcurrency: &AppConcurrency{
+			MaxQueue:     16,
+			MaxQueueWait: "30s",
+		},
+		AppHealth: &AppHealth{
+			Interval: "10s",
+			Timeout:  "2s",
+			Failures: 3,
+		},
+		ProxyJobs: &ProxyJobs{
+			TTL:         "24h",
+			Timeout:     "30m",
+			Workers:     8,
+			MaxBodySize: 16 << 20,
+			MaxQueued:   1000,
+		},
+		AuthCache: &AuthCache{
+			TTL:         "60s",
+			NegativeTTL: "10s",
+			StaleTTL:    "0s",
+		},
+		ProxyRetry: &ProxyRetry{
+			MaxRetries:  2,
+			Backoff:     "200ms",
+			Budget:      "5s",
+			MaxBodySize: 1 << 20,
+		},
 		LogLevel: "INFO",
 		Headers: &Headers{
 			AccessControlAllowOrigins: []string{"*"},
@@ -123,6 +359,10 @@ func DefaultConfig() *Config {
 		RelayOn:                  false,
 		RelayDiscovery:           false,
 		RunningMode:              DefaultRunningMode,
+		AppBalancePolicy:         DefaultAppBalancePolicy,
+		AppPathPrefix:            DefaultAppPathPrefix,
+		AuthBackend:              DefaultAuthBackend,
+		ProxyUsageRetention:      DefaultProxyUsageRetention,
 	}
 }
 
@@ -3,15 +3,23 @@ package server
 import (
 	"errors"
 	"fmt"
+	"github.com/EdgeMatrixChain/edge-matrix-computing/agent"
 	"github.com/EdgeMatrixChain/edge-matrix-computing/config"
 	"math"
+	"mime"
 	"net"
+	"strconv"
+	"strings"
+	"time"
 
 	serverConfig "github.com/EdgeMatrixChain/edge-matrix-computing/command/server/config"
 
 	"github.com/EdgeMatrixChain/edge-matrix-core/core/network/common"
 
 	"github.com/EdgeMatrixChain/edge-matrix-computing/command/helper"
+	"github.com/EdgeMatrixChain/edge-matrix-computing/proxy"
+	"github.com/EdgeMatrixChain/edge-matrix-computing/server"
+	"github.com/EdgeMatrixChain/edge-matrix-computing/supervisor"
 	"github.com/EdgeMatrixChain/edge-matrix-core/core/network"
 	"github.com/EdgeMatrixChain/edge-matrix-core/core/secrets"
 )
@@ -43,6 +51,80 @@ func (p *serverParams) initRawParams() error {
 		return err
 	}
 
+	if err := p.initAppBalancePolicy(); err != nil {
+		return err
+	}
+
+	if err := p.initAppRoutes(); err != nil {
+		return err
+	}
+
+	if err := p.initProxyRetry(); err != nil {
+		return err
+	}
+
+	p.initRateLimit()
+
+	if err := p.initAuthCache(); err != nil {
+		return err
+	}
+
+	if err := p.initAuthBackend(); err != nil {
+		return err
+	}
+
+	if err := p.initProxyTLS(); err != nil {
+		return err
+	}
+
+	if err := p.initProxyTransport(); err != nil {
+		return err
+	}
+
+	if err := p.initAccessLog(); err != nil {
+		return err
+	}
+
+	if err := p.initTracing(); err != nil {
+		return err
+	}
+
+	if err := p.initStreamContentTypes(); err != nil {
+		return err
+	}
+
+	if err := p.initOpenAIGateway(); err != nil {
+		return err
+	}
+
+	if err := p.initProxyBreaker(); err != nil {
+		return err
+	}
+
+	if err := p.initProxyJobs(); err != nil {
+		return err
+	}
+
+	if err := p.initUsageRetention(); err != nil {
+		return err
+	}
+
+	if err := p.initAppConcurrency(); err != nil {
+		return err
+	}
+
+	if err := p.initUpstreams(); err != nil {
+		return err
+	}
+
+	if err := p.initAppHealth(); err != nil {
+		return err
+	}
+
+	if err := p.initApps(); err != nil {
+		return err
+	}
+
 	p.initPeerLimits()
 	p.initLogFileLocation()
 
@@ -57,6 +139,292 @@ func (p *serverParams) initDataDirLocation() error {
 	return nil
 }
 
+func (p *serverParams) initAppBalancePolicy() error {
+	var parseErr error
+
+	if p.appBalancePolicy, parseErr = proxy.ParseBalancePolicy(
+		p.rawConfig.AppBalancePolicy,
+	); parseErr != nil {
+		return parseErr
+	}
+
+	return nil
+}
+
+func (p *serverParams) initAppRoutes() error {
+	if strings.Contains(p.rawConfig.AppPathPrefix, "/") {
+		return fmt.Errorf("invalid app path prefix '%s', expected a single path segment", p.rawConfig.AppPathPrefix)
+	}
+
+	p.appRoutePorts = make(map[string]int, len(p.rawConfig.AppRoutePorts))
+	for _, mapping := range p.rawConfig.AppRoutePorts {
+		appName, rawPort, ok := strings.Cut(mapping, "=")
+		if !ok || appName == "" {
+			return fmt.Errorf("invalid app route port '%s', expected appName=port", mapping)
+		}
+
+		port, err := strconv.Atoi(rawPort)
+		if err != nil || port <= 0 || port > 65535 {
+			return fmt.Errorf("invalid app route port '%s', expected appName=port", mapping)
+		}
+
+		p.appRoutePorts[appName] = port
+	}
+
+	return nil
+}
+
+func (p *serverParams) initProxyRetry() error {
+	rawRetry := p.rawConfig.ProxyRetry
+	retry := proxy.DefaultRetryConfig()
+
+	if rawRetry == nil {
+		p.proxyRetry = retry
+
+		return nil
+	}
+
+	retry.MaxRetries = rawRetry.MaxRetries
+	retry.MaxBodySize = rawRetry.MaxBodySize
+	retry.NonIdempotent = rawRetry.NonIdempotent
+	retry.Failover = rawRetry.Failover
+
+	var parseErr error
+
+	if rawRetry.Backoff != "" {
+		if retry.Backoff, parseErr = time.ParseDuration(rawRetry.Backoff); parseErr != nil {
+			return fmt.Errorf("invalid proxy retry backoff: %w", parseErr)
+		}
+	}
+
+	if rawRetry.Budget != "" {
+		if retry.Budget, parseErr = time.ParseDuration(rawRetry.Budget); parseErr != nil {
+			return fmt.Errorf("invalid proxy retry budget: %w", parseErr)
+		}
+	}
+
+	p.proxyRetry = retry
+
+	return nil
+}
+
+func (p *serverParams) initAuthCache() error {
+	rawAuthCache := p.rawConfig.AuthCache
+	if rawAuthCache == nil {
+		return nil
+	}
+
+	authCache := &agent.AuthCacheConfig{}
+
+	durations := []struct {
+		name  string
+		raw   string
+		value *time.Duration
+	}{
+		{"ttl", rawAuthCache.TTL, &authCache.TTL},
+		{"negative ttl", rawAuthCache.NegativeTTL, &authCache.NegativeTTL},
+		{"stale ttl", rawAuthCache.StaleTTL, &authCache.StaleTTL},
+	}
+
+	for _, d := range durations {
+		if d.raw == "" {
+			continue
+		}
+
+		value, err := time.ParseDuration(d.raw)
+		if err != nil {
+			return fmt.Errorf("invalid auth cache %s: %w", d.name, err)
+		}
+		*d.value = value
+	}
+
+	p.authCache = authCache
+
+	return nil
+}
+
+func (p *serverParams) initAuthBackend() error {
+	switch p.rawConfig.AuthBackend {
+	case "", agent.AuthBackendHub:
+		return nil
+	case agent.AuthBackendStatic:
+		if p.rawConfig.AuthKeysFile == "" {
+			return fmt.Errorf("--%s is required by the %s auth backend", authKeysFileFlag, agent.AuthBackendStatic)
+		}
+	case agent.AuthBackendJWT:
+		if p.rawConfig.AuthJWKSFile == "" {
+			return fmt.Errorf("--%s is required by the %s auth backend", authJWKSFileFlag, agent.AuthBackendJWT)
+		}
+	default:
+		return fmt.Errorf("unknown auth backend '%s'", p.rawConfig.AuthBackend)
+	}
+
+	return nil
+}
+
+func (p *serverParams) initProxyTLS() error {
+	rawTLS := p.rawConfig.ProxyTLS
+	if rawTLS == nil || (rawTLS.CertFile == "" && rawTLS.KeyFile == "") {
+		return nil
+	}
+
+	if rawTLS.CertFile == "" || rawTLS.KeyFile == "" {
+		return fmt.Errorf("both --%s and --%s are required for https", proxyTLSCertFileFlag, proxyTLSKeyFileFlag)
+	}
+
+	clientAuth := proxy.ClientAuthMode(rawTLS.ClientAuth)
+	switch clientAuth {
+	case proxy.ClientAuthNone, proxy.ClientAuthOptional, proxy.ClientAuthRequire:
+	default:
+		return fmt.Errorf("unknown client auth mode '%s'", rawTLS.ClientAuth)
+	}
+
+	if clientAuth != proxy.ClientAuthNone && rawTLS.ClientCAFile == "" {
+		return fmt.Errorf("--%s is required by the client auth", proxyTLSClientCAFileFlag)
+	}
+
+	p.proxyTLS = &proxy.TLSConfig{
+		CertFile:     rawTLS.CertFile,
+		KeyFile:      rawTLS.KeyFile,
+		ClientCAFile: rawTLS.ClientCAFile,
+		ClientAuth:   clientAuth,
+	}
+
+	return nil
+}
+
+func (p *serverParams) initProxyTransport() error {
+	rawTransport := p.rawConfig.ProxyTransport
+	transport := proxy.DefaultTransportConfig()
+
+	if rawTransport != nil {
+		transport.MaxConnsPerNode = rawTransport.MaxConnsPerNode
+		transport.MaxIdleConnsPerNode = rawTransport.MaxIdleConnsPerNode
+
+		if rawTransport.IdleConnTimeout != "" {
+			idleConnTimeout, err := time.ParseDuration(rawTransport.IdleConnTimeout)
+			if err != nil {
+				return fmt.Errorf("invalid proxy idle conn timeout: %w", err)
+			}
+			transport.IdleConnTimeout = idleConnTimeout
+		}
+	}
+
+	p.proxyTransport = transport
+
+	return nil
+}
+
+func (p *serverParams) initUsageRetention() error {
+	if p.rawConfig.ProxyUsageRetention == "" {
+		return nil
+	}
+
+	retention, err := time.ParseDuration(p.rawConfig.ProxyUsageRetention)
+	if err != nil {
+		return fmt.Errorf("invalid proxy usage retention: %w", err)
+	}
+
+	if retention < 0 {
+		return errors.New("proxy usage retention must not be negative")
+	}
+
+	p.usageRetention = retention
+
+	return nil
+}
+
+func (p *serverParams) initAccessLog() error {
+	rawAccessLog := p.rawConfig.AccessLog
+	if rawAccessLog == nil || rawAccessLog.Sink == "" {
+		return nil
+	}
+
+	format := proxy.AccessLogFormat(rawAccessLog.Format)
+	switch format {
+	case "", proxy.AccessLogJSON, proxy.AccessLogCLF:
+	default:
+		return fmt.Errorf("unknown access log format '%s'", rawAccessLog.Format)
+	}
+
+	p.accessLog = &proxy.AccessLogConfig{
+		Sink:   rawAccessLog.Sink,
+		Format: format,
+	}
+
+	return nil
+}
+
+func (p *serverParams) initTracing() error {
+	rawTracing := p.rawConfig.Telemetry.Tracing
+	if rawTracing == nil || rawTracing.Exporter == "" {
+		return nil
+	}
+
+	switch rawTracing.Exporter {
+	case server.TracingExporterOTLP, server.TracingExporterStdout:
+	default:
+		return fmt.Errorf("unknown tracing exporter '%s'", rawTracing.Exporter)
+	}
+
+	if rawTracing.SampleRatio < 0 || rawTracing.SampleRatio > 1 {
+		return fmt.Errorf("--%s must be between 0 and 1", tracingSampleRatioFlag)
+	}
+
+	p.tracing = &server.Tracing{
+		Exporter:    rawTracing.Exporter,
+		Endpoint:    rawTracing.Endpoint,
+		Insecure:    rawTracing.Insecure,
+		SampleRatio: rawTracing.SampleRatio,
+	}
+
+	return nil
+}
+
+func (p *serverParams) initStreamContentTypes() error {
+	if len(p.rawConfig.ProxyStreamContentTypes) == 0 {
+		p.streamContentTypes = proxy.DefaultStreamContentTypes
+
+		return nil
+	}
+
+	for _, contentType := range p.rawConfig.ProxyStreamContentTypes {
+		if _, _, err := mime.ParseMediaType(contentType); err != nil {
+			return fmt.Errorf("invalid stream content type '%s': %w", contentType, err)
+		}
+	}
+
+	p.streamContentTypes = p.rawConfig.ProxyStreamContentTypes
+
+	return nil
+}
+
+func (p *serverParams) initRateLimit() {
+	rawRateLimit := p.rawConfig.RateLimit
+	if rawRateLimit == nil || (rawRateLimit.Key == nil && rawRateLimit.IP == nil && rawRateLimit.Node == nil) {
+		return
+	}
+
+	p.rateLimit = &proxy.RateLimitConfig{
+		Key:  toRateLimit(rawRateLimit.Key),
+		IP:   toRateLimit(rawRateLimit.IP),
+		Node: toRateLimit(rawRateLimit.Node),
+	}
+}
+
+func toRateLimit(rule *serverConfig.RateLimitRule) *proxy.RateLimit {
+	if rule == nil {
+		return nil
+	}
+
+	return &proxy.RateLimit{
+		Rate:         rule.Rate,
+		Burst:        rule.Burst,
+		DailyQuota:   rule.DailyQuota,
+		MonthlyQuota: rule.MonthlyQuota,
+	}
+}
+
 func (p *serverParams) initLogFileLocation() {
 	if p.isLogFileLocationSet() {
 		p.logFileLocation = p.rawConfig.LogFilePath
@@ -306,3 +674,257 @@ func (p *serverParams) initGRPCAddress() error {
 
 	return nil
 }
+
+func (p *serverParams) initOpenAIGateway() error {
+	rawGateway := p.rawConfig.OpenAIGateway
+	if rawGateway == nil || !rawGateway.Enabled {
+		return nil
+	}
+
+	models := make(map[string]string, len(rawGateway.Models))
+	for _, mapping := range rawGateway.Models {
+		model, appName, ok := strings.Cut(mapping, "=")
+		if !ok || model == "" || appName == "" {
+			return fmt.Errorf("invalid openai model '%s', expected model=appName", mapping)
+		}
+
+		models[model] = appName
+	}
+
+	p.openAI = &proxy.OpenAIConfig{Models: models}
+
+	return nil
+}
+
+func (p *serverParams) initProxyBreaker() error {
+	rawBreaker := p.rawConfig.ProxyBreaker
+	if rawBreaker == nil {
+		p.proxyBreaker = proxy.DefaultBreakerConfig()
+
+		return nil
+	}
+
+	// the breaker is disabled if neither threshold is set
+	if rawBreaker.Failures <= 0 && rawBreaker.ErrorRate <= 0 {
+		return nil
+	}
+
+	if rawBreaker.ErrorRate > 1 {
+		return fmt.Errorf("--%s must be between 0 and 1", proxyBreakerErrorRateFlag)
+	}
+
+	breaker := proxy.DefaultBreakerConfig()
+	breaker.ConsecutiveFailures = rawBreaker.Failures
+	breaker.ErrorRate = rawBreaker.ErrorRate
+	breaker.MinRequests = rawBreaker.MinRequests
+
+	var parseErr error
+
+	if rawBreaker.Window != "" {
+		if breaker.Window, parseErr = time.ParseDuration(rawBreaker.Window); parseErr != nil {
+			return fmt.Errorf("invalid proxy breaker window: %w", parseErr)
+		}
+	}
+
+	if rawBreaker.OpenDuration != "" {
+		if breaker.OpenDuration, parseErr = time.ParseDuration(rawBreaker.OpenDuration); parseErr != nil {
+			return fmt.Errorf("invalid proxy breaker open duration: %w", parseErr)
+		}
+	}
+
+	p.proxyBreaker = breaker
+
+	return nil
+}
+
+func (p *serverParams) initProxyJobs() error {
+	rawJobs := p.rawConfig.ProxyJobs
+	if rawJobs == nil || !rawJobs.Enabled {
+		return nil
+	}
+
+	jobs := proxy.DefaultJobsConfig()
+	if rawJobs.Workers > 0 {
+		jobs.Workers = rawJobs.Workers
+	}
+
+	if rawJobs.MaxBodySize > 0 {
+		jobs.MaxBodySize = rawJobs.MaxBodySize
+	}
+
+	if rawJobs.MaxQueued > 0 {
+		jobs.MaxQueued = rawJobs.MaxQueued
+	}
+
+	jobs.CallbackAllowedHosts = rawJobs.CallbackAllowedHosts
+
+	var parseErr error
+
+	if rawJobs.TTL != "" {
+		if jobs.TTL, parseErr = time.ParseDuration(rawJobs.TTL); parseErr != nil {
+			return fmt.Errorf("invalid proxy job ttl: %w", parseErr)
+		}
+	}
+
+	if rawJobs.Timeout != "" {
+		if jobs.Timeout, parseErr = time.ParseDuration(rawJobs.Timeout); parseErr != nil {
+			return fmt.Errorf("invalid proxy job timeout: %w", parseErr)
+		}
+	}
+
+	p.proxyJobs = jobs
+
+	return nil
+}
+
+func (p *serverParams) initUpstreams() error {
+	upstreams := make([]*proxy.UpstreamConfig, 0, len(p.rawConfig.Upstreams))
+
+	for _, rawUpstream := range p.rawConfig.Upstreams {
+		upstream := &proxy.UpstreamConfig{
+			Name:         rawUpstream.Name,
+			Port:         rawUpstream.Port,
+			Target:       rawUpstream.Target,
+			CAFile:       rawUpstream.CAFile,
+			PathPrefixes: rawUpstream.PathPrefixes,
+			Headers:      rawUpstream.Headers,
+			HealthPath:   rawUpstream.HealthPath,
+			Concurrency:  p.appConcurrency,
+		}
+
+		// a service can set its own limits, else it has the limits of the app
+		if rawUpstream.MaxConcurrent > 0 {
+			concurrency, err := parseConcurrency(rawUpstream.MaxConcurrent, rawUpstream.MaxQueue, rawUpstream.MaxQueueWait)
+			if err != nil {
+				return fmt.Errorf("invalid limits of upstream %s: %w", rawUpstream.Name, err)
+			}
+			upstream.Concurrency = concurrency
+		}
+
+		if rawUpstream.Timeout != "" {
+			timeout, err := time.ParseDuration(rawUpstream.Timeout)
+			if err != nil {
+				return fmt.Errorf("invalid timeout of upstream %s: %w", rawUpstream.Name, err)
+			}
+			upstream.Timeout = timeout
+		}
+
+		upstreams = append(upstreams, upstream)
+	}
+
+	p.upstreams = upstreams
+
+	return nil
+}
+
+func (p *serverParams) initApps() error {
+	apps := make([]*supervisor.ProcessConfig, 0, len(p.rawConfig.Apps))
+
+	for _, rawApp := range p.rawConfig.Apps {
+		app := &supervisor.ProcessConfig{
+			Name:     rawApp.Name,
+			Command:  rawApp.Command,
+			Args:     rawApp.Args,
+			Env:      rawApp.Env,
+			Dir:      rawApp.Dir,
+			ReadyURL: rawApp.ReadyURL,
+		}
+
+		if rawApp.ReadyTimeout != "" {
+			readyTimeout, err := time.ParseDuration(rawApp.ReadyTimeout)
+			if err != nil {
+				return fmt.Errorf("invalid ready timeout of app %s: %w", rawApp.Name, err)
+			}
+			app.ReadyTimeout = readyTimeout
+		}
+
+		if rawApp.StopTimeout != "" {
+			stopTimeout, err := time.ParseDuration(rawApp.StopTimeout)
+			if err != nil {
+				return fmt.Errorf("invalid stop timeout of app %s: %w", rawApp.Name, err)
+			}
+			app.StopTimeout = stopTimeout
+		}
+
+		apps = append(apps, app)
+	}
+
+	p.apps = apps
+
+	return nil
+}
+
+func (p *serverParams) initAppHealth() error {
+	health := proxy.DefaultHealthConfig()
+
+	rawHealth := p.rawConfig.AppHealth
+	if rawHealth == nil {
+		p.appHealth = health
+
+		return nil
+	}
+
+	health.Path = rawHealth.Path
+	if rawHealth.Failures > 0 {
+		health.Failures = rawHealth.Failures
+	}
+
+	var parseErr error
+
+	if rawHealth.Interval != "" {
+		if health.Interval, parseErr = time.ParseDuration(rawHealth.Interval); parseErr != nil {
+			return fmt.Errorf("invalid app health interval: %w", parseErr)
+		}
+	}
+
+	if health.Interval <= 0 {
+		return fmt.Errorf("--%s must be positive", appHealthIntervalFlag)
+	}
+
+	if rawHealth.Timeout != "" {
+		if health.Timeout, parseErr = time.ParseDuration(rawHealth.Timeout); parseErr != nil {
+			return fmt.Errorf("invalid app health timeout: %w", parseErr)
+		}
+	}
+
+	p.appHealth = health
+
+	return nil
+}
+
+func (p *serverParams) initAppConcurrency() error {
+	rawConcurrency := p.rawConfig.AppConcurrency
+	if rawConcurrency == nil || rawConcurrency.MaxConcurrent <= 0 {
+		return nil
+	}
+
+	concurrency, err := parseConcurrency(rawConcurrency.MaxConcurrent, rawConcurrency.MaxQueue, rawConcurrency.MaxQueueWait)
+	if err != nil {
+		return err
+	}
+
+	p.appConcurrency = concurrency
+
+	return nil
+}
+
+func parseConcurrency(maxConcurrent int, maxQueue int, maxQueueWait string) (*proxy.ConcurrencyConfig, error) {
+	if maxQueue < 0 {
+		return nil, fmt.Errorf("--%s must not be negative", appMaxQueueFlag)
+	}
+
+	concurrency := &proxy.ConcurrencyConfig{
+		MaxConcurrent: maxConcurrent,
+		MaxQueue:      maxQueue,
+	}
+
+	if maxQueueWait != "" {
+		maxWait, err := time.ParseDuration(maxQueueWait)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max queue wait: %w", err)
+		}
+		concurrency.MaxWait = maxWait
+	}
+
+	return concurrency, nil
+}
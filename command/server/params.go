@@ -2,11 +2,15 @@ package server
 
 import (
 	"errors"
+	"github.com/EdgeMatrixChain/edge-matrix-computing/agent"
 	config2 "github.com/EdgeMatrixChain/edge-matrix-computing/config"
 	"net"
+	"time"
 
 	"github.com/EdgeMatrixChain/edge-matrix-computing/command/server/config"
+	"github.com/EdgeMatrixChain/edge-matrix-computing/proxy"
 	"github.com/EdgeMatrixChain/edge-matrix-computing/server"
+	"github.com/EdgeMatrixChain/edge-matrix-computing/supervisor"
 	"github.com/EdgeMatrixChain/edge-matrix-core/core/network"
 	"github.com/EdgeMatrixChain/edge-matrix-core/core/secrets"
 	"github.com/hashicorp/go-hclog"
@@ -45,7 +49,80 @@ const (
 	appNoAuthFlag   = "app-no-auth"
 	appNoAgentFlag  = "app-no-agent"
 
+	appHealthPathFlag     = "app-health-path"
+	appHealthIntervalFlag = "app-health-interval"
+	appHealthTimeoutFlag  = "app-health-timeout"
+	appHealthFailuresFlag = "app-health-failures"
+
+	appMaxConcurrentFlag = "app-max-concurrent"
+	appMaxQueueFlag      = "app-max-queue"
+	appMaxQueueWaitFlag  = "app-max-queue-wait"
+
 	authUrlFlag = "auth-url"
+
+	appBalancePolicyFlag = "app-balance-policy"
+	appPathPrefixFlag    = "app-path-prefix"
+	appRoutePortFlag     = "app-route-port"
+
+	proxyRetryMaxFlag           = "proxy-retry-max"
+	proxyRetryBackoffFlag       = "proxy-retry-backoff"
+	proxyRetryBudgetFlag        = "proxy-retry-budget"
+	proxyRetryMaxBodySizeFlag   = "proxy-retry-max-body-size"
+	proxyRetryNonIdempotentFlag = "proxy-retry-non-idempotent"
+	proxyFailoverFlag           = "proxy-failover"
+
+	proxyPolicyFileFlag = "proxy-policy-file"
+	proxyHostDomainFlag = "proxy-host-domain"
+
+	proxyUsageMeteringFlag  = "proxy-usage-metering"
+	proxyUsageRetentionFlag = "proxy-usage-retention"
+
+	authCacheTTLFlag         = "auth-cache-ttl"
+	authCacheNegativeTTLFlag = "auth-cache-negative-ttl"
+	authCacheStaleTTLFlag    = "auth-cache-stale-ttl"
+
+	authBackendFlag          = "auth-backend"
+	authKeysFileFlag         = "auth-keys-file"
+	authJWKSFileFlag         = "auth-jwks-file"
+	authJWTIssuerFlag        = "auth-jwt-issuer"
+	authJWTAudienceFlag      = "auth-jwt-audience"
+	authJWTAllowNoExpiryFlag = "auth-jwt-allow-no-expiry"
+
+	proxyTLSCertFileFlag     = "proxy-tls-cert-file"
+	proxyTLSKeyFileFlag      = "proxy-tls-key-file"
+	proxyTLSClientCAFileFlag = "proxy-tls-client-ca-file"
+	proxyTLSClientAuthFlag   = "proxy-tls-client-auth"
+
+	proxyMaxConnsPerNodeFlag     = "proxy-max-conns-per-node"
+	proxyMaxIdleConnsPerNodeFlag = "proxy-max-idle-conns-per-node"
+	proxyIdleConnTimeoutFlag     = "proxy-idle-conn-timeout"
+
+	accessLogFlag       = "access-log"
+	accessLogFormatFlag = "access-log-format"
+
+	tracingExporterFlag    = "tracing-exporter"
+	tracingEndpointFlag    = "tracing-endpoint"
+	tracingInsecureFlag    = "tracing-insecure"
+	tracingSampleRatioFlag = "tracing-sample-ratio"
+
+	proxyStreamContentTypesFlag = "proxy-stream-content-type"
+
+	openAIGatewayFlag = "openai-gateway"
+	openAIModelFlag   = "openai-model"
+
+	proxyBreakerFailuresFlag     = "proxy-breaker-failures"
+	proxyBreakerErrorRateFlag    = "proxy-breaker-error-rate"
+	proxyBreakerMinRequestsFlag  = "proxy-breaker-min-requests"
+	proxyBreakerWindowFlag       = "proxy-breaker-window"
+	proxyBreakerOpenDurationFlag = "proxy-breaker-open-duration"
+
+	proxyJobsFlag            = "proxy-jobs"
+	proxyJobTTLFlag          = "proxy-job-ttl"
+	proxyJobTimeoutFlag      = "proxy-job-timeout"
+	proxyJobWorkersFlag      = "proxy-job-workers"
+	proxyJobMaxBodySizeFlag  = "proxy-job-max-body-size"
+	proxyJobMaxQueuedFlag    = "proxy-job-max-queued"
+	proxyJobCallbackHostFlag = "proxy-job-callback-allowed-host"
 )
 
 const (
@@ -55,9 +132,19 @@ const (
 var (
 	params = &serverParams{
 		rawConfig: &config.Config{
-			Telemetry: &config.Telemetry{},
-			Network:   &config.Network{},
-			TelePool:  &config.TelePool{},
+			Telemetry:      &config.Telemetry{Tracing: &config.Tracing{}},
+			Network:        &config.Network{},
+			TelePool:       &config.TelePool{},
+			ProxyRetry:     &config.ProxyRetry{},
+			AuthCache:      &config.AuthCache{},
+			ProxyTLS:       &config.ProxyTLS{},
+			ProxyTransport: &config.ProxyTransport{},
+			AccessLog:      &config.AccessLog{},
+			OpenAIGateway:  &config.OpenAIGateway{},
+			ProxyBreaker:   &config.ProxyBreaker{},
+			ProxyJobs:      &config.ProxyJobs{},
+			AppHealth:      &config.AppHealth{},
+			AppConcurrency: &config.AppConcurrency{},
 		},
 	}
 )
@@ -85,6 +172,26 @@ type serverParams struct {
 
 	corsAllowedOrigins []string
 
+	appBalancePolicy proxy.BalancePolicy
+	appRoutePorts    map[string]int
+	proxyRetry       *proxy.RetryConfig
+	proxyBreaker     *proxy.BreakerConfig
+	proxyJobs        *proxy.JobsConfig
+	usageRetention   time.Duration
+	rateLimit        *proxy.RateLimitConfig
+	authCache        *agent.AuthCacheConfig
+	proxyTLS         *proxy.TLSConfig
+	proxyTransport   *proxy.TransportConfig
+	accessLog        *proxy.AccessLogConfig
+	tracing          *server.Tracing
+
+	streamContentTypes []string
+	openAI             *proxy.OpenAIConfig
+	upstreams          []*proxy.UpstreamConfig
+	appHealth          *proxy.HealthConfig
+	appConcurrency     *proxy.ConcurrencyConfig
+	apps               []*supervisor.ProcessConfig
+
 	genesisConfig *config2.GenesisConfig
 	secretsConfig *secrets.SecretsManagerConfig
 
@@ -142,6 +249,21 @@ func (p *serverParams) generateConfig() *server.Config {
 		TransparentProxy: &server.TransparentProxyConfig{
 			ProxyAddr:                p.transparentProxyAddress,
 			AccessControlAllowOrigin: p.corsAllowedOrigins,
+			BalancePolicy:            p.appBalancePolicy,
+			AppPathPrefix:            p.rawConfig.AppPathPrefix,
+			AppPorts:                 p.appRoutePorts,
+			Retry:                    p.proxyRetry,
+			PolicyFile:               p.rawConfig.ProxyPolicyFile,
+			HostDomain:               p.rawConfig.ProxyHostDomain,
+			UsageMetering:            p.rawConfig.ProxyUsageMetering,
+			UsageRetention:           p.usageRetention,
+			RateLimit:                p.rateLimit,
+			TLS:                      p.proxyTLS,
+			Transport:                p.proxyTransport,
+			StreamContentTypes:       p.streamContentTypes,
+			OpenAI:                   p.openAI,
+			Breaker:                  p.proxyBreaker,
+			Jobs:                     p.proxyJobs,
 		},
 		JSONRPC: &server.JSONRPC{
 			JSONRPCAddr:              p.jsonRPCAddress,
@@ -154,6 +276,7 @@ func (p *serverParams) generateConfig() *server.Config {
 		Telemetry: &server.Telemetry{
 			PrometheusAddr: p.prometheusAddress,
 		},
+		Tracing: p.tracing,
 		EdgeNetwork: &network.Config{
 			NoDiscover:       p.rawConfig.Network.NoDiscover,
 			Addr:             p.edgeLibp2pAddress,
@@ -185,6 +308,22 @@ func (p *serverParams) generateConfig() *server.Config {
 		AppNoAuth:   p.rawConfig.AppNoAuth,
 		AppNoAgent:  p.rawConfig.AppNoAgent,
 
-		AuthUrl: p.rawConfig.AuthUrl,
+		AuthUrl:   p.rawConfig.AuthUrl,
+		AuthCache: p.authCache,
+
+		AuthBackend:  p.rawConfig.AuthBackend,
+		AuthKeysFile: p.rawConfig.AuthKeysFile,
+		AuthJWT: &agent.JWTConfig{
+			JWKSFile:      p.rawConfig.AuthJWKSFile,
+			Issuer:        p.rawConfig.AuthJWTIssuer,
+			Audience:      p.rawConfig.AuthJWTAudience,
+			AllowNoExpiry: p.rawConfig.AuthJWTAllowNoExpiry,
+		},
+
+		AccessLog:   p.accessLog,
+		Upstreams:   p.upstreams,
+		Health:      p.appHealth,
+		Concurrency: p.appConcurrency,
+		Apps:        p.apps,
 	}
 }
@@ -2,10 +2,13 @@ package server
 
 import (
 	"fmt"
+	"strings"
+
 	"github.com/EdgeMatrixChain/edge-matrix-computing/command"
 	"github.com/EdgeMatrixChain/edge-matrix-computing/command/helper"
 	"github.com/EdgeMatrixChain/edge-matrix-computing/command/server/config"
 	"github.com/EdgeMatrixChain/edge-matrix-computing/command/server/export"
+	"github.com/EdgeMatrixChain/edge-matrix-computing/proxy"
 	"github.com/EdgeMatrixChain/edge-matrix-computing/server"
 	"github.com/spf13/cobra"
 )
@@ -95,6 +98,14 @@ func setFlags(cmd *cobra.Command) {
 		"the host DNS address which can be used by a remote peer for connection",
 	)
 
+	cmd.Flags().StringVar(
+		&params.rawConfig.Telemetry.PrometheusAddr,
+		prometheusAddressFlag,
+		"",
+		"the address and port for the prometheus instrumentation service (address:port). "+
+			"If only port is defined (:port) it will bind to 0.0.0.0:port",
+	)
+
 	cmd.Flags().StringVar(
 		&params.rawConfig.SecretsConfigPath,
 		secretsConfigFlag,
@@ -181,6 +192,69 @@ func setFlags(cmd *cobra.Command) {
 		"the base url for auth",
 	)
 
+	cmd.Flags().StringVar(
+		&params.rawConfig.AuthBackend,
+		authBackendFlag,
+		defaultConfig.AuthBackend,
+		"the backend checking the bearers (hub, static, jwt)",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.AuthKeysFile,
+		authKeysFileFlag,
+		"",
+		"the path to the API keys file of the static auth backend (.json, .yaml or .yml)",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.AuthJWKSFile,
+		authJWKSFileFlag,
+		"",
+		"the path to the JWKS file of the jwt auth backend",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.AuthJWTIssuer,
+		authJWTIssuerFlag,
+		"",
+		"the expected issuer of the tokens of the jwt auth backend",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.AuthJWTAudience,
+		authJWTAudienceFlag,
+		"",
+		"the expected audience of the tokens of the jwt auth backend",
+	)
+
+	cmd.Flags().BoolVar(
+		&params.rawConfig.AuthJWTAllowNoExpiry,
+		authJWTAllowNoExpiryFlag,
+		false,
+		"accept the tokens without an exp claim in the jwt auth backend, they never expire",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.AuthCache.TTL,
+		authCacheTTLFlag,
+		defaultConfig.AuthCache.TTL,
+		"how long a bearer accepted by the hub is cached, 0 disables the cache",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.AuthCache.NegativeTTL,
+		authCacheNegativeTTLFlag,
+		defaultConfig.AuthCache.NegativeTTL,
+		"how long a rejected bearer is cached, 0 disables the negative cache",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.AuthCache.StaleTTL,
+		authCacheStaleTTLFlag,
+		defaultConfig.AuthCache.StaleTTL,
+		"how long an expired bearer is still accepted while it is checked again in the background, 0 disables it",
+	)
+
 	cmd.Flags().Uint64Var(
 		&params.rawConfig.AppPort,
 		appPortFlag,
@@ -202,6 +276,343 @@ func setFlags(cmd *cobra.Command) {
 		"should the application no authentication required (default false)",
 	)
 
+	cmd.Flags().StringVar(
+		&params.rawConfig.AppHealth.Path,
+		appHealthPathFlag,
+		"",
+		"the health path of the upstream services of the edge node, checked with a GET, disabled if empty",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.AppHealth.Interval,
+		appHealthIntervalFlag,
+		defaultConfig.AppHealth.Interval,
+		"the interval of the health checks of the upstream services",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.AppHealth.Timeout,
+		appHealthTimeoutFlag,
+		defaultConfig.AppHealth.Timeout,
+		"the maximum duration of a health check",
+	)
+
+	cmd.Flags().IntVar(
+		&params.rawConfig.AppHealth.Failures,
+		appHealthFailuresFlag,
+		defaultConfig.AppHealth.Failures,
+		"the number of consecutive failed health checks before an upstream service is down",
+	)
+
+	cmd.Flags().IntVar(
+		&params.rawConfig.AppConcurrency.MaxConcurrent,
+		appMaxConcurrentFlag,
+		0,
+		"the number of requests served at once by each upstream service of the edge node, 0 is unlimited",
+	)
+
+	cmd.Flags().IntVar(
+		&params.rawConfig.AppConcurrency.MaxQueue,
+		appMaxQueueFlag,
+		defaultConfig.AppConcurrency.MaxQueue,
+		"the number of requests waiting for a free slot of an upstream service, the others are rejected with a 429",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.AppConcurrency.MaxQueueWait,
+		appMaxQueueWaitFlag,
+		defaultConfig.AppConcurrency.MaxQueueWait,
+		"the maximum time a request waits for a free slot before it is rejected with a 503, 0 is unlimited",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.AppBalancePolicy,
+		appBalancePolicyFlag,
+		defaultConfig.AppBalancePolicy,
+		"the policy for picking a node when routing by app name (round-robin, random, least-in-flight, lowest-latency)",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.AppPathPrefix,
+		appPathPrefixFlag,
+		defaultConfig.AppPathPrefix,
+		"the path prefix of the routing by app name, /<prefix>/<appName>/<path>, it shadows the legacy routes with the same prefix; empty disables it",
+	)
+
+	cmd.Flags().StringArrayVar(
+		&params.rawConfig.AppRoutePorts,
+		appRoutePortFlag,
+		nil,
+		"an appName=port mapping of the port reached when routing by app name, an app without a port is not routed",
+	)
+
+	cmd.Flags().IntVar(
+		&params.rawConfig.ProxyTransport.MaxConnsPerNode,
+		proxyMaxConnsPerNodeFlag,
+		defaultConfig.ProxyTransport.MaxConnsPerNode,
+		"the maximum number of libp2p streams from the transparent proxy to an edge node, 0 means no limit",
+	)
+
+	cmd.Flags().IntVar(
+		&params.rawConfig.ProxyTransport.MaxIdleConnsPerNode,
+		proxyMaxIdleConnsPerNodeFlag,
+		defaultConfig.ProxyTransport.MaxIdleConnsPerNode,
+		"the number of idle libp2p streams kept open to an edge node",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.ProxyTransport.IdleConnTimeout,
+		proxyIdleConnTimeoutFlag,
+		defaultConfig.ProxyTransport.IdleConnTimeout,
+		"how long an idle libp2p stream to an edge node is kept open",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.Telemetry.Tracing.Exporter,
+		tracingExporterFlag,
+		"",
+		"the exporter of the OpenTelemetry spans (otlp, stdout), disabled if empty",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.Telemetry.Tracing.Endpoint,
+		tracingEndpointFlag,
+		"",
+		"the host:port of the OTLP/HTTP collector, localhost:4318 if empty",
+	)
+
+	cmd.Flags().BoolVar(
+		&params.rawConfig.Telemetry.Tracing.Insecure,
+		tracingInsecureFlag,
+		false,
+		"send the spans to the OTLP collector over plain http",
+	)
+
+	cmd.Flags().Float64Var(
+		&params.rawConfig.Telemetry.Tracing.SampleRatio,
+		tracingSampleRatioFlag,
+		defaultConfig.Telemetry.Tracing.SampleRatio,
+		"the ratio of the traces sampled by this node, the sampling decision of the caller is kept",
+	)
+
+	cmd.Flags().StringArrayVar(
+		&params.rawConfig.ProxyStreamContentTypes,
+		proxyStreamContentTypesFlag,
+		nil,
+		fmt.Sprintf("a media type whose responses are streamed to the client as they are read, "+
+			"responses with an unknown length are always streamed (default %s)", strings.Join(proxy.DefaultStreamContentTypes, ", ")),
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.AccessLog.Sink,
+		accessLogFlag,
+		defaultConfig.AccessLog.Sink,
+		"the sink of the access logs of the transparent proxy and the edge node (stdout, stderr or a file path), disabled if empty",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.AccessLog.Format,
+		accessLogFormatFlag,
+		defaultConfig.AccessLog.Format,
+		"the format of the access logs (json, clf)",
+	)
+
+	cmd.Flags().BoolVar(
+		&params.rawConfig.OpenAIGateway.Enabled,
+		openAIGatewayFlag,
+		false,
+		"serve the OpenAI-compatible /v1/models, /v1/chat/completions and /v1/completions on the transparent proxy",
+	)
+
+	cmd.Flags().StringArrayVar(
+		&params.rawConfig.OpenAIGateway.Models,
+		openAIModelFlag,
+		nil,
+		"a model=appName mapping of the OpenAI gateway, a model without mapping is routed to the app of the same name",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.ProxyTLS.CertFile,
+		proxyTLSCertFileFlag,
+		"",
+		"the path to the certificate of the transparent proxy, enables https. Reloaded when the file changes",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.ProxyTLS.KeyFile,
+		proxyTLSKeyFileFlag,
+		"",
+		"the path to the private key of the certificate of the transparent proxy",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.ProxyTLS.ClientCAFile,
+		proxyTLSClientCAFileFlag,
+		"",
+		"the path to the CA certificates of the client certificates",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.ProxyTLS.ClientAuth,
+		proxyTLSClientAuthFlag,
+		"",
+		"the client certificate mode of the transparent proxy (optional, require), disabled if empty",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.ProxyPolicyFile,
+		proxyPolicyFileFlag,
+		"",
+		"the path to the NodeID allow/deny policy of the transparent proxy (.json, .yaml or .yml), reloaded on change or SIGHUP",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.ProxyHostDomain,
+		proxyHostDomainFlag,
+		"",
+		"route the requests to <port>--<nodeId>.<domain> by their Host header, with their path unchanged, disabled if empty",
+	)
+
+	cmd.Flags().BoolVar(
+		&params.rawConfig.ProxyUsageMetering,
+		proxyUsageMeteringFlag,
+		false,
+		"write a usage record for every request forwarded by the transparent proxy in the db directory of the data dir",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.ProxyUsageRetention,
+		proxyUsageRetentionFlag,
+		defaultConfig.ProxyUsageRetention,
+		"how long the usage records are kept, 0 keeps them forever",
+	)
+
+	cmd.Flags().IntVar(
+		&params.rawConfig.ProxyRetry.MaxRetries,
+		proxyRetryMaxFlag,
+		defaultConfig.ProxyRetry.MaxRetries,
+		"the number of retries of a failed forward in the transparent proxy, 0 disables retries",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.ProxyRetry.Backoff,
+		proxyRetryBackoffFlag,
+		defaultConfig.ProxyRetry.Backoff,
+		"the delay before the first retry, doubled on each retry",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.ProxyRetry.Budget,
+		proxyRetryBudgetFlag,
+		defaultConfig.ProxyRetry.Budget,
+		"the maximum time spent on all the attempts of a request",
+	)
+
+	cmd.Flags().Int64Var(
+		&params.rawConfig.ProxyRetry.MaxBodySize,
+		proxyRetryMaxBodySizeFlag,
+		defaultConfig.ProxyRetry.MaxBodySize,
+		"the maximum size in bytes of a request body buffered for retries",
+	)
+
+	cmd.Flags().BoolVar(
+		&params.rawConfig.ProxyRetry.NonIdempotent,
+		proxyRetryNonIdempotentFlag,
+		false,
+		"should POST and PATCH requests without an Idempotency-Key header be retried (default false)",
+	)
+
+	cmd.Flags().BoolVar(
+		&params.rawConfig.ProxyRetry.Failover,
+		proxyFailoverFlag,
+		false,
+		"should failed requests routed by app name be retried on another node advertising the app (default false)",
+	)
+
+	cmd.Flags().IntVar(
+		&params.rawConfig.ProxyBreaker.Failures,
+		proxyBreakerFailuresFlag,
+		defaultConfig.ProxyBreaker.Failures,
+		"the number of consecutive failed forwards which opens the circuit of a node, 0 disables it",
+	)
+
+	cmd.Flags().Float64Var(
+		&params.rawConfig.ProxyBreaker.ErrorRate,
+		proxyBreakerErrorRateFlag,
+		defaultConfig.ProxyBreaker.ErrorRate,
+		"the ratio of failed forwards in the window which opens the circuit of a node, 0 disables it",
+	)
+
+	cmd.Flags().IntVar(
+		&params.rawConfig.ProxyBreaker.MinRequests,
+		proxyBreakerMinRequestsFlag,
+		defaultConfig.ProxyBreaker.MinRequests,
+		"the number of forwards in the window before the error rate applies",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.ProxyBreaker.Window,
+		proxyBreakerWindowFlag,
+		defaultConfig.ProxyBreaker.Window,
+		"the window of the error rate of the circuit breaker",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.ProxyBreaker.OpenDuration,
+		proxyBreakerOpenDurationFlag,
+		defaultConfig.ProxyBreaker.OpenDuration,
+		"how long the circuit of a node stays open before a probe request is let through",
+	)
+
+	cmd.Flags().BoolVar(
+		&params.rawConfig.ProxyJobs.Enabled,
+		proxyJobsFlag,
+		false,
+		"enable the async job API of the transparent proxy, POST /jobs/<nodeId>/<port>/<path>, the jobs are kept in the db directory of the data dir",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.ProxyJobs.TTL,
+		proxyJobTTLFlag,
+		defaultConfig.ProxyJobs.TTL,
+		"how long a finished job and its result are kept",
+	)
+
+	cmd.Flags().StringVar(
+		&params.rawConfig.ProxyJobs.Timeout,
+		proxyJobTimeoutFlag,
+		defaultConfig.ProxyJobs.Timeout,
+		"the maximum duration of the forward of a job",
+	)
+
+	cmd.Flags().IntVar(
+		&params.rawConfig.ProxyJobs.Workers,
+		proxyJobWorkersFlag,
+		defaultConfig.ProxyJobs.Workers,
+		"the number of jobs forwarded at the same time",
+	)
+
+	cmd.Flags().Int64Var(
+		&params.rawConfig.ProxyJobs.MaxBodySize,
+		proxyJobMaxBodySizeFlag,
+		defaultConfig.ProxyJobs.MaxBodySize,
+		"the maximum size in bytes of the request and the result of a job",
+	)
+
+	cmd.Flags().IntVar(
+		&params.rawConfig.ProxyJobs.MaxQueued,
+		proxyJobMaxQueuedFlag,
+		defaultConfig.ProxyJobs.MaxQueued,
+		"the maximum number of unfinished jobs, the submissions above it are refused with 503",
+	)
+
+	cmd.Flags().StringArrayVar(
+		&params.rawConfig.ProxyJobs.CallbackAllowedHosts,
+		proxyJobCallbackHostFlag,
+		nil,
+		"a callback host allowed to resolve to a loopback, private or link-local address",
+	)
+
 	cmd.Flags().Uint64Var(
 		&params.rawConfig.TelePool.MaxSlots,
 		maxSlotsFlag,
@@ -0,0 +1,93 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/EdgeMatrixChain/edge-matrix-computing/command"
+	"github.com/EdgeMatrixChain/edge-matrix-computing/command/helper"
+	"github.com/EdgeMatrixChain/edge-matrix-computing/server/proto"
+)
+
+var (
+	params = &reportParams{}
+)
+
+const (
+	fromFlag   = "from"
+	toFlag     = "to"
+	windowFlag = "window"
+	keyFlag    = "key"
+	nodeIDFlag = "node-id"
+	csvFlag    = "csv"
+)
+
+type reportParams struct {
+	from   string
+	to     string
+	window time.Duration
+	key    string
+	nodeID string
+	csv    bool
+
+	report *proto.UsageReportResponse
+}
+
+// parseTime accepts a RFC 3339 time or a date, an empty value is an unbounded range
+func parseTime(flag string, value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Unix(), nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid --%s '%s', expected a RFC 3339 time or a date (YYYY-MM-DD)", flag, value)
+}
+
+func (p *reportParams) initReport(grpcAddress string) error {
+	from, err := parseTime(fromFlag, p.from)
+	if err != nil {
+		return err
+	}
+
+	to, err := parseTime(toFlag, p.to)
+	if err != nil {
+		return err
+	}
+
+	if p.window < 0 {
+		return fmt.Errorf("--%s must not be negative", windowFlag)
+	}
+
+	systemClient, err := helper.GetSystemClientConnection(grpcAddress)
+	if err != nil {
+		return err
+	}
+
+	report, err := systemClient.UsageReport(
+		context.Background(),
+		&proto.UsageReportRequest{
+			From:   from,
+			To:     to,
+			Window: int64(p.window.Seconds()),
+			Key:    p.key,
+			NodeId: p.nodeID,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	p.report = report
+
+	return nil
+}
+
+func (p *reportParams) getResult() command.CommandResult {
+	return newUsageReportResult(p.report, p.window > 0, p.csv)
+}
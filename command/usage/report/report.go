@@ -0,0 +1,76 @@
+package report
+
+import (
+	"github.com/EdgeMatrixChain/edge-matrix-computing/command"
+	"github.com/EdgeMatrixChain/edge-matrix-computing/command/helper"
+	"github.com/spf13/cobra"
+)
+
+func GetCommand() *cobra.Command {
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "Aggregates the usage records of the transparent proxy by time window, API key and node",
+		Run:   runCommand,
+	}
+
+	setFlags(reportCmd)
+
+	return reportCmd
+}
+
+func setFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(
+		&params.from,
+		fromFlag,
+		"",
+		"the start of the report (RFC 3339 time or YYYY-MM-DD), unbounded if empty",
+	)
+
+	cmd.Flags().StringVar(
+		&params.to,
+		toFlag,
+		"",
+		"the end of the report, excluded (RFC 3339 time or YYYY-MM-DD), unbounded if empty",
+	)
+
+	cmd.Flags().DurationVar(
+		&params.window,
+		windowFlag,
+		0,
+		"the period of the aggregates, e.g. 1h or 24h, the whole range is aggregated if 0",
+	)
+
+	cmd.Flags().StringVar(
+		&params.key,
+		keyFlag,
+		"",
+		"only report the usage of this key (e.g. key:0123456789abcdef) or client identity",
+	)
+
+	cmd.Flags().StringVar(
+		&params.nodeID,
+		nodeIDFlag,
+		"",
+		"only report the usage of this node",
+	)
+
+	cmd.Flags().BoolVar(
+		&params.csv,
+		csvFlag,
+		false,
+		"write the report in the CSV format",
+	)
+}
+
+func runCommand(cmd *cobra.Command, _ []string) {
+	outputter := command.InitializeOutputter(cmd)
+	defer outputter.WriteOutput()
+
+	if err := params.initReport(helper.GetGRPCAddress(cmd)); err != nil {
+		outputter.SetError(err)
+
+		return
+	}
+
+	outputter.SetCommandResult(params.getResult())
+}
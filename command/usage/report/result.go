@@ -0,0 +1,145 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/EdgeMatrixChain/edge-matrix-computing/command/helper"
+	"github.com/EdgeMatrixChain/edge-matrix-computing/server/proto"
+)
+
+type UsageAggregate struct {
+	WindowStart      string  `json:"window_start,omitempty"`
+	Key              string  `json:"key"`
+	NodeID           string  `json:"node_id"`
+	Requests         int64   `json:"requests"`
+	Errors           int64   `json:"errors"`
+	BytesIn          int64   `json:"bytes_in"`
+	BytesOut         int64   `json:"bytes_out"`
+	AvgDurationMs    float64 `json:"avg_duration_ms"`
+	AvgTTFBMs        float64 `json:"avg_ttfb_ms"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+}
+
+type UsageReportResult struct {
+	Enabled    bool              `json:"enabled"`
+	Aggregates []*UsageAggregate `json:"aggregates"`
+
+	csv bool
+}
+
+func newUsageReportResult(report *proto.UsageReportResponse, windowed bool, csv bool) *UsageReportResult {
+	result := &UsageReportResult{
+		Enabled:    report.Enabled,
+		Aggregates: make([]*UsageAggregate, len(report.Aggregates)),
+		csv:        csv,
+	}
+
+	for i, aggregate := range report.Aggregates {
+		result.Aggregates[i] = &UsageAggregate{
+			Key:              aggregate.Key,
+			NodeID:           aggregate.NodeId,
+			Requests:         aggregate.Requests,
+			Errors:           aggregate.Errors,
+			BytesIn:          aggregate.BytesIn,
+			BytesOut:         aggregate.BytesOut,
+			PromptTokens:     aggregate.PromptTokens,
+			CompletionTokens: aggregate.CompletionTokens,
+		}
+
+		if windowed {
+			result.Aggregates[i].WindowStart = time.Unix(aggregate.WindowStart, 0).UTC().Format(time.RFC3339)
+		}
+
+		if aggregate.Requests > 0 {
+			result.Aggregates[i].AvgDurationMs = aggregate.DurationMs / float64(aggregate.Requests)
+			result.Aggregates[i].AvgTTFBMs = aggregate.TtfbMs / float64(aggregate.Requests)
+		}
+	}
+
+	return result
+}
+
+func (r *UsageReportResult) GetOutput() string {
+	if r.csv {
+		return r.getCSVOutput()
+	}
+
+	var buffer bytes.Buffer
+
+	buffer.WriteString("\n[USAGE REPORT]\n")
+
+	if !r.Enabled {
+		buffer.WriteString("The usage records are disabled, see --proxy-usage-metering\n")
+
+		return buffer.String()
+	}
+
+	if len(r.Aggregates) == 0 {
+		buffer.WriteString("No usage records\n")
+
+		return buffer.String()
+	}
+
+	rows := make([]string, len(r.Aggregates)+1)
+	rows[0] = "Window|Key|NodeID|Requests|Errors|Bytes in|Bytes out|Avg duration (ms)|Avg TTFB (ms)|Tokens (prompt/completion)"
+	for i, aggregate := range r.Aggregates {
+		rows[i+1] = fmt.Sprintf("%s|%s|%s|%d|%d|%d|%d|%.1f|%.1f|%d/%d",
+			valueOrAll(aggregate.WindowStart), valueOrNone(aggregate.Key), aggregate.NodeID,
+			aggregate.Requests, aggregate.Errors, aggregate.BytesIn, aggregate.BytesOut,
+			aggregate.AvgDurationMs, aggregate.AvgTTFBMs, aggregate.PromptTokens, aggregate.CompletionTokens)
+	}
+	buffer.WriteString(helper.FormatList(rows))
+	buffer.WriteString("\n")
+
+	return buffer.String()
+}
+
+func (r *UsageReportResult) getCSVOutput() string {
+	var buffer bytes.Buffer
+
+	writer := csv.NewWriter(&buffer)
+	_ = writer.Write([]string{
+		"window_start", "key", "node_id", "requests", "errors", "bytes_in", "bytes_out",
+		"avg_duration_ms", "avg_ttfb_ms", "prompt_tokens", "completion_tokens",
+	})
+
+	for _, aggregate := range r.Aggregates {
+		_ = writer.Write([]string{
+			aggregate.WindowStart,
+			aggregate.Key,
+			aggregate.NodeID,
+			strconv.FormatInt(aggregate.Requests, 10),
+			strconv.FormatInt(aggregate.Errors, 10),
+			strconv.FormatInt(aggregate.BytesIn, 10),
+			strconv.FormatInt(aggregate.BytesOut, 10),
+			strconv.FormatFloat(aggregate.AvgDurationMs, 'f', 3, 64),
+			strconv.FormatFloat(aggregate.AvgTTFBMs, 'f', 3, 64),
+			strconv.FormatInt(aggregate.PromptTokens, 10),
+			strconv.FormatInt(aggregate.CompletionTokens, 10),
+		})
+	}
+	writer.Flush()
+
+	return buffer.String()
+}
+
+func valueOrAll(value string) string {
+	if value == "" {
+		return "all"
+	}
+
+	return value
+}
+
+func valueOrNone(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
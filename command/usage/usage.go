@@ -0,0 +1,27 @@
+package usage
+
+import (
+	"github.com/EdgeMatrixChain/edge-matrix-computing/command/helper"
+	"github.com/EdgeMatrixChain/edge-matrix-computing/command/usage/report"
+	"github.com/spf13/cobra"
+)
+
+func GetCommand() *cobra.Command {
+	usageCmd := &cobra.Command{
+		Use:   "usage",
+		Short: "Top level command for the usage records of the transparent proxy. Only accepts subcommands.",
+	}
+
+	helper.RegisterGRPCAddressFlag(usageCmd)
+
+	registerSubcommands(usageCmd)
+
+	return usageCmd
+}
+
+func registerSubcommands(baseCmd *cobra.Command) {
+	baseCmd.AddCommand(
+		// usage report
+		report.GetCommand(),
+	)
+}
@@ -0,0 +1,288 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader identifies a request on the relay, the edge node and the webapp
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the maximum length of a request id sent by a client
+const maxRequestIDLength = 128
+
+type AccessLogFormat string
+
+const (
+	AccessLogJSON AccessLogFormat = "json"
+	// AccessLogCLF is the Common Log Format followed by the fields it doesn't have
+	AccessLogCLF AccessLogFormat = "clf"
+)
+
+const (
+	AccessLogStdout = "stdout"
+	AccessLogStderr = "stderr"
+)
+
+// AccessLogConfig defines where and how the access logs are written
+type AccessLogConfig struct {
+	// Sink is stdout, stderr or the path of a file, the access logs are disabled if empty
+	Sink   string
+	Format AccessLogFormat
+}
+
+// AccessLogEntry is the access log of a request on one hop
+type AccessLogEntry struct {
+	Time       time.Time `json:"time"`
+	Hop        string    `json:"hop"`
+	RequestID  string    `json:"request_id"`
+	RemoteAddr string    `json:"remote_addr"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	NodeID     string    `json:"node_id,omitempty"`
+	Port       int       `json:"port,omitempty"`
+	Status     int       `json:"status"`
+	Bytes      int64     `json:"bytes"`
+	DurationMs float64   `json:"duration_ms"`
+	Principal  string    `json:"principal,omitempty"`
+}
+
+// AccessLogger writes an access log entry for every request
+type AccessLogger struct {
+	format AccessLogFormat
+
+	lock   sync.Mutex
+	writer io.Writer
+	closer io.Closer
+}
+
+// NewAccessLogger opens the sink of the access logs, it returns nil if the sink is empty
+func NewAccessLogger(config *AccessLogConfig) (*AccessLogger, error) {
+	if config == nil || config.Sink == "" {
+		return nil, nil
+	}
+
+	l := &AccessLogger{format: config.Format}
+
+	switch l.format {
+	case "":
+		l.format = AccessLogJSON
+	case AccessLogJSON, AccessLogCLF:
+	default:
+		return nil, fmt.Errorf("unknown access log format '%s'", config.Format)
+	}
+
+	switch config.Sink {
+	case AccessLogStdout:
+		l.writer = os.Stdout
+	case AccessLogStderr:
+		l.writer = os.Stderr
+	default:
+		file, err := os.OpenFile(config.Sink, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open access log: %w", err)
+		}
+
+		l.writer = file
+		l.closer = file
+	}
+
+	return l, nil
+}
+
+func (l *AccessLogger) Close() error {
+	if l == nil || l.closer == nil {
+		return nil
+	}
+
+	return l.closer.Close()
+}
+
+func (l *AccessLogger) write(entry *AccessLogEntry) {
+	var line []byte
+
+	if l.format == AccessLogCLF {
+		principal := entry.Principal
+		if principal == "" {
+			principal = "-"
+		}
+
+		host, _, err := net.SplitHostPort(entry.RemoteAddr)
+		if err != nil {
+			host = entry.RemoteAddr
+		}
+
+		line = []byte(fmt.Sprintf("%s - %s [%s] \"%s %s\" %d %d request_id=%s hop=%s node_id=%s port=%d duration_ms=%.3f\n",
+			host, principal, entry.Time.Format("02/Jan/2006:15:04:05 -0700"),
+			entry.Method, entry.Path, entry.Status, entry.Bytes,
+			entry.RequestID, entry.Hop, entry.NodeID, entry.Port, entry.DurationMs))
+	} else {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return
+		}
+
+		line = append(data, '\n')
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	_, _ = l.writer.Write(line)
+}
+
+// Handler assigns the request id, echoes it in the response and writes the access log of the hop.
+// It is safe to call on a nil AccessLogger, the request id is still assigned.
+func (l *AccessLogger) Handler(hop string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := ensureRequestID(r)
+		w.Header().Set(RequestIDHeader, requestID)
+
+		if l == nil {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		entry := &AccessLogEntry{
+			Time:       time.Now(),
+			Hop:        hop,
+			RequestID:  requestID,
+			RemoteAddr: r.RemoteAddr,
+			Method:     r.Method,
+			Path:       r.URL.Path,
+		}
+		recorder := &responseRecorder{ResponseWriter: w}
+
+		defer func() {
+			entry.Status = recorder.status
+			if entry.Status == 0 {
+				entry.Status = http.StatusOK
+			}
+			entry.Bytes = recorder.bytes
+			entry.DurationMs = float64(time.Since(entry.Time).Microseconds()) / 1000
+			l.write(entry)
+		}()
+
+		next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), "AccessLog", entry)))
+	})
+}
+
+// ensureRequestID returns the request id of the request, a new one is set if it has none or an invalid one
+func ensureRequestID(r *http.Request) string {
+	requestID := r.Header.Get(RequestIDHeader)
+	if !validRequestID(requestID) {
+		requestID = uuid.New().String()
+		r.Header.Set(RequestIDHeader, requestID)
+	}
+
+	return requestID
+}
+
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range requestID {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+// responseRecorder records the status, the size and the time of the first byte of the response
+type responseRecorder struct {
+	http.ResponseWriter
+	status    int
+	bytes     int64
+	firstByte time.Time
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+		r.firstByte = time.Now()
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+		r.firstByte = time.Now()
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+
+	return n, err
+}
+
+func (r *responseRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack is used by the websocket upgrades, the bytes of the tunnel are not counted
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("hijacking is not supported")
+	}
+
+	r.status = http.StatusSwitchingProtocols
+
+	return hijacker.Hijack()
+}
+
+// BearerPrincipal returns the principal of a bearer for the access logs, the bearer itself is never logged
+func BearerPrincipal(bearer string) string {
+	if bearer == "" {
+		return ""
+	}
+
+	return "key:" + hashKey(bearer)[:16]
+}
+
+// requestPrincipal returns the identity of the client certificate, else the principal of the bearer
+func requestPrincipal(r *http.Request, bearer string) string {
+	if identity := clientIdentity(r); identity != "" {
+		return identity
+	}
+
+	return BearerPrincipal(bearer)
+}
+
+// setAccessLogTarget records the target node of the request in its access log entry
+func setAccessLogTarget(r *http.Request, nodeID string, port int) {
+	if entry, ok := r.Context().Value("AccessLog").(*AccessLogEntry); ok {
+		entry.NodeID = nodeID
+		entry.Port = port
+	}
+}
+
+// setAccessLogPrincipal records the authenticated principal of the request in its access log entry
+func setAccessLogPrincipal(r *http.Request, principal string) {
+	if entry, ok := r.Context().Value("AccessLog").(*AccessLogEntry); ok {
+		entry.Principal = principal
+	}
+}
+
+// RequestID returns the request id assigned by the access log handler
+func RequestID(r *http.Request) string {
+	return r.Header.Get(RequestIDHeader)
+}
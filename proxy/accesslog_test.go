@@ -0,0 +1,160 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBufferAccessLogger returns an access logger writing to the buffer
+func newBufferAccessLogger(format AccessLogFormat) (*AccessLogger, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+
+	return &AccessLogger{format: format, writer: buf}, buf
+}
+
+// targetHandler records the target and the principal of the request, and answers with its request id
+func targetHandler(w http.ResponseWriter, r *http.Request) {
+	setAccessLogTarget(r, "node1", 9527)
+	setAccessLogPrincipal(r, BearerPrincipal("sk-alice"))
+
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write([]byte(RequestID(r)))
+}
+
+func TestAccessLogRequestID(t *testing.T) {
+	var nilLogger *AccessLogger
+
+	tests := []struct {
+		name      string
+		requestID string
+		accepted  bool
+	}{
+		{"none", "", false},
+		{"valid", "req-1234/abc", true},
+		{"with space", "req 1234", false},
+		{"non ascii", "req-é", false},
+		{"too long", strings.Repeat("a", maxRequestIDLength+1), false},
+		{"max length", strings.Repeat("a", maxRequestIDLength), true},
+	}
+
+	for _, logger := range []*AccessLogger{nilLogger, {format: AccessLogJSON, writer: &bytes.Buffer{}}} {
+		handler := logger.Handler("relay", http.HandlerFunc(targetHandler))
+
+		for _, test := range tests {
+			r := httptest.NewRequest(http.MethodGet, "/edge/node1/9527/v1", nil)
+			if test.requestID != "" {
+				r.Header.Set(RequestIDHeader, test.requestID)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			// the request id seen by the next handler is echoed in the response
+			requestID := w.Header().Get(RequestIDHeader)
+			assert.Equal(t, requestID, w.Body.String(), test.name)
+
+			if test.accepted {
+				assert.Equal(t, test.requestID, requestID, test.name)
+			} else {
+				_, err := uuid.Parse(requestID)
+				assert.NoError(t, err, test.name)
+			}
+		}
+	}
+}
+
+func TestAccessLogJSON(t *testing.T) {
+	logger, buf := newBufferAccessLogger(AccessLogJSON)
+
+	r := httptest.NewRequest(http.MethodPost, "/edge/node1/9527/v1?stream=true", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set(RequestIDHeader, "req-1")
+
+	w := httptest.NewRecorder()
+	logger.Handler("relay", http.HandlerFunc(targetHandler)).ServeHTTP(w, r)
+
+	var entry AccessLogEntry
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, byte('\n'), buf.Bytes()[buf.Len()-1])
+
+	assert.Equal(t, "relay", entry.Hop)
+	assert.Equal(t, "req-1", entry.RequestID)
+	assert.Equal(t, "10.0.0.1:1234", entry.RemoteAddr)
+	assert.Equal(t, http.MethodPost, entry.Method)
+	assert.Equal(t, "/edge/node1/9527/v1", entry.Path)
+	assert.Equal(t, "node1", entry.NodeID)
+	assert.Equal(t, 9527, entry.Port)
+	assert.Equal(t, http.StatusCreated, entry.Status)
+	assert.Equal(t, int64(len("req-1")), entry.Bytes)
+	assert.Equal(t, BearerPrincipal("sk-alice"), entry.Principal)
+	assert.GreaterOrEqual(t, entry.DurationMs, float64(0))
+
+	// the bearer itself is never logged
+	assert.NotContains(t, buf.String(), "sk-alice")
+}
+
+func TestAccessLogCLF(t *testing.T) {
+	logger, buf := newBufferAccessLogger(AccessLogCLF)
+
+	r := httptest.NewRequest(http.MethodGet, "/edge/node1/9527/v1", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set(RequestIDHeader, "req-1")
+
+	logger.Handler("edge", http.HandlerFunc(targetHandler)).ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Regexp(t, `^10\.0\.0\.1 - key:[0-9a-f]{16} \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /edge/node1/9527/v1" 201 5 `+
+		`request_id=req-1 hop=edge node_id=node1 port=9527 duration_ms=\d+\.\d{3}\n$`, buf.String())
+
+	// the unknown fields are written as a dash, and the status defaults to 200
+	buf.Reset()
+
+	r = httptest.NewRequest(http.MethodGet, "/alive", nil)
+	r.RemoteAddr = "pipe"
+	r.Header.Set(RequestIDHeader, "req-2")
+
+	logger.Handler("edge", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Regexp(t, `^pipe - - \[.+\] "GET /alive" 200 0 request_id=req-2 hop=edge node_id= port=0 `, buf.String())
+}
+
+func TestNewAccessLogger(t *testing.T) {
+	logger, err := NewAccessLogger(&AccessLogConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, logger)
+
+	_, err = NewAccessLogger(&AccessLogConfig{Sink: AccessLogStdout, Format: "xml"})
+	assert.ErrorContains(t, err, "unknown access log format 'xml'")
+
+	_, err = NewAccessLogger(&AccessLogConfig{Sink: filepath.Join(t.TempDir(), "missing", "access.log")})
+	assert.ErrorContains(t, err, "failed to open access log")
+
+	// the file sink is appended to, in json by default
+	sink := filepath.Join(t.TempDir(), "access.log")
+	require.NoError(t, os.WriteFile(sink, []byte("previous\n"), 0640))
+
+	logger, err = NewAccessLogger(&AccessLogConfig{Sink: sink})
+	require.NoError(t, err)
+	assert.Equal(t, AccessLogJSON, logger.format)
+
+	logger.write(&AccessLogEntry{Time: time.Now(), Hop: "relay", RequestID: "req-1", Status: http.StatusOK})
+	require.NoError(t, logger.Close())
+
+	data, err := os.ReadFile(sink)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, "previous", lines[0])
+	assert.Contains(t, lines[1], `"request_id":"req-1"`)
+}
@@ -0,0 +1,214 @@
+package proxy
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/EdgeMatrixChain/edge-matrix-core/core/application"
+)
+
+// DefaultAppPeersRefresh is the interval of the refresh of the app peer index
+const DefaultAppPeersRefresh = 5 * time.Second
+
+// AppPeerSource returns the app peer of a NodeID, it is implemented by the app peers syncer
+type AppPeerSource interface {
+	GetAppPeer(id string) *application.AppPeer
+}
+
+// AppPeerIndex keeps the app peers known to the syncer, indexed by NodeID, by lowercase NodeID and by app name,
+// so a request is routed without walking the peerstores.
+// The syncer only resolves a NodeID, so the index is refreshed from the candidate NodeIDs, which are the peers
+// of the relay and edge hosts, and from the NodeIDs already indexed or looked up.
+type AppPeerIndex struct {
+	source     AppPeerSource
+	candidates func() []string
+
+	lock    sync.RWMutex
+	peers   map[string]*application.AppPeer
+	byLower map[string]string
+	byApp   map[string]map[string]*application.AppPeer
+
+	closeCh   chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+func NewAppPeerIndex(source AppPeerSource, candidates func() []string) *AppPeerIndex {
+	return &AppPeerIndex{
+		source:     source,
+		candidates: candidates,
+		peers:      make(map[string]*application.AppPeer),
+		byLower:    make(map[string]string),
+		byApp:      make(map[string]map[string]*application.AppPeer),
+		closeCh:    make(chan struct{}),
+	}
+}
+
+// Start builds the index, then refreshes it on every interval
+func (x *AppPeerIndex) Start(interval time.Duration) {
+	x.Refresh()
+
+	x.wg.Add(1)
+	go func() {
+		defer x.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				x.Refresh()
+			case <-x.closeCh:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the refresh
+func (x *AppPeerIndex) Close() {
+	if x == nil {
+		return
+	}
+
+	x.closeOnce.Do(func() { close(x.closeCh) })
+	x.wg.Wait()
+}
+
+// Refresh resolves the candidate and indexed NodeIDs with the syncer and rebuilds the index,
+// the peers the syncer no longer knows are removed
+func (x *AppPeerIndex) Refresh() {
+	nodeIDs := make(map[string]bool)
+	if x.candidates != nil {
+		for _, nodeID := range x.candidates() {
+			nodeIDs[nodeID] = true
+		}
+	}
+
+	x.lock.RLock()
+	for nodeID := range x.peers {
+		nodeIDs[nodeID] = true
+	}
+	x.lock.RUnlock()
+
+	peers := make(map[string]*application.AppPeer, len(nodeIDs))
+	for nodeID := range nodeIDs {
+		if appPeer := x.source.GetAppPeer(nodeID); appPeer != nil {
+			peers[nodeID] = appPeer
+		}
+	}
+
+	byLower := make(map[string]string, len(peers))
+	byApp := make(map[string]map[string]*application.AppPeer)
+	for nodeID, appPeer := range peers {
+		byLower[strings.ToLower(nodeID)] = nodeID
+
+		if appPeer.AppName == "" {
+			continue
+		}
+		if byApp[appPeer.AppName] == nil {
+			byApp[appPeer.AppName] = make(map[string]*application.AppPeer)
+		}
+		byApp[appPeer.AppName][nodeID] = appPeer
+	}
+
+	x.lock.Lock()
+	defer x.lock.Unlock()
+
+	x.peers, x.byLower, x.byApp = peers, byLower, byApp
+}
+
+// GetAppPeer returns the app peer of the NodeID, a peer missing from the index is looked up and indexed
+func (x *AppPeerIndex) GetAppPeer(nodeID string) *application.AppPeer {
+	if x == nil {
+		return nil
+	}
+
+	x.lock.RLock()
+	appPeer, ok := x.peers[nodeID]
+	x.lock.RUnlock()
+
+	if ok {
+		return appPeer
+	}
+
+	appPeer = x.source.GetAppPeer(nodeID)
+	if appPeer != nil {
+		x.add(nodeID, appPeer)
+	}
+
+	return appPeer
+}
+
+func (x *AppPeerIndex) add(nodeID string, appPeer *application.AppPeer) {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+
+	x.peers[nodeID] = appPeer
+	x.byLower[strings.ToLower(nodeID)] = nodeID
+
+	if appPeer.AppName != "" {
+		if x.byApp[appPeer.AppName] == nil {
+			x.byApp[appPeer.AppName] = make(map[string]*application.AppPeer)
+		}
+		x.byApp[appPeer.AppName][nodeID] = appPeer
+	}
+}
+
+// GetAppPeers returns the app peers advertising the app name, keyed by NodeID
+func (x *AppPeerIndex) GetAppPeers(appName string) map[string]*application.AppPeer {
+	if x == nil {
+		return map[string]*application.AppPeer{}
+	}
+
+	x.lock.RLock()
+	defer x.lock.RUnlock()
+
+	appPeers := make(map[string]*application.AppPeer, len(x.byApp[appName]))
+	for nodeID, appPeer := range x.byApp[appName] {
+		appPeers[nodeID] = appPeer
+	}
+
+	return appPeers
+}
+
+// ListAppPeers returns all the indexed app peers, keyed by NodeID
+func (x *AppPeerIndex) ListAppPeers() map[string]*application.AppPeer {
+	if x == nil {
+		return map[string]*application.AppPeer{}
+	}
+
+	x.lock.RLock()
+	defer x.lock.RUnlock()
+
+	appPeers := make(map[string]*application.AppPeer, len(x.peers))
+	for nodeID, appPeer := range x.peers {
+		appPeers[nodeID] = appPeer
+	}
+
+	return appPeers
+}
+
+// ResolveNodeID returns the NodeID matching the label case-insensitively, browsers lowercase the host
+func (x *AppPeerIndex) ResolveNodeID(label string) (string, bool) {
+	if x == nil {
+		return "", false
+	}
+
+	x.lock.RLock()
+	nodeID, ok := x.byLower[strings.ToLower(label)]
+	x.lock.RUnlock()
+
+	if ok {
+		return nodeID, true
+	}
+
+	// the label may be a NodeID which is not indexed yet
+	if x.GetAppPeer(label) != nil {
+		return label, true
+	}
+
+	return "", false
+}
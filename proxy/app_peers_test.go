@@ -0,0 +1,120 @@
+package proxy
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/EdgeMatrixChain/edge-matrix-core/core/application"
+	"github.com/stretchr/testify/assert"
+)
+
+type testPeerSource struct {
+	lock    sync.Mutex
+	peers   map[string]*application.AppPeer
+	lookups int
+}
+
+func (s *testPeerSource) GetAppPeer(id string) *application.AppPeer {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.lookups++
+
+	return s.peers[id]
+}
+
+func (s *testPeerSource) set(id string, appPeer *application.AppPeer) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if appPeer == nil {
+		delete(s.peers, id)
+	} else {
+		s.peers[id] = appPeer
+	}
+}
+
+func TestAppPeerIndex(t *testing.T) {
+	source := &testPeerSource{peers: map[string]*application.AppPeer{
+		"16Uiu2HAkA": {AppName: "llm"},
+		"16Uiu2HAkB": {AppName: "llm"},
+		"16Uiu2HAkC": {AppName: "sd"},
+		"16Uiu2HAkD": {},
+	}}
+	candidates := []string{"16Uiu2HAkA", "16Uiu2HAkB", "16Uiu2HAkC", "16Uiu2HAkD", "16Uiu2HAkUnknown"}
+
+	index := NewAppPeerIndex(source, func() []string { return candidates })
+	index.Refresh()
+
+	assert.Len(t, index.GetAppPeers("llm"), 2)
+	assert.Len(t, index.GetAppPeers("sd"), 1)
+	assert.Empty(t, index.GetAppPeers("none"))
+	assert.Len(t, index.ListAppPeers(), 4)
+
+	// the lookups are served by the index
+	lookups := source.lookups
+	assert.NotNil(t, index.GetAppPeer("16Uiu2HAkA"))
+	index.GetAppPeers("llm")
+	assert.Equal(t, lookups, source.lookups)
+
+	nodeID, ok := index.ResolveNodeID("16uiu2hakc")
+	assert.True(t, ok)
+	assert.Equal(t, "16Uiu2HAkC", nodeID)
+
+	_, ok = index.ResolveNodeID("16uiu2hakunknown")
+	assert.False(t, ok)
+}
+
+func TestAppPeerIndexSyncerOnlyPeer(t *testing.T) {
+	source := &testPeerSource{peers: map[string]*application.AppPeer{
+		"16Uiu2HAkE": {AppName: "llm"},
+	}}
+
+	// the peer is not in the peerstores
+	index := NewAppPeerIndex(source, func() []string { return nil })
+	index.Refresh()
+	assert.Empty(t, index.GetAppPeers("llm"))
+
+	// once addressed by NodeID, it is indexed and kept by the refresh
+	assert.NotNil(t, index.GetAppPeer("16Uiu2HAkE"))
+	index.Refresh()
+	assert.Len(t, index.GetAppPeers("llm"), 1)
+
+	nodeID, ok := index.ResolveNodeID("16uiu2hake")
+	assert.True(t, ok)
+	assert.Equal(t, "16Uiu2HAkE", nodeID)
+
+	// it is removed once the syncer drops it
+	source.set("16Uiu2HAkE", nil)
+	index.Refresh()
+	assert.Empty(t, index.GetAppPeers("llm"))
+	assert.Nil(t, index.GetAppPeer("16Uiu2HAkE"))
+}
+
+func TestAppPeerIndexAppChange(t *testing.T) {
+	source := &testPeerSource{peers: map[string]*application.AppPeer{
+		"16Uiu2HAkA": {AppName: "llm"},
+	}}
+
+	index := NewAppPeerIndex(source, func() []string { return []string{"16Uiu2HAkA"} })
+	index.Refresh()
+	assert.Len(t, index.GetAppPeers("llm"), 1)
+
+	source.set("16Uiu2HAkA", &application.AppPeer{AppName: "sd"})
+	index.Refresh()
+	assert.Empty(t, index.GetAppPeers("llm"))
+	assert.Len(t, index.GetAppPeers("sd"), 1)
+}
+
+func TestAppPeerIndexNil(t *testing.T) {
+	var index *AppPeerIndex
+
+	assert.Nil(t, index.GetAppPeer("16Uiu2HAkA"))
+	assert.Empty(t, index.GetAppPeers("llm"))
+	assert.Empty(t, index.ListAppPeers())
+
+	_, ok := index.ResolveNodeID("16uiu2hakA")
+	assert.False(t, ok)
+
+	index.Close()
+}
@@ -0,0 +1,229 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// AppPathPrefix is the default path prefix for routing by application name, e.g. /app/<appName>/<path>
+const AppPathPrefix = "app"
+
+type BalancePolicy string
+
+const (
+	BalanceRoundRobin    BalancePolicy = "round-robin"
+	BalanceRandom        BalancePolicy = "random"
+	BalanceLeastInFlight BalancePolicy = "least-in-flight"
+	BalanceLowestLatency BalancePolicy = "lowest-latency"
+
+	DefaultBalancePolicy = BalanceRoundRobin
+)
+
+// latencyDecay is the weight of the latest sample in the latency moving average
+const latencyDecay = 0.3
+
+// loadTTL is how long the load advertised by a node is used for routing
+const loadTTL = 10 * time.Second
+
+// statsTTL is how long the stats of a node without requests are kept, the nodes which are gone are pruned
+const statsTTL = 10 * time.Minute
+
+var (
+	errNoAppPeer = errors.New("no node found for app")
+	errNoAppPort = errors.New("no port declared for app")
+)
+
+// ParseBalancePolicy validates the name of a balance policy
+func ParseBalancePolicy(name string) (BalancePolicy, error) {
+	switch policy := BalancePolicy(name); policy {
+	case BalanceRoundRobin, BalanceRandom, BalanceLeastInFlight, BalanceLowestLatency:
+		return policy, nil
+	case "":
+		return DefaultBalancePolicy, nil
+	default:
+		return "", fmt.Errorf("unknown balance policy '%s'", name)
+	}
+}
+
+type nodeStats struct {
+	inFlight int64
+	latency  time.Duration
+	// load is advertised by the node in the X-Edge-Load header, 1 or more when it is saturated
+	load   float64
+	loadAt time.Time
+	// usedAt is the last time the node was picked or responded
+	usedAt time.Time
+}
+
+// appBalancer picks a node among all the nodes advertising the same application
+type appBalancer struct {
+	policy BalancePolicy
+
+	lock     sync.Mutex
+	counters map[string]uint64
+	stats    map[string]*nodeStats
+	prunedAt time.Time
+}
+
+func newAppBalancer(policy BalancePolicy) *appBalancer {
+	return &appBalancer{
+		policy:   policy,
+		counters: make(map[string]uint64),
+		stats:    make(map[string]*nodeStats),
+		prunedAt: time.Now(),
+	}
+}
+
+// pick returns one of the candidate nodes according to the policy
+func (b *appBalancer) pick(appName string, candidates []string) (string, error) {
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("%w %s", errNoAppPeer, appName)
+	}
+
+	sort.Strings(candidates)
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.prune()
+
+	switch b.policy {
+	case BalanceRandom:
+		return candidates[rand.Intn(len(candidates))], nil
+	case BalanceLeastInFlight:
+		return b.pickMin(candidates, func(stats *nodeStats) int64 {
+			return stats.inFlight
+		}), nil
+	case BalanceLowestLatency:
+		// nodes without samples are tried first
+		return b.pickMin(candidates, func(stats *nodeStats) int64 {
+			return int64(stats.latency)
+		}), nil
+	default:
+		counter := b.counters[appName]
+		b.counters[appName] = counter + 1
+
+		return candidates[counter%uint64(len(candidates))], nil
+	}
+}
+
+func (b *appBalancer) pickMin(candidates []string, value func(stats *nodeStats) int64) string {
+	picked := candidates[0]
+	min := int64(-1)
+
+	for _, nodeID := range candidates {
+		v := int64(0)
+		if stats, ok := b.stats[nodeID]; ok {
+			v = value(stats)
+		}
+
+		if min < 0 || v < min {
+			picked, min = nodeID, v
+		}
+	}
+
+	return picked
+}
+
+// acquire marks a request to the node as in flight
+func (b *appBalancer) acquire(nodeID string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.getStats(nodeID).inFlight++
+}
+
+// release marks a request to the node as done
+func (b *appBalancer) release(nodeID string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if stats := b.getStats(nodeID); stats.inFlight > 0 {
+		stats.inFlight--
+	}
+}
+
+// observeLatency records the time the node took to respond
+func (b *appBalancer) observeLatency(nodeID string, latency time.Duration) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	stats := b.getStats(nodeID)
+	if stats.latency == 0 {
+		stats.latency = latency
+	} else {
+		stats.latency = time.Duration(latencyDecay*float64(latency) + (1-latencyDecay)*float64(stats.latency))
+	}
+}
+
+// observeLoad records the load advertised by the node in its response
+func (b *appBalancer) observeLoad(nodeID string, header http.Header) {
+	value := header.Get(EdgeLoadHeader)
+	if value == "" {
+		return
+	}
+
+	load, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	stats := b.getStats(nodeID)
+	stats.load = load
+	stats.loadAt = time.Now()
+}
+
+// filterBusy removes the nodes which recently advertised a saturated service, all the nodes are returned if they are all busy
+func (b *appBalancer) filterBusy(nodeIDs []string) []string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	idle := make([]string, 0, len(nodeIDs))
+	for _, nodeID := range nodeIDs {
+		if stats, ok := b.stats[nodeID]; ok && stats.load >= 1 && time.Since(stats.loadAt) < loadTTL {
+			continue
+		}
+		idle = append(idle, nodeID)
+	}
+
+	if len(idle) == 0 {
+		return nodeIDs
+	}
+
+	return idle
+}
+
+func (b *appBalancer) getStats(nodeID string) *nodeStats {
+	stats, ok := b.stats[nodeID]
+	if !ok {
+		stats = &nodeStats{}
+		b.stats[nodeID] = stats
+	}
+	stats.usedAt = time.Now()
+
+	return stats
+}
+
+// prune removes the stats of the nodes without requests for statsTTL, at most once per statsTTL
+func (b *appBalancer) prune() {
+	now := time.Now()
+	if now.Sub(b.prunedAt) < statsTTL {
+		return
+	}
+	b.prunedAt = now
+
+	for nodeID, stats := range b.stats {
+		if stats.inFlight == 0 && now.Sub(stats.usedAt) >= statsTTL {
+			delete(b.stats, nodeID)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/EdgeMatrixChain/edge-matrix-core/core/application"
+	"github.com/libp2p/go-libp2p"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBalancePolicy(t *testing.T) {
+	policy, err := ParseBalancePolicy("")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultBalancePolicy, policy)
+
+	policy, err = ParseBalancePolicy("least-in-flight")
+	require.NoError(t, err)
+	assert.Equal(t, BalanceLeastInFlight, policy)
+
+	_, err = ParseBalancePolicy("fastest")
+	assert.Error(t, err)
+}
+
+func TestBalancerNoCandidate(t *testing.T) {
+	b := newAppBalancer(BalanceRoundRobin)
+
+	_, err := b.pick("llm", nil)
+	assert.ErrorIs(t, err, errNoAppPeer)
+}
+
+func TestBalancerRoundRobin(t *testing.T) {
+	b := newAppBalancer(BalanceRoundRobin)
+
+	picked := make([]string, 0, 4)
+	for i := 0; i < 4; i++ {
+		// the candidates are sorted, so the order of the peers map doesn't matter
+		nodeID, err := b.pick("llm", []string{"b", "a"})
+		require.NoError(t, err)
+		picked = append(picked, nodeID)
+	}
+	assert.Equal(t, []string{"a", "b", "a", "b"}, picked)
+
+	// each app has its own counter
+	nodeID, err := b.pick("sd", []string{"b", "a"})
+	require.NoError(t, err)
+	assert.Equal(t, "a", nodeID)
+}
+
+func TestBalancerRandom(t *testing.T) {
+	b := newAppBalancer(BalanceRandom)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 200; i++ {
+		nodeID, err := b.pick("llm", []string{"a", "b", "c"})
+		require.NoError(t, err)
+		seen[nodeID] = true
+	}
+	assert.Len(t, seen, 3)
+}
+
+func TestBalancerLeastInFlight(t *testing.T) {
+	b := newAppBalancer(BalanceLeastInFlight)
+
+	b.acquire("a")
+	b.acquire("a")
+	b.acquire("b")
+
+	nodeID, err := b.pick("llm", []string{"a", "b", "c"})
+	require.NoError(t, err)
+	assert.Equal(t, "c", nodeID)
+
+	b.acquire("c")
+	b.acquire("c")
+	b.release("a")
+	b.release("a")
+
+	nodeID, err = b.pick("llm", []string{"a", "b", "c"})
+	require.NoError(t, err)
+	assert.Equal(t, "a", nodeID)
+
+	// a release without acquire doesn't go negative
+	b.release("d")
+	assert.Equal(t, int64(0), b.stats["d"].inFlight)
+}
+
+func TestBalancerLowestLatency(t *testing.T) {
+	b := newAppBalancer(BalanceLowestLatency)
+
+	b.observeLatency("a", 100*time.Millisecond)
+	b.observeLatency("b", 20*time.Millisecond)
+
+	nodeID, err := b.pick("llm", []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, "b", nodeID)
+
+	// a node without samples is tried first
+	nodeID, err = b.pick("llm", []string{"a", "b", "c"})
+	require.NoError(t, err)
+	assert.Equal(t, "c", nodeID)
+
+	// the moving average follows the new samples
+	for i := 0; i < 10; i++ {
+		b.observeLatency("b", 500*time.Millisecond)
+	}
+	nodeID, err = b.pick("llm", []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, "a", nodeID)
+}
+
+func TestBalancerFilterBusy(t *testing.T) {
+	b := newAppBalancer(BalanceRoundRobin)
+
+	header := http.Header{}
+	header.Set(EdgeLoadHeader, "1.50")
+	b.observeLoad("a", header)
+	header.Set(EdgeLoadHeader, "0.50")
+	b.observeLoad("b", header)
+
+	assert.Equal(t, []string{"b", "c"}, b.filterBusy([]string{"a", "b", "c"}))
+
+	// all the nodes are returned when they are all busy
+	assert.Equal(t, []string{"a"}, b.filterBusy([]string{"a"}))
+
+	// an old load is ignored
+	b.stats["a"].loadAt = time.Now().Add(-loadTTL)
+	assert.Equal(t, []string{"a", "b"}, b.filterBusy([]string{"a", "b"}))
+}
+
+func TestBalancerPrune(t *testing.T) {
+	b := newAppBalancer(BalanceLeastInFlight)
+
+	b.acquire("busy")
+	b.observeLatency("gone", time.Second)
+	b.observeLatency("recent", time.Second)
+
+	old := time.Now().Add(-statsTTL)
+	b.stats["busy"].usedAt = old
+	b.stats["gone"].usedAt = old
+	b.prunedAt = old
+
+	_, err := b.pick("llm", []string{"recent"})
+	require.NoError(t, err)
+
+	assert.Contains(t, b.stats, "busy")
+	assert.Contains(t, b.stats, "recent")
+	assert.NotContains(t, b.stats, "gone")
+}
+
+// portStore records the ports the bearers are authorized for
+type portStore struct {
+	*testStore
+	ports []int
+}
+
+func (s *portStore) AuthBearer(bearer string, nodeId string, port int) (bool, string) {
+	s.ports = append(s.ports, port)
+
+	return s.testStore.AuthBearer(bearer, nodeId, port)
+}
+
+func TestAppRoutePort(t *testing.T) {
+	relayHost, err := libp2p.New(libp2p.NoListenAddrs)
+	require.NoError(t, err)
+	defer relayHost.Close()
+
+	store := &portStore{testStore: &testStore{
+		relayHost: relayHost,
+		appPeers: map[string]*application.AppPeer{
+			"a": {AppName: "llm"},
+			"b": {AppName: "sd"},
+		},
+		keys: map[string]bool{"good": true},
+	}}
+	j := newTestProxy(&Config{Store: store, AppPorts: map[string]int{"llm": 8080}})
+
+	var (
+		routed        *EdgePath
+		forwardedPort string
+	)
+	handler := j.bearerMiddlewareFactory(newEdgePathParser(AppPathPrefix))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		routed, _ = r.Context().Value("EdgePath").(*EdgePath)
+		forwardedPort = r.Header.Get("X-Forwarded-EdgePort")
+	}))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "http://relay"+path, nil)
+		r.Header.Set("Authorization", "Bearer good")
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		return w
+	}
+
+	// the port of the app is resolved before the bearer is authorized
+	w := serve("/app/llm/v1/chat")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, routed)
+	assert.Equal(t, "a", routed.NodeID)
+	assert.Equal(t, 8080, routed.Port)
+	assert.Equal(t, "8080", forwardedPort)
+	assert.Equal(t, []int{8080}, store.ports)
+
+	// an app without a declared port is not routed
+	w = serve("/app/sd/v1/chat")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "no port declared for app 'sd'")
+
+	// the policy is evaluated with the port of the app
+	j.policy = &PolicyEngine{policy: &Policy{Rules: []PolicyRule{{Name: "no-llm", Action: PolicyDeny, Ports: []int{8080}}}}}
+	w = serve("/app/llm/v1/chat")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, []int{8080}, store.ports)
+}
+
+func TestAppPathPrefix(t *testing.T) {
+	tests := []struct {
+		prefix  string
+		path    string
+		appName string
+		nodeID  string
+		port    int
+	}{
+		{AppPathPrefix, "/app/llm/v1/chat", "llm", "", 0},
+		{AppPathPrefix, "/edge/16Uiu2HAmGood/9527/v1/chat", "", "16Uiu2HAmGood", 9527},
+		// the legacy routes under the prefix are reachable once the prefix is moved or disabled
+		{"apps", "/apps/llm/v1/chat", "llm", "", 0},
+		{"apps", "/app/16Uiu2HAmGood/9527/v1/chat", "", "16Uiu2HAmGood", 9527},
+		{"", "/app/16Uiu2HAmGood/9527/v1/chat", "", "16Uiu2HAmGood", 9527},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, "http://relay"+test.path, nil)
+
+		pathInfo, err := newEdgePathParser(test.prefix)(r)
+		require.NoError(t, err, test.path)
+		assert.Equal(t, test.appName, pathInfo.AppName, test.path)
+		assert.Equal(t, test.nodeID, pathInfo.NodeID, test.path)
+		assert.Equal(t, test.port, pathInfo.Port, test.path)
+		assert.Equal(t, "v1/chat", pathInfo.InterfaceURL, test.path)
+	}
+}
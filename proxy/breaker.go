@@ -0,0 +1,350 @@
+package proxy
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+)
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+const (
+	DefaultBreakerFailures     = 5
+	DefaultBreakerErrorRate    = 0.5
+	DefaultBreakerMinRequests  = 20
+	DefaultBreakerWindow       = time.Minute
+	DefaultBreakerOpenDuration = 30 * time.Second
+)
+
+// BreakerConfig defines when the circuit of a node opens
+type BreakerConfig struct {
+	// ConsecutiveFailures opens the circuit after this number of failures in a row, 0 disables it
+	ConsecutiveFailures int
+	// ErrorRate opens the circuit when the ratio of failures in the window reaches it, 0 disables it
+	ErrorRate float64
+	// MinRequests is the number of requests in the window before the error rate applies
+	MinRequests int
+	// Window is the period over which the error rate is computed
+	Window time.Duration
+	// OpenDuration is how long the circuit stays open before a probe request is let through
+	OpenDuration time.Duration
+}
+
+// DefaultBreakerConfig returns the default circuit breaker config
+func DefaultBreakerConfig() *BreakerConfig {
+	return &BreakerConfig{
+		ConsecutiveFailures: DefaultBreakerFailures,
+		ErrorRate:           DefaultBreakerErrorRate,
+		MinRequests:         DefaultBreakerMinRequests,
+		Window:              DefaultBreakerWindow,
+		OpenDuration:        DefaultBreakerOpenDuration,
+	}
+}
+
+// BreakerStatus is the state of the circuit of a node
+type BreakerStatus struct {
+	NodeID              string
+	State               BreakerState
+	ConsecutiveFailures int
+	Requests            int
+	Failures            int
+	OpenedAt            time.Time
+	RetryAt             time.Time
+}
+
+// nodeBreaker is the circuit of a single node
+type nodeBreaker struct {
+	state               BreakerState
+	consecutiveFailures int
+	windowStart         time.Time
+	requests            int
+	failures            int
+	openedAt            time.Time
+	// probing is true while the probe request of a half-open circuit is in flight
+	probing bool
+	// usedAt is the last time a request to the node was allowed or recorded
+	usedAt time.Time
+}
+
+// Breakers holds a circuit breaker by NodeID, it stops the forwards to a failing node
+// until a probe request succeeds
+type Breakers struct {
+	config *BreakerConfig
+
+	lock     sync.Mutex
+	breakers map[string]*nodeBreaker
+	prunedAt time.Time
+}
+
+func NewBreakers(config *BreakerConfig) *Breakers {
+	return &Breakers{
+		config:   config,
+		breakers: make(map[string]*nodeBreaker),
+		prunedAt: time.Now(),
+	}
+}
+
+// prune removes the circuits of the nodes without requests for statsTTL, at most once per statsTTL.
+// An open circuit is kept until it would let a probe through, so pruning never closes it early.
+func (b *Breakers) prune(now time.Time) {
+	if now.Sub(b.prunedAt) < statsTTL {
+		return
+	}
+	b.prunedAt = now
+
+	for nodeID, breaker := range b.breakers {
+		if breaker.probing || now.Sub(breaker.usedAt) < statsTTL {
+			continue
+		}
+
+		if breaker.state == BreakerOpen && now.Before(breaker.openedAt.Add(b.config.OpenDuration)) {
+			continue
+		}
+
+		delete(b.breakers, nodeID)
+
+		if breaker.state != BreakerClosed {
+			metrics.SetGaugeWithLabels([]string{proxyMetrics, "breaker_open"}, 0, []metrics.Label{{Name: "node_id", Value: nodeID}})
+		}
+	}
+}
+
+func (b *Breakers) get(nodeID string, now time.Time) *nodeBreaker {
+	breaker, ok := b.breakers[nodeID]
+	if !ok {
+		breaker = &nodeBreaker{state: BreakerClosed, windowStart: now}
+		b.breakers[nodeID] = breaker
+	}
+	breaker.usedAt = now
+
+	if b.config.Window > 0 && now.Sub(breaker.windowStart) >= b.config.Window {
+		breaker.windowStart = now
+		breaker.requests = 0
+		breaker.failures = 0
+	}
+
+	return breaker
+}
+
+// allow returns true if a request can be forwarded to the node, else the delay before the circuit half-opens.
+// It is safe to call on nil Breakers.
+func (b *Breakers) allow(nodeID string) (bool, time.Duration) {
+	if b == nil {
+		return true, 0
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := time.Now()
+	b.prune(now)
+	breaker := b.get(nodeID, now)
+
+	switch breaker.state {
+	case BreakerOpen:
+		retryAt := breaker.openedAt.Add(b.config.OpenDuration)
+		if now.Before(retryAt) {
+			return false, retryAt.Sub(now)
+		}
+
+		b.transition(nodeID, breaker, BreakerHalfOpen)
+		breaker.probing = true
+
+		return true, 0
+	case BreakerHalfOpen:
+		if breaker.probing {
+			return false, b.config.OpenDuration
+		}
+		breaker.probing = true
+
+		return true, 0
+	default:
+		return true, 0
+	}
+}
+
+// isOpen returns true if the requests to the node are rejected
+func (b *Breakers) isOpen(nodeID string) bool {
+	if b == nil {
+		return false
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	breaker, ok := b.breakers[nodeID]
+	if !ok {
+		return false
+	}
+
+	switch breaker.state {
+	case BreakerOpen:
+		return time.Since(breaker.openedAt) < b.config.OpenDuration
+	case BreakerHalfOpen:
+		return breaker.probing
+	default:
+		return false
+	}
+}
+
+// record counts the outcome of a forward allowed by the breaker of the node
+func (b *Breakers) record(nodeID string, success bool) {
+	if b == nil {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	breaker := b.get(nodeID, time.Now())
+	breaker.probing = false
+	breaker.requests++
+
+	if success {
+		breaker.consecutiveFailures = 0
+		if breaker.state != BreakerClosed {
+			b.transition(nodeID, breaker, BreakerClosed)
+		}
+
+		return
+	}
+
+	breaker.failures++
+	breaker.consecutiveFailures++
+
+	switch {
+	case breaker.state == BreakerHalfOpen:
+		b.open(nodeID, breaker)
+	case b.config.ConsecutiveFailures > 0 && breaker.consecutiveFailures >= b.config.ConsecutiveFailures:
+		b.open(nodeID, breaker)
+	case b.config.ErrorRate > 0 && breaker.requests >= b.config.MinRequests &&
+		float64(breaker.failures)/float64(breaker.requests) >= b.config.ErrorRate:
+		b.open(nodeID, breaker)
+	}
+}
+
+// cancel releases the probe of a half-open circuit without an outcome, e.g. the client went away
+func (b *Breakers) cancel(nodeID string) {
+	if b == nil {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if breaker, ok := b.breakers[nodeID]; ok {
+		breaker.probing = false
+	}
+}
+
+func (b *Breakers) open(nodeID string, breaker *nodeBreaker) {
+	breaker.openedAt = time.Now()
+	b.transition(nodeID, breaker, BreakerOpen)
+}
+
+func (b *Breakers) transition(nodeID string, breaker *nodeBreaker, state BreakerState) {
+	breaker.state = state
+	if state == BreakerClosed {
+		breaker.windowStart = time.Now()
+		breaker.requests = 0
+		breaker.failures = 0
+	}
+
+	labels := []metrics.Label{{Name: "node_id", Value: nodeID}}
+	metrics.SetGaugeWithLabels([]string{proxyMetrics, "breaker_open"}, boolGauge(state != BreakerClosed), labels)
+	metrics.IncrCounterWithLabels([]string{proxyMetrics, "breaker_transitions"}, 1, append(labels, metrics.Label{Name: "state", Value: string(state)}))
+}
+
+func boolGauge(value bool) float32 {
+	if value {
+		return 1
+	}
+
+	return 0
+}
+
+// Status returns the circuits which are not closed or have failures, sorted by NodeID
+func (b *Breakers) Status() []*BreakerStatus {
+	if b == nil {
+		return nil
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	statuses := make([]*BreakerStatus, 0)
+	for nodeID, breaker := range b.breakers {
+		if breaker.state == BreakerClosed && breaker.failures == 0 {
+			continue
+		}
+
+		status := &BreakerStatus{
+			NodeID:              nodeID,
+			State:               breaker.state,
+			ConsecutiveFailures: breaker.consecutiveFailures,
+			Requests:            breaker.requests,
+			Failures:            breaker.failures,
+		}
+		if breaker.state != BreakerClosed {
+			status.OpenedAt = breaker.openedAt
+			status.RetryAt = breaker.openedAt.Add(b.config.OpenDuration)
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, k int) bool {
+		return statuses[i].NodeID < statuses[k].NodeID
+	})
+
+	return statuses
+}
+
+// filterOpen removes the nodes whose circuit is open, all the nodes are returned if they are all open
+func (b *Breakers) filterOpen(nodeIDs []string) []string {
+	if b == nil {
+		return nodeIDs
+	}
+
+	closed := make([]string, 0, len(nodeIDs))
+	for _, nodeID := range nodeIDs {
+		if !b.isOpen(nodeID) {
+			closed = append(closed, nodeID)
+		}
+	}
+
+	if len(closed) == 0 {
+		return nodeIDs
+	}
+
+	return closed
+}
+
+// isNodeFailure returns true if the response of the edge node means it could not serve the request,
+// including the requests it refused because its upstream service is down
+func isNodeFailure(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return true
+	case http.StatusServiceUnavailable:
+		return resp.Header.Get(EdgeHealthHeader) == string(HealthDown)
+	default:
+		return false
+	}
+}
+
+// setRetryAfter sets the Retry-After header in whole seconds, at least 1
+func setRetryAfter(w http.ResponseWriter, delay time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Max(1, math.Ceil(delay.Seconds())))))
+}
@@ -0,0 +1,210 @@
+package proxy
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBreakers(config *BreakerConfig) *Breakers {
+	if config.OpenDuration == 0 {
+		config.OpenDuration = time.Hour
+	}
+
+	return NewBreakers(config)
+}
+
+func TestBreakerConsecutiveFailures(t *testing.T) {
+	b := newTestBreakers(&BreakerConfig{ConsecutiveFailures: 3})
+
+	for i := 0; i < 2; i++ {
+		ok, _ := b.allow("node-1")
+		require.True(t, ok)
+		b.record("node-1", false)
+	}
+
+	// a success resets the count
+	b.record("node-1", true)
+
+	for i := 0; i < 2; i++ {
+		b.record("node-1", false)
+	}
+
+	ok, _ := b.allow("node-1")
+	assert.True(t, ok)
+	assert.False(t, b.isOpen("node-1"))
+
+	b.record("node-1", false)
+
+	ok, retryAfter := b.allow("node-1")
+	assert.False(t, ok)
+	assert.InDelta(t, time.Hour, retryAfter, float64(time.Second))
+	assert.True(t, b.isOpen("node-1"))
+
+	// the other nodes are not affected
+	ok, _ = b.allow("node-2")
+	assert.True(t, ok)
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	b := newTestBreakers(&BreakerConfig{ConsecutiveFailures: 1, OpenDuration: 20 * time.Millisecond})
+
+	b.record("node-1", false)
+	ok, _ := b.allow("node-1")
+	require.False(t, ok)
+
+	time.Sleep(30 * time.Millisecond)
+
+	// a single probe is let through
+	ok, _ = b.allow("node-1")
+	assert.True(t, ok)
+	assert.Equal(t, BreakerHalfOpen, b.Status()[0].State)
+	assert.True(t, b.isOpen("node-1"))
+
+	ok, _ = b.allow("node-1")
+	assert.False(t, ok)
+
+	// the probe failed, the circuit opens again
+	b.record("node-1", false)
+	assert.Equal(t, BreakerOpen, b.Status()[0].State)
+
+	ok, _ = b.allow("node-1")
+	assert.False(t, ok)
+
+	time.Sleep(30 * time.Millisecond)
+
+	ok, _ = b.allow("node-1")
+	require.True(t, ok)
+
+	// the probe succeeded, the circuit is closed
+	b.record("node-1", true)
+	assert.Empty(t, b.Status())
+
+	ok, _ = b.allow("node-1")
+	assert.True(t, ok)
+	ok, _ = b.allow("node-1")
+	assert.True(t, ok)
+}
+
+func TestBreakerCancelReleasesProbe(t *testing.T) {
+	b := newTestBreakers(&BreakerConfig{ConsecutiveFailures: 1, OpenDuration: 10 * time.Millisecond})
+
+	b.record("node-1", false)
+	time.Sleep(20 * time.Millisecond)
+
+	ok, _ := b.allow("node-1")
+	require.True(t, ok)
+
+	ok, _ = b.allow("node-1")
+	require.False(t, ok)
+
+	// the client went away, another probe can be sent
+	b.cancel("node-1")
+	assert.False(t, b.isOpen("node-1"))
+
+	ok, _ = b.allow("node-1")
+	assert.True(t, ok)
+	assert.Equal(t, BreakerHalfOpen, b.Status()[0].State)
+
+	// cancel without a breaker is a no-op
+	b.cancel("node-2")
+}
+
+func TestBreakerErrorRate(t *testing.T) {
+	b := newTestBreakers(&BreakerConfig{ErrorRate: 0.5, MinRequests: 4, Window: time.Hour})
+
+	// below MinRequests the rate doesn't apply
+	b.record("node-1", false)
+	b.record("node-1", true)
+	b.record("node-1", false)
+	assert.False(t, b.isOpen("node-1"))
+
+	b.record("node-1", true)
+	assert.False(t, b.isOpen("node-1"))
+
+	// 3 failures of 5 requests
+	b.record("node-1", false)
+	assert.True(t, b.isOpen("node-1"))
+}
+
+func TestBreakerErrorRateWindow(t *testing.T) {
+	b := newTestBreakers(&BreakerConfig{ErrorRate: 0.5, MinRequests: 2, Window: 20 * time.Millisecond})
+
+	b.record("node-1", false)
+	b.record("node-1", true)
+	b.record("node-1", true)
+
+	// the requests of the previous window are not counted
+	time.Sleep(30 * time.Millisecond)
+
+	b.record("node-1", false)
+	assert.False(t, b.isOpen("node-1"))
+
+	status := b.Status()
+	require.Len(t, status, 1)
+	assert.Equal(t, 1, status[0].Requests)
+	assert.Equal(t, 1, status[0].Failures)
+
+	b.record("node-1", true)
+	b.record("node-1", false)
+	assert.True(t, b.isOpen("node-1"))
+}
+
+func TestBreakerFilterOpen(t *testing.T) {
+	b := newTestBreakers(&BreakerConfig{ConsecutiveFailures: 1})
+
+	b.record("node-1", false)
+	assert.Equal(t, []string{"node-2"}, b.filterOpen([]string{"node-1", "node-2"}))
+
+	// all the nodes are returned if they are all open
+	b.record("node-2", false)
+	assert.Equal(t, []string{"node-1", "node-2"}, b.filterOpen([]string{"node-1", "node-2"}))
+
+	var disabled *Breakers
+	assert.Equal(t, []string{"node-1"}, disabled.filterOpen([]string{"node-1"}))
+	ok, _ := disabled.allow("node-1")
+	assert.True(t, ok)
+}
+
+func TestBreakerPrune(t *testing.T) {
+	b := newTestBreakers(&BreakerConfig{ConsecutiveFailures: 1, OpenDuration: time.Hour})
+
+	b.allow("idle")
+	b.record("open", false)
+	b.record("expired", false)
+	b.allow("active")
+
+	now := time.Now()
+	b.lock.Lock()
+	for _, nodeID := range []string{"idle", "open", "expired"} {
+		b.breakers[nodeID].usedAt = now.Add(-2 * statsTTL)
+	}
+	b.breakers["expired"].openedAt = now.Add(-2 * time.Hour)
+	b.prunedAt = now.Add(-2 * statsTTL)
+	b.lock.Unlock()
+
+	b.allow("active")
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	// the open circuit is kept until its probe is due
+	assert.Contains(t, b.breakers, "open")
+	assert.Contains(t, b.breakers, "active")
+	assert.NotContains(t, b.breakers, "idle")
+	assert.NotContains(t, b.breakers, "expired")
+}
+
+func TestIsNodeFailure(t *testing.T) {
+	down := http.Header{}
+	down.Set(EdgeHealthHeader, string(HealthDown))
+
+	assert.True(t, isNodeFailure(&http.Response{StatusCode: http.StatusBadGateway}))
+	assert.True(t, isNodeFailure(&http.Response{StatusCode: http.StatusGatewayTimeout}))
+	assert.True(t, isNodeFailure(&http.Response{StatusCode: http.StatusServiceUnavailable, Header: down}))
+	assert.False(t, isNodeFailure(&http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}}))
+	assert.False(t, isNodeFailure(&http.Response{StatusCode: http.StatusInternalServerError}))
+}
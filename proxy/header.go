@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// AllowedMethods is the value of the Access-Control-Allow-Methods header
+const AllowedMethods = "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS"
+
+// hopHeaders are the hop-by-hop headers which are not forwarded (RFC 7230, section 6.1)
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// RemoveHopHeaders removes the hop-by-hop headers and the headers listed in Connection
+func RemoveHopHeaders(header http.Header) {
+	for _, value := range header.Values("Connection") {
+		for _, name := range strings.Split(value, ",") {
+			if name = textproto.TrimString(name); name != "" {
+				header.Del(name)
+			}
+		}
+	}
+
+	// keep "TE: trailers", so the upstream knows the client accepts trailers
+	acceptTrailers := headerContainsToken(header, "Te", "trailers")
+
+	for _, name := range hopHeaders {
+		header.Del(name)
+	}
+
+	if acceptTrailers {
+		header.Set("Te", "trailers")
+	}
+}
+
+// CopyHeader adds all the values of src to dst, skipping the hop-by-hop headers and the excluded keys
+func CopyHeader(dst http.Header, src http.Header, excludes ...string) {
+	filtered := src.Clone()
+	RemoveHopHeaders(filtered)
+
+	for _, exclude := range excludes {
+		filtered.Del(exclude)
+	}
+
+	for key, values := range filtered {
+		for _, value := range values {
+			dst.Add(key, value)
+		}
+	}
+}
+
+// AnnounceTrailers declares the trailer keys of resp, must be called before WriteHeader
+func AnnounceTrailers(w http.ResponseWriter, resp *http.Response) {
+	for key := range resp.Trailer {
+		w.Header().Add("Trailer", key)
+	}
+}
+
+// CopyTrailers writes the trailers of resp, must be called after the body has been read to EOF
+func CopyTrailers(w http.ResponseWriter, resp *http.Response) {
+	for key, values := range resp.Trailer {
+		for _, value := range values {
+			w.Header().Add(http.TrailerPrefix+key, value)
+		}
+	}
+}
+
+// headerHasValue returns true if one of the values of the header is value
+func headerHasValue(header http.Header, key string, value string) bool {
+	for _, v := range header.Values(key) {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsPreflightRequest returns true if the request is a CORS preflight request
+func IsPreflightRequest(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// ResponseHasBody returns false if the response to the request must not carry a body
+func ResponseHasBody(r *http.Request, statusCode int) bool {
+	if r.Method == http.MethodHead {
+		return false
+	}
+
+	switch {
+	case statusCode >= 100 && statusCode < 200,
+		statusCode == http.StatusNoContent,
+		statusCode == http.StatusNotModified:
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,187 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/EdgeMatrixChain/edge-matrix-core/core/application"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoveHopHeaders(t *testing.T) {
+	header := http.Header{
+		"Connection":          {"keep-alive, X-Session", "X-Debug"},
+		"Keep-Alive":          {"timeout=5"},
+		"Proxy-Authorization": {"Basic secret"},
+		"Te":                  {"trailers, deflate"},
+		"Transfer-Encoding":   {"chunked"},
+		"Upgrade":             {"h2c"},
+		"X-Session":           {"1"},
+		"X-Debug":             {"on"},
+		"X-Multi":             {"a", "b"},
+		"Content-Type":        {"application/json"},
+	}
+
+	RemoveHopHeaders(header)
+
+	assert.Equal(t, http.Header{
+		// the client still accepts trailers
+		"Te":           {"trailers"},
+		"X-Multi":      {"a", "b"},
+		"Content-Type": {"application/json"},
+	}, header)
+
+	header = http.Header{"Te": {"deflate"}}
+	RemoveHopHeaders(header)
+	assert.Empty(t, header)
+}
+
+func TestCopyHeader(t *testing.T) {
+	src := http.Header{
+		"Connection":   {"X-Hop"},
+		"X-Hop":        {"1"},
+		"Upgrade":      {"websocket"},
+		"Set-Cookie":   {"a=1", "b=2"},
+		"X-Request-Id": {"client"},
+		"Vary":         {"Origin"},
+	}
+	dst := http.Header{"Vary": {"Accept"}}
+
+	CopyHeader(dst, src, RequestIDHeader)
+
+	assert.Equal(t, http.Header{
+		"Set-Cookie": {"a=1", "b=2"},
+		"Vary":       {"Accept", "Origin"},
+	}, dst)
+
+	// the source is left as is
+	assert.Equal(t, []string{"X-Hop"}, src.Values("Connection"))
+	assert.Equal(t, "client", src.Get(RequestIDHeader))
+}
+
+func TestTrailersRoundTrip(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "X-Checksum, X-Multi")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("hello"))
+		w.Header().Set("X-Checksum", "abc")
+		w.Header()["X-Multi"] = []string{"1", "2"}
+	}))
+	defer upstream.Close()
+
+	front := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, err := http.Get(upstream.URL)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer resp.Body.Close()
+
+		CopyHeader(w.Header(), resp.Header)
+		AnnounceTrailers(w, resp)
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(w, resp.Body)
+		CopyTrailers(w, resp)
+	}))
+	defer front.Close()
+
+	resp, err := http.Get(front.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(body))
+
+	// the trailers are received once the body is read
+	assert.Equal(t, "abc", resp.Trailer.Get("X-Checksum"))
+	assert.Equal(t, []string{"1", "2"}, resp.Trailer.Values("X-Multi"))
+}
+
+func TestResponseHasBody(t *testing.T) {
+	tests := []struct {
+		method     string
+		statusCode int
+		hasBody    bool
+	}{
+		{http.MethodGet, http.StatusOK, true},
+		{http.MethodPost, http.StatusCreated, true},
+		{http.MethodDelete, http.StatusNotFound, true},
+		{http.MethodHead, http.StatusOK, false},
+		{http.MethodGet, http.StatusSwitchingProtocols, false},
+		{http.MethodDelete, http.StatusNoContent, false},
+		{http.MethodGet, http.StatusNotModified, false},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, "/", nil)
+		assert.Equal(t, test.hasBody, ResponseHasBody(r, test.statusCode), "%s %d", test.method, test.statusCode)
+	}
+}
+
+func TestAllowedMethods(t *testing.T) {
+	j := newTestProxy(&Config{})
+
+	w := httptest.NewRecorder()
+	j.setCORSHeaders(w, httptest.NewRequest(http.MethodOptions, "/", nil))
+
+	methods := strings.Split(w.Header().Get("Access-Control-Allow-Methods"), ", ")
+	for _, method := range []string{http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodHead} {
+		assert.Contains(t, methods, method)
+	}
+}
+
+// methodHandler answers with the method and the body of the request
+func methodHandler(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	switch r.Method {
+	case http.MethodDelete:
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodHead:
+		w.Header().Set("Content-Length", "5")
+	default:
+		_, _ = fmt.Fprintf(w, "%s %s", r.Method, body)
+	}
+}
+
+func TestHandleMethods(t *testing.T) {
+	edgeHost, relayHost, _ := newForwardHosts(t, methodHandler)
+	nodeID := edgeHost.ID().String()
+
+	j := newTestProxy(&Config{Store: &testStore{
+		relayHost: relayHost,
+		appPeers:  map[string]*application.AppPeer{nodeID: {Addr: edgeHost.Addrs()[0].String()}},
+	}})
+	j.clients = newP2PClients(nil)
+	defer j.clients.close()
+
+	tests := []struct {
+		method     string
+		body       string
+		statusCode int
+		expected   string
+	}{
+		{http.MethodPut, `{"name":"a"}`, http.StatusOK, `PUT {"name":"a"}`},
+		{http.MethodPatch, `{"name":"b"}`, http.StatusOK, `PATCH {"name":"b"}`},
+		{http.MethodDelete, "", http.StatusNoContent, ""},
+		{http.MethodHead, "", http.StatusOK, ""},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, "/"+nodeID+"/9527/items/1", strings.NewReader(test.body))
+		pathInfo := &EdgePath{NodeID: nodeID, Port: 9527, InterfaceURL: "items/1"}
+		r = r.WithContext(context.WithValue(r.Context(), "EdgePath", pathInfo))
+
+		w := httptest.NewRecorder()
+		j.handle(w, r)
+
+		assert.Equal(t, test.statusCode, w.Code, test.method)
+		assert.Equal(t, test.expected, w.Body.String(), test.method)
+	}
+}
@@ -0,0 +1,283 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/go-hclog"
+)
+
+// EdgeHealthHeader is the state of the edge node, set on the responses refused because its upstream is down
+const EdgeHealthHeader = "X-Edge-Health"
+
+type HealthState string
+
+const (
+	// HealthReady means all the checked upstream services are up
+	HealthReady HealthState = "ready"
+	// HealthDegraded means some of the checked upstream services are down
+	HealthDegraded HealthState = "degraded"
+	// HealthDown means all the checked upstream services are down
+	HealthDown HealthState = "down"
+)
+
+const (
+	DefaultHealthInterval = 10 * time.Second
+	DefaultHealthTimeout  = 2 * time.Second
+	DefaultHealthFailures = 3
+)
+
+// HealthConfig defines the health checks of the upstream services of the edge node
+type HealthConfig struct {
+	// Path is the health path of the services which don't declare one, the services are not checked if empty
+	Path string
+	// Interval is the period of the checks
+	Interval time.Duration
+	// Timeout is the maximum duration of a check
+	Timeout time.Duration
+	// Failures is the number of consecutive failed checks before a service is down
+	Failures int
+}
+
+// DefaultHealthConfig returns the default health check config, without a path
+func DefaultHealthConfig() *HealthConfig {
+	return &HealthConfig{
+		Interval: DefaultHealthInterval,
+		Timeout:  DefaultHealthTimeout,
+		Failures: DefaultHealthFailures,
+	}
+}
+
+// HealthTarget is an upstream service checked with a GET on its health url
+type HealthTarget struct {
+	Name   string
+	URL    string
+	Client *http.Client
+}
+
+// UpstreamHealth is the health of an upstream service
+type UpstreamHealth struct {
+	Name                string    `json:"name"`
+	Up                  bool      `json:"up"`
+	ConsecutiveFailures int       `json:"consecutive_failures,omitempty"`
+	Error               string    `json:"error,omitempty"`
+	CheckedAt           time.Time `json:"checked_at"`
+}
+
+// HealthChecker checks the upstream services of the edge node on an interval
+type HealthChecker struct {
+	logger  hclog.Logger
+	config  *HealthConfig
+	targets []*HealthTarget
+
+	lock   sync.RWMutex
+	health map[string]*UpstreamHealth
+	state  HealthState
+
+	// changed is signaled when the state changes, it holds at most one signal
+	changed chan struct{}
+
+	closeCh chan struct{}
+	wg      sync.WaitGroup
+}
+
+func NewHealthChecker(logger hclog.Logger, config *HealthConfig, targets []*HealthTarget) *HealthChecker {
+	h := &HealthChecker{
+		logger:  logger.Named("health"),
+		config:  config,
+		targets: targets,
+		health:  make(map[string]*UpstreamHealth, len(targets)),
+		state:   HealthDown,
+		changed: make(chan struct{}, 1),
+		closeCh: make(chan struct{}),
+	}
+
+	for _, target := range targets {
+		if target.Client == nil {
+			target.Client = &http.Client{}
+		}
+		// a service is down until its first successful check
+		h.health[target.Name] = &UpstreamHealth{Name: target.Name}
+	}
+
+	return h
+}
+
+// Start checks the services once, so the state is known, then on every interval
+func (h *HealthChecker) Start() {
+	h.check()
+
+	h.wg.Add(1)
+	go h.run()
+}
+
+// Close stops the checks
+func (h *HealthChecker) Close() {
+	if h == nil {
+		return
+	}
+
+	close(h.closeCh)
+	h.wg.Wait()
+}
+
+func (h *HealthChecker) run() {
+	defer h.wg.Done()
+
+	ticker := time.NewTicker(h.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			h.check()
+		case <-h.closeCh:
+			return
+		}
+	}
+}
+
+// check checks all the services at once and updates the state
+func (h *HealthChecker) check() {
+	errs := make([]error, len(h.targets))
+
+	var wg sync.WaitGroup
+	for i, target := range h.targets {
+		wg.Add(1)
+
+		go func(i int, target *HealthTarget) {
+			defer wg.Done()
+			errs[i] = h.checkTarget(target)
+		}(i, target)
+	}
+	wg.Wait()
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	now := time.Now().UTC()
+	up := 0
+
+	for i, target := range h.targets {
+		health := h.health[target.Name]
+		health.CheckedAt = now
+		health.Error = ""
+
+		wasUp := health.Up
+		if errs[i] == nil {
+			health.ConsecutiveFailures = 0
+			health.Up = true
+		} else {
+			health.ConsecutiveFailures++
+			health.Error = errs[i].Error()
+			// a service which is up is down after Failures failed checks in a row
+			health.Up = wasUp && health.ConsecutiveFailures < h.config.Failures
+		}
+		if health.Up {
+			up++
+		}
+
+		if wasUp != health.Up {
+			h.logger.Warn("upstream health changed", "upstream", target.Name, "up", health.Up, "err", health.Error)
+		}
+		metrics.SetGaugeWithLabels([]string{proxyMetrics, "upstream_up"}, boolGauge(health.Up), []metrics.Label{{Name: "upstream", Value: target.Name}})
+	}
+
+	state := HealthReady
+	switch {
+	case up == 0:
+		state = HealthDown
+	case up < len(h.targets):
+		state = HealthDegraded
+	}
+
+	if state != h.state {
+		h.logger.Info("health state changed", "from", h.state, "to", state)
+		h.state = state
+
+		select {
+		case h.changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// checkTarget returns an error if the health url of the service doesn't respond with a 2xx or 3xx
+func (h *HealthChecker) checkTarget(target *HealthTarget) error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.URL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := target.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("health check returned %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// State returns the state of the edge node.
+// It is safe to call on a nil HealthChecker, the node is then always ready.
+func (h *HealthChecker) State() HealthState {
+	if h == nil {
+		return HealthReady
+	}
+
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	return h.state
+}
+
+// IsDown returns true if the service is checked and down
+func (h *HealthChecker) IsDown(name string) bool {
+	if h == nil {
+		return false
+	}
+
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	health, ok := h.health[name]
+
+	return ok && !health.Up
+}
+
+// Status returns the health of the checked services, in the order of the targets
+func (h *HealthChecker) Status() []UpstreamHealth {
+	if h == nil {
+		return nil
+	}
+
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	status := make([]UpstreamHealth, 0, len(h.targets))
+	for _, target := range h.targets {
+		status = append(status, *h.health[target.Name])
+	}
+
+	return status
+}
+
+// Changed is signaled when the state of the node changes, the signals of the changes which are not
+// received yet are merged, so the receiver reads the current state with State
+func (h *HealthChecker) Changed() <-chan struct{} {
+	return h.changed
+}
@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthCheckerChanged(t *testing.T) {
+	var up int32 = 1
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&up) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	h := NewHealthChecker(hclog.NewNullLogger(), &HealthConfig{Interval: time.Hour, Timeout: time.Second, Failures: 2},
+		[]*HealthTarget{{Name: "app", URL: srv.URL}})
+
+	changed := func() bool {
+		select {
+		case <-h.Changed():
+			return true
+		default:
+			return false
+		}
+	}
+
+	// a service is down until its first check
+	assert.Equal(t, HealthDown, h.State())
+
+	h.check()
+	assert.Equal(t, HealthReady, h.State())
+	assert.True(t, changed())
+
+	// the service is down after Failures failed checks
+	atomic.StoreInt32(&up, 0)
+	h.check()
+	assert.Equal(t, HealthReady, h.State())
+	assert.False(t, changed())
+
+	h.check()
+	assert.Equal(t, HealthDown, h.State())
+	assert.True(t, h.IsDown("app"))
+
+	// the changes which are not received are merged
+	atomic.StoreInt32(&up, 1)
+	h.check()
+	assert.Equal(t, HealthReady, h.State())
+	assert.True(t, changed())
+	assert.False(t, changed())
+}
+
+func TestHealthCheckerDegraded(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	h := NewHealthChecker(hclog.NewNullLogger(), DefaultHealthConfig(), []*HealthTarget{
+		{Name: "up", URL: srv.URL},
+		{Name: "down", URL: srv.URL + "/missing"},
+	})
+	h.check()
+
+	assert.Equal(t, HealthDegraded, h.State())
+	assert.False(t, h.IsDown("up"))
+	assert.True(t, h.IsDown("down"))
+	assert.False(t, h.IsDown("unchecked"))
+}
+
+func TestHealthCheckerNil(t *testing.T) {
+	var h *HealthChecker
+
+	assert.Equal(t, HealthReady, h.State())
+	assert.False(t, h.IsDown("app"))
+	assert.Nil(t, h.Status())
+}
@@ -0,0 +1,119 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// hostPortSeparator separates the port from the NodeID in the host label, e.g. 9527--<nodeId>.edge.example.com
+const hostPortSeparator = "--"
+
+// hostLabel returns the label of the host before the routing domain, ok is false if the host is not a node subdomain
+func (j *TransparentProxy) hostLabel(host string) (string, bool) {
+	if j.config.HostDomain == "" {
+		return "", false
+	}
+
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+
+	label, ok := strings.CutSuffix(strings.ToLower(host), "."+strings.ToLower(strings.TrimPrefix(j.config.HostDomain, ".")))
+	if !ok || label == "" || strings.Contains(label, ".") {
+		return "", false
+	}
+
+	return label, true
+}
+
+// hostRoutingHandler serves the requests to a node subdomain with hostHandler, the others with next
+func (j *TransparentProxy) hostRoutingHandler(hostHandler http.Handler, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := j.hostLabel(r.Host); ok {
+			hostHandler.ServeHTTP(w, r)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// parseHostPath takes the NodeID and the port from the Host header, e.g. <port>--<nodeId>.edge.example.com,
+// the path is sent unchanged to the webapp. The port can be omitted to use the port declared for the app of the node.
+func (j *TransparentProxy) parseHostPath(r *http.Request) (*EdgePath, error) {
+	label, ok := j.hostLabel(r.Host)
+	if !ok {
+		return nil, errors.New("invalid host: not a node subdomain")
+	}
+
+	// the request doesn't go through the mux, which cleans the path
+	if HasDotDotSegment(r.URL.Path) {
+		return nil, ErrPathTraversal
+	}
+
+	pathInfo := &EdgePath{
+		InterfaceURL: strings.TrimPrefix(r.URL.Path, "/"),
+	}
+
+	nodeID := label
+	port, node, found := strings.Cut(label, hostPortSeparator)
+	if found {
+		decodedPort, err := strconv.Atoi(port)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode port: %w", err)
+		}
+		pathInfo.Port = decodedPort
+		nodeID = node
+	}
+
+	resolvedID, err := j.resolveHostNodeID(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	pathInfo.NodeID = resolvedID
+
+	if !found {
+		var appName string
+		if appPeer := j.config.Store.GetAppPeer(resolvedID); appPeer != nil {
+			appName = appPeer.AppName
+		}
+
+		if pathInfo.Port, err = j.appPort(appName); err != nil {
+			return nil, err
+		}
+	}
+
+	return pathInfo, nil
+}
+
+// resolveHostNodeID finds the NodeID of a host label.
+// Browsers lowercase the host, so the NodeID is looked up in the lowercase index of the app peers.
+func (j *TransparentProxy) resolveHostNodeID(label string) (string, error) {
+	if nodeID, ok := j.config.Store.ResolveAppPeerID(label); ok {
+		return nodeID, nil
+	}
+
+	return "", fmt.Errorf("unknown node '%s'", label)
+}
+
+// matchOrigin returns true if the origin is allowed by the pattern, which is either an origin
+// or a wildcard subdomain origin, e.g. https://*.edge.example.com
+func matchOrigin(pattern string, origin string) bool {
+	if pattern == origin {
+		return true
+	}
+
+	scheme, suffix, ok := strings.Cut(pattern, "://*.")
+	if !ok {
+		return false
+	}
+
+	host, ok := strings.CutPrefix(origin, scheme+"://")
+
+	return ok && strings.HasSuffix(host, "."+suffix) && !strings.Contains(strings.TrimSuffix(host, "."+suffix), "/")
+}
@@ -0,0 +1,150 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/EdgeMatrixChain/edge-matrix-core/core/application"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testNodeID = "16Uiu2HAkzBCWtZq49xzn4HcsGw7NZHSuSSS97HfzLyMDyY9KTDie"
+
+func newHostRoutingProxy() *TransparentProxy {
+	return newTestProxy(&Config{
+		HostDomain: "edge.example.com",
+		AppPorts:   map[string]int{"llama": 8080},
+		Store: &testStore{appPeers: map[string]*application.AppPeer{
+			testNodeID:        {AppName: "llama"},
+			"16Uiu2HAmNoPort": {AppName: "sd"},
+		}},
+	})
+}
+
+func TestParseHostPath(t *testing.T) {
+	j := newHostRoutingProxy()
+
+	testCases := []struct {
+		name         string
+		host         string
+		path         string
+		nodeID       string
+		port         int
+		interfaceURL string
+		err          bool
+	}{
+		{"lowercased NodeID", "16uiu2hakzbcwtzq49xzn4hcsgw7nzhsusss97hfzlymdyy9ktdie.edge.example.com", "/v1/chat", testNodeID, 8080, "v1/chat", false},
+		{"exact NodeID", testNodeID + ".edge.example.com", "/", testNodeID, 8080, "", false},
+		// without a port in the host, the port declared for the app of the node is reached
+		{"undeclared app port", "16uiu2hamnoport.edge.example.com", "/", "", 0, "", true},
+		{"port of a node without declared app port", "9527--16uiu2hamnoport.edge.example.com", "/", "16Uiu2HAmNoPort", 9527, "", false},
+		{"port", "9527--16uiu2hakzbcwtzq49xzn4hcsgw7nzhsusss97hfzlymdyy9ktdie.edge.example.com", "/api/v1", testNodeID, 9527, "api/v1", false},
+		{"host port", "9527--16uiu2hakzbcwtzq49xzn4hcsgw7nzhsusss97hfzlymdyy9ktdie.EDGE.example.com:8443", "/", testNodeID, 9527, "", false},
+		{"invalid port", "abc--16uiu2hakzbcwtzq49xzn4hcsgw7nzhsusss97hfzlymdyy9ktdie.edge.example.com", "/", "", 0, "", true},
+		{"unknown node", "16uiu2hamunknown.edge.example.com", "/", "", 0, "", true},
+		{"another domain", testNodeID + ".example.com", "/", "", 0, "", true},
+		{"nested subdomain", "a." + testNodeID + ".edge.example.com", "/", "", 0, "", true},
+		{"domain only", "edge.example.com", "/", "", 0, "", true},
+		{"traversal", testNodeID + ".edge.example.com", "/v1/../admin", "", 0, "", true},
+		{"encoded traversal", testNodeID + ".edge.example.com", "/v1/%2e%2e/admin", "", 0, "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://relay"+tc.path, nil)
+			r.Host = tc.host
+			// the decoded path, %2e%2e is what a client sending %252e%252e gets
+			r.URL.Path = tc.path
+
+			pathInfo, err := j.parseHostPath(r)
+			if tc.err {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.nodeID, pathInfo.NodeID)
+			assert.Equal(t, tc.port, pathInfo.Port)
+			assert.Equal(t, tc.interfaceURL, pathInfo.InterfaceURL)
+		})
+	}
+}
+
+func TestHostRoutingHandler(t *testing.T) {
+	j := newHostRoutingProxy()
+
+	var routed string
+	handler := j.hostRoutingHandler(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { routed = "host" }),
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { routed = "mux" }),
+	)
+
+	r := httptest.NewRequest(http.MethodGet, "http://relay/", nil)
+	r.Host = "9527--node.edge.example.com"
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, "host", routed)
+
+	r.Host = "relay.example.com"
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, "mux", routed)
+
+	// without a domain, no request is routed by host
+	j.config.HostDomain = ""
+	r.Host = "9527--node.edge.example.com"
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, "mux", routed)
+}
+
+func TestMatchOrigin(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		origin  string
+		match   bool
+	}{
+		{"https://app.example.com", "https://app.example.com", true},
+		{"https://app.example.com", "http://app.example.com", false},
+		{"https://*.edge.example.com", "https://9527--node.edge.example.com", true},
+		{"https://*.edge.example.com", "https://a.b.edge.example.com", true},
+		{"https://*.edge.example.com", "https://edge.example.com", false},
+		{"https://*.edge.example.com", "http://node.edge.example.com", false},
+		{"https://*.edge.example.com", "https://node.edge.example.com.evil.com", false},
+		{"https://*.edge.example.com", "https://eviledge.example.com", false},
+		{"https://*.edge.example.com", "https://evil.com/.edge.example.com", false},
+		{"https://*.edge.example.com", "https://node.edge.example.com:8443", false},
+		{"*", "https://app.example.com", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pattern+" "+tc.origin, func(t *testing.T) {
+			assert.Equal(t, tc.match, matchOrigin(tc.pattern, tc.origin))
+		})
+	}
+}
+
+func TestAppPeerIndexResolvesHosts(t *testing.T) {
+	source := &testStore{appPeers: map[string]*application.AppPeer{testNodeID: {AppName: "llama"}}}
+	index := NewAppPeerIndex(source, func() []string { return []string{testNodeID} })
+	index.Refresh()
+
+	j := newTestProxy(&Config{HostDomain: "edge.example.com", AppPorts: map[string]int{"llama": 8080}, Store: &indexStore{testStore: source, index: index}})
+
+	r := httptest.NewRequest(http.MethodGet, "http://relay/", nil)
+	r.Host = "16uiu2hakzbcwtzq49xzn4hcsgw7nzhsusss97hfzlymdyy9ktdie.edge.example.com"
+
+	pathInfo, err := j.parseHostPath(r)
+	require.NoError(t, err)
+	assert.Equal(t, testNodeID, pathInfo.NodeID)
+}
+
+// indexStore resolves the host labels with an app peer index, like the server
+type indexStore struct {
+	*testStore
+	index *AppPeerIndex
+}
+
+func (s *indexStore) ResolveAppPeerID(label string) (string, bool) {
+	return s.index.ResolveNodeID(label)
+}
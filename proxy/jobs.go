@@ -0,0 +1,860 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-hclog"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// JobsUrl is the prefix of the async job API, POST /jobs/<nodeId>/<port>/<path> and GET /jobs/<id>
+const JobsUrl = "/jobs/"
+
+// JobCallbackHeader is the url the job is posted to when it's done
+const JobCallbackHeader = "X-Job-Callback"
+
+// jobPrefix is the prefix of the jobs in the db
+var jobPrefix = []byte("job/")
+
+const (
+	DefaultJobTTL         = 24 * time.Hour
+	DefaultJobTimeout     = 30 * time.Minute
+	DefaultJobWorkers     = 8
+	DefaultJobMaxBodySize = 16 << 20 // 16MB
+	DefaultJobMaxQueued   = 1000
+)
+
+const (
+	// jobSweepInterval is the interval of the deletion of the expired jobs
+	jobSweepInterval = time.Minute
+	// jobCallbackAttempts is the number of attempts to post a job to its callback
+	jobCallbackAttempts = 3
+	jobCallbackTimeout  = 10 * time.Second
+	// jobRetryAfter is the delay advised to the client when the queue is full
+	jobRetryAfter = 30 * time.Second
+)
+
+// jobCredentialHeaders are the headers which are kept in memory only, never in the db
+var jobCredentialHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// jobCredentialQuery is the query parameter which is kept in memory only, never in the db
+const jobCredentialQuery = "access_token"
+
+var (
+	errJobsFull           = errors.New("too many queued jobs")
+	errJobCredentialsLost = errors.New("the credentials of the job were lost in a restart, submit it again")
+)
+
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+)
+
+// JobsConfig defines the async jobs of the transparent proxy
+type JobsConfig struct {
+	// DBPath is the directory of the jobs and their results
+	DBPath string
+	// TTL is how long a finished job and its result are kept
+	TTL time.Duration
+	// Timeout is the maximum duration of the forward of a job
+	Timeout time.Duration
+	// Workers is the number of jobs forwarded at the same time
+	Workers int
+	// MaxBodySize is the maximum size of the request and the result of a job
+	MaxBodySize int64
+	// MaxQueued is the maximum number of unfinished jobs, 0 means no limit
+	MaxQueued int
+	// CallbackAllowedHosts are the callback hosts allowed to resolve to a loopback, private or link-local address
+	CallbackAllowedHosts []string
+}
+
+// DefaultJobsConfig returns the default async jobs config
+func DefaultJobsConfig() *JobsConfig {
+	return &JobsConfig{
+		TTL:         DefaultJobTTL,
+		Timeout:     DefaultJobTimeout,
+		Workers:     DefaultJobWorkers,
+		MaxBodySize: DefaultJobMaxBodySize,
+		MaxQueued:   DefaultJobMaxQueued,
+	}
+}
+
+// JobResult is the response of the edge node to the request of a job
+type JobResult struct {
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header"`
+	Body     []byte      `json:"body"`
+	Verified bool        `json:"verified"`
+}
+
+// Job is a request forwarded in the background, it is kept in the db until it expires
+type Job struct {
+	ID        string    `json:"id"`
+	Status    JobStatus `json:"status"`
+	Key       string    `json:"key"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+	Callback  string    `json:"callback,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	// Credentials is true if the request has credentials, which are kept in memory only
+	Credentials bool `json:"credentials,omitempty"`
+
+	EdgePath EdgePath    `json:"edge_path"`
+	Method   string      `json:"method"`
+	Query    string      `json:"query"`
+	Header   http.Header `json:"header"`
+	Body     []byte      `json:"body"`
+
+	Result *JobResult `json:"result,omitempty"`
+}
+
+// jobView is the job returned to the client, without the request and the result body
+type jobView struct {
+	ID        string     `json:"id"`
+	Status    JobStatus  `json:"status"`
+	NodeID    string     `json:"node_id"`
+	Port      int        `json:"port"`
+	Path      string     `json:"path"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	// ResultStatus is the http status of the result, which is served by GET /jobs/<id>/result
+	ResultStatus int  `json:"result_status,omitempty"`
+	Verified     bool `json:"verified,omitempty"`
+}
+
+func (job *Job) view() *jobView {
+	view := &jobView{
+		ID:        job.ID,
+		Status:    job.Status,
+		NodeID:    job.EdgePath.NodeID,
+		Port:      job.EdgePath.Port,
+		Path:      job.EdgePath.InterfaceURL,
+		CreatedAt: job.CreatedAt,
+		UpdatedAt: job.UpdatedAt,
+		Error:     job.Error,
+	}
+
+	if !job.ExpiresAt.IsZero() {
+		view.ExpiresAt = &job.ExpiresAt
+	}
+
+	if job.Result != nil {
+		view.ResultStatus = job.Result.Status
+		view.Verified = job.Result.Verified
+	}
+
+	return view
+}
+
+// jobCredentials are the credentials removed from the stored request of a job
+type jobCredentials struct {
+	header http.Header
+	// query is the original query, with the access token
+	query string
+}
+
+// Jobs stores the async jobs of the transparent proxy and runs them with a bounded number of workers
+type Jobs struct {
+	logger hclog.Logger
+	config *JobsConfig
+	db     *leveldb.DB
+
+	// lock serializes the updates of the jobs
+	lock    sync.Mutex
+	workers chan struct{}
+	// queued is the number of unfinished jobs
+	queued int
+	// credentials are the credentials of the unfinished jobs by id, they are lost on a restart
+	credentials map[string]*jobCredentials
+
+	callbackClient *http.Client
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewJobs(logger hclog.Logger, config *JobsConfig) (*Jobs, error) {
+	db, err := leveldb.OpenFile(config.DBPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open jobs db: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	q := &Jobs{
+		logger:      logger.Named("jobs"),
+		config:      config,
+		db:          db,
+		workers:     make(chan struct{}, config.Workers),
+		credentials: make(map[string]*jobCredentials),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+
+	// the callbacks dial the checked addresses, which covers the redirects and a host resolving
+	// to another address after the submission, and ignore the proxy of the environment
+	q.callbackClient = &http.Client{
+		Timeout:   jobCallbackTimeout,
+		Transport: &http.Transport{DialContext: q.dialCallback},
+	}
+
+	return q, nil
+}
+
+// Close stops the running jobs, they are resumed on the next start, and closes the db
+func (q *Jobs) Close() {
+	if q == nil {
+		return
+	}
+
+	q.cancel()
+	q.wg.Wait()
+
+	if err := q.db.Close(); err != nil {
+		q.logger.Error("failed to close jobs db", "err", err)
+	}
+}
+
+func jobKey(id string) []byte {
+	return append(append([]byte(nil), jobPrefix...), id...)
+}
+
+func (q *Jobs) get(id string) (*Job, error) {
+	value, err := q.db.Get(jobKey(id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &Job{}
+	if err := json.Unmarshal(value, job); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (q *Jobs) put(job *Job) error {
+	value, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	return q.db.Put(jobKey(job.ID), value, nil)
+}
+
+// update applies the change to the stored job
+func (q *Jobs) update(id string, change func(job *Job)) (*Job, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	job, err := q.get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	change(job)
+	job.UpdatedAt = time.Now().UTC()
+
+	return job, q.put(job)
+}
+
+// pending returns the ids of the jobs which were not finished, e.g. before a restart, and counts them as queued.
+// They are counted even above MaxQueued, the limit only applies to the submissions.
+func (q *Jobs) pending() []string {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	iter := q.db.NewIterator(util.BytesPrefix(jobPrefix), nil)
+	defer iter.Release()
+
+	ids := make([]string, 0)
+	for iter.Next() {
+		job := &Job{}
+		if err := json.Unmarshal(iter.Value(), job); err != nil {
+			continue
+		}
+
+		if job.Status == JobQueued || job.Status == JobRunning {
+			ids = append(ids, job.ID)
+		}
+	}
+	q.queued += len(ids)
+
+	return ids
+}
+
+// reserve counts a new unfinished job, it returns false if MaxQueued jobs are not finished
+func (q *Jobs) reserve() bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.config.MaxQueued > 0 && q.queued >= q.config.MaxQueued {
+		return false
+	}
+	q.queued++
+
+	return true
+}
+
+// done releases the place of the job in the queue and forgets its credentials
+func (q *Jobs) done(id string) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.queued--
+	delete(q.credentials, id)
+}
+
+func (q *Jobs) setCredentials(id string, credentials *jobCredentials) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.credentials[id] = credentials
+}
+
+func (q *Jobs) getCredentials(id string) *jobCredentials {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return q.credentials[id]
+}
+
+// removeCredentials removes the credentials from the header and the query of a request,
+// it returns them with the query without the access token, nil if the request has none
+func removeCredentials(header http.Header, rawQuery string) (*jobCredentials, string) {
+	credentials := &jobCredentials{header: make(http.Header)}
+
+	for _, key := range jobCredentialHeaders {
+		if values := header.Values(key); len(values) > 0 {
+			credentials.header[key] = values
+			header.Del(key)
+		}
+	}
+
+	// a malformed query is still parsed up to the error
+	query, _ := url.ParseQuery(rawQuery)
+	if query.Has(jobCredentialQuery) {
+		credentials.query = rawQuery
+		query.Del(jobCredentialQuery)
+		rawQuery = query.Encode()
+	}
+
+	if len(credentials.header) == 0 && credentials.query == "" {
+		return nil, rawQuery
+	}
+
+	return credentials, rawQuery
+}
+
+// isPrivateIP returns true if the address is not reachable from the internet,
+// e.g. a loopback, private or link-local address
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified()
+}
+
+func (q *Jobs) callbackHostAllowed(host string) bool {
+	for _, allowed := range q.config.CallbackAllowedHosts {
+		if strings.EqualFold(allowed, host) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolveCallback returns the addresses of a callback host, an error if one of them is private
+// and the host is not allowed by the operator
+func (q *Jobs) resolveCallback(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	if q.callbackHostAllowed(host) {
+		return addrs, nil
+	}
+
+	for _, addr := range addrs {
+		if isPrivateIP(addr.IP) {
+			return nil, fmt.Errorf("callback host %s resolves to the private address %s", host, addr.IP)
+		}
+	}
+
+	return addrs, nil
+}
+
+// checkCallback returns an error if the callback is not an http url of a public host
+func (q *Jobs) checkCallback(ctx context.Context, callback string) error {
+	callbackURL, err := url.Parse(callback)
+	if err != nil || (callbackURL.Scheme != "http" && callbackURL.Scheme != "https") || callbackURL.Hostname() == "" {
+		return errors.New("expected an http or https url")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, jobCallbackTimeout)
+	defer cancel()
+
+	_, err = q.resolveCallback(ctx, callbackURL.Hostname())
+
+	return err
+}
+
+// dialCallback dials the first reachable address of the callback host which is not private
+func (q *Jobs) dialCallback(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs, err := q.resolveCallback(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: jobCallbackTimeout}
+
+	for _, addr := range addrs {
+		var conn net.Conn
+		if conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(addr.IP.String(), port)); err == nil {
+			return conn, nil
+		}
+	}
+
+	return nil, err
+}
+
+// sweep deletes the expired jobs
+func (q *Jobs) sweep(now time.Time) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	iter := q.db.NewIterator(util.BytesPrefix(jobPrefix), nil)
+	defer iter.Release()
+
+	batch := new(leveldb.Batch)
+	for iter.Next() {
+		job := &Job{}
+		if err := json.Unmarshal(iter.Value(), job); err != nil || (!job.ExpiresAt.IsZero() && now.After(job.ExpiresAt)) {
+			batch.Delete(append([]byte(nil), iter.Key()...))
+		}
+	}
+
+	if batch.Len() == 0 {
+		return
+	}
+
+	if err := q.db.Write(batch, nil); err != nil {
+		q.logger.Error("failed to delete expired jobs", "err", err)
+	}
+}
+
+// setupJobs registers the async job API, the submissions go through the proxy middleware
+func (j *TransparentProxy) setupJobs(mux *http.ServeMux, middleware func(http.Handler) http.Handler, noAuth bool) {
+	submitHandler := middleware(http.HandlerFunc(j.handleJobSubmit))
+	getHandler := j.jobGetHandler(noAuth)
+
+	mux.HandleFunc(JobsUrl, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case IsPreflightRequest(r):
+			j.setCORSHeaders(w, r)
+		case r.Method == http.MethodPost:
+			submitHandler.ServeHTTP(w, r)
+		case r.Method == http.MethodGet:
+			getHandler.ServeHTTP(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	j.resumeJobs()
+
+	j.jobs.wg.Add(1)
+	go j.sweepJobs()
+}
+
+// resumeJobs runs again the jobs which were not finished when the proxy stopped
+func (j *TransparentProxy) resumeJobs() {
+	pending := j.jobs.pending()
+	if len(pending) > 0 {
+		j.logger.Info("resuming jobs", "count", len(pending))
+	}
+
+	for _, id := range pending {
+		j.startJob(id)
+	}
+}
+
+func (j *TransparentProxy) sweepJobs() {
+	defer j.jobs.wg.Done()
+
+	ticker := time.NewTicker(jobSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			j.jobs.sweep(now.UTC())
+		case <-j.jobs.ctx.Done():
+			return
+		}
+	}
+}
+
+// handleJobSubmit stores the request routed by the middleware as a job and responds with its id
+func (j *TransparentProxy) handleJobSubmit(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	pathInfo, ok := r.Context().Value("EdgePath").(*EdgePath)
+	if !ok || pathInfo.NodeID == "" {
+		http.Error(w, "Invalid edge path", http.StatusBadRequest)
+
+		return
+	}
+
+	callback := r.Header.Get(JobCallbackHeader)
+	if callback != "" {
+		if err := j.jobs.checkCallback(r.Context(), callback); err != nil {
+			http.Error(w, fmt.Sprintf("invalid %s: %s", JobCallbackHeader, err), http.StatusBadRequest)
+
+			return
+		}
+	}
+
+	// the queue is checked before the body is read, so a full queue holds no more bodies
+	if !j.jobs.reserve() {
+		metrics.IncrCounterWithLabels([]string{proxyMetrics, "jobs_rejected"}, 1, nil)
+		setRetryAfter(w, jobRetryAfter)
+		http.Error(w, errJobsFull.Error(), http.StatusServiceUnavailable)
+
+		return
+	}
+
+	id := uuid.New().String()
+	started := false
+
+	defer func() {
+		if !started {
+			j.jobs.done(id)
+		}
+	}()
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, j.jobs.config.MaxBodySize+1))
+	if err != nil {
+		http.Error(w, "Failed to read the request body", http.StatusBadRequest)
+
+		return
+	}
+
+	if int64(len(body)) > j.jobs.config.MaxBodySize {
+		http.Error(w, "The request body is too large for a job", http.StatusRequestEntityTooLarge)
+
+		return
+	}
+
+	bearer, _ := r.Context().Value("Bearer").(string)
+	now := time.Now().UTC()
+
+	job := &Job{
+		ID:        id,
+		Status:    JobQueued,
+		Key:       requestPrincipal(r, bearer),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Callback:  callback,
+		EdgePath:  *pathInfo,
+		Method:    r.Method,
+		Query:     r.URL.RawQuery,
+		Header:    make(http.Header),
+		Body:      body,
+	}
+	CopyHeader(job.Header, r.Header, JobCallbackHeader)
+
+	credentials, query := removeCredentials(job.Header, job.Query)
+	if credentials != nil {
+		job.Query = query
+		job.Credentials = true
+		j.jobs.setCredentials(id, credentials)
+	}
+
+	if err := j.jobs.put(job); err != nil {
+		http.Error(w, "Failed to store the job", http.StatusInternalServerError)
+
+		return
+	}
+
+	metrics.IncrCounterWithLabels([]string{proxyMetrics, "jobs"}, 1, []metrics.Label{{Name: "status", Value: string(JobQueued)}})
+	j.logger.Info("job queued", "RequestID", RequestID(r), "JobID", job.ID, "NodeID", pathInfo.NodeID)
+
+	started = true
+	j.startJob(id)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", JobsUrl+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(job.view())
+}
+
+// jobGetHandler serves GET /jobs/<id> and the result of the job with GET /jobs/<id>/result.
+// Only the key which submitted the job can read it, it is authorized for the node of the job as on the submission.
+func (j *TransparentProxy) jobGetHandler(noAuth bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		j.setCORSHeaders(w, r)
+
+		id, resource, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, JobsUrl), "/")
+		if id == "" || (resource != "" && resource != "result") {
+			http.Error(w, "Not found", http.StatusNotFound)
+
+			return
+		}
+
+		// the client IP is limited before the bearer is checked
+		if _, ok := j.checkClientRateLimit(w, r); !ok {
+			return
+		}
+
+		bearer := ""
+		if !noAuth {
+			bearer = getBearer(r)
+			if bearer == "" {
+				IncrAuthFailure("relay", "missing")
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+
+				return
+			}
+		}
+		principal := requestPrincipal(r, bearer)
+
+		// the job of another key is not found, so it is not checked with the store
+		job, err := j.jobs.get(id)
+		if err != nil || (!noAuth && job.Key != principal) {
+			http.Error(w, "Job not found", http.StatusNotFound)
+
+			return
+		}
+
+		if !noAuth {
+			if ok, _ := j.authBearer(r.Context(), bearer, job.EdgePath.NodeID, job.EdgePath.Port); !ok {
+				IncrAuthFailure("relay", "invalid")
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+
+				return
+			}
+		}
+		SetRequestPrincipal(r, principal)
+
+		if resource == "" {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(job.view())
+
+			return
+		}
+
+		if job.Result == nil {
+			http.Error(w, fmt.Sprintf("Job is %s", job.Status), http.StatusConflict)
+
+			return
+		}
+
+		CopyHeader(w.Header(), job.Result.Header)
+		w.WriteHeader(job.Result.Status)
+		_, _ = w.Write(job.Result.Body)
+	})
+}
+
+// startJob runs the job in the background when a worker is free, the job is read from the db
+// by the worker, so the waiting jobs don't hold their body
+func (j *TransparentProxy) startJob(id string) {
+	j.jobs.wg.Add(1)
+
+	go func() {
+		defer j.jobs.wg.Done()
+		defer j.jobs.done(id)
+
+		select {
+		case j.jobs.workers <- struct{}{}:
+		case <-j.jobs.ctx.Done():
+			return
+		}
+		defer func() { <-j.jobs.workers }()
+
+		j.runJob(id)
+	}()
+}
+
+func (j *TransparentProxy) runJob(id string) {
+	job, err := j.jobs.update(id, func(job *Job) { job.Status = JobRunning })
+	if err != nil {
+		j.logger.Error("failed to update job", "JobID", id, "err", err)
+
+		return
+	}
+
+	var result *JobResult
+
+	credentials := j.jobs.getCredentials(id)
+	if job.Credentials && credentials == nil {
+		err = errJobCredentialsLost
+	} else {
+		result, err = j.forwardJob(job, credentials)
+	}
+
+	// the job is resumed on the next start if the proxy is stopping
+	if j.jobs.ctx.Err() != nil {
+		return
+	}
+
+	finished, updateErr := j.jobs.update(job.ID, func(job *Job) {
+		job.Status = JobSucceeded
+		job.Result = result
+		job.ExpiresAt = time.Now().UTC().Add(j.jobs.config.TTL)
+
+		if err != nil {
+			job.Status = JobFailed
+			job.Error = err.Error()
+		}
+	})
+	if updateErr != nil {
+		j.logger.Error("failed to update job", "JobID", job.ID, "err", updateErr)
+
+		return
+	}
+
+	metrics.IncrCounterWithLabels([]string{proxyMetrics, "jobs"}, 1, []metrics.Label{{Name: "status", Value: string(finished.Status)}})
+	j.logger.Info("job finished", "JobID", job.ID, "NodeID", job.EdgePath.NodeID, "status", finished.Status)
+
+	if finished.Callback != "" {
+		j.postJobCallback(finished)
+	}
+}
+
+// forwardJob sends the request of the job to the edge node and reads the result
+func (j *TransparentProxy) forwardJob(job *Job, credentials *jobCredentials) (*JobResult, error) {
+	ctx, cancel := context.WithTimeout(j.jobs.ctx, j.jobs.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, job.Method, JobsUrl+job.ID, bytes.NewReader(job.Body))
+	if err != nil {
+		return nil, err
+	}
+	req.URL.RawQuery = job.Query
+	req.Header = job.Header.Clone()
+	if credentials != nil {
+		for key, values := range credentials.header {
+			req.Header[key] = values
+		}
+
+		if credentials.query != "" {
+			req.URL.RawQuery = credentials.query
+		}
+	}
+	req.ContentLength = int64(len(job.Body))
+
+	pathInfo := job.EdgePath
+
+	if allowed, _ := j.breakers.allow(pathInfo.NodeID); !allowed {
+		return nil, fmt.Errorf("circuit breaker of node %s is open", pathInfo.NodeID)
+	}
+
+	resp, _, err := j.forward(req, &pathInfo, bytes.NewReader(job.Body))
+	j.recordBreaker(req, pathInfo.NodeID, resp, err)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	defer j.balancer.release(pathInfo.NodeID)
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, j.jobs.config.MaxBodySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the result: %w", err)
+	}
+
+	if int64(len(body)) > j.jobs.config.MaxBodySize {
+		return nil, errors.New("the result is too large for a job")
+	}
+
+	result := &JobResult{
+		Status: resp.StatusCode,
+		Header: make(http.Header),
+		Body:   body,
+	}
+	CopyHeader(result.Header, resp.Header, "Content-Length", "Trailer", "Access-Control-Allow-Origin",
+		RequestIDHeader, EdgeNodeHeader, EdgeSignatureHeader, EdgeVerifiedHeader)
+
+	// the body is read, so the signature is either in the headers or in the trailers
+	signature := resp.Header.Get(EdgeSignatureHeader)
+	if signature == "" {
+		signature = resp.Trailer.Get(EdgeSignatureHeader)
+	}
+	bodyHash := sha256.Sum256(body)
+	result.Verified = signature != "" && VerifyResponse(pathInfo.NodeID, job.Header.Get(RequestIDHeader), resp.StatusCode, bodyHash[:], signature)
+
+	result.Header.Set(EdgeNodeHeader, pathInfo.NodeID)
+	result.Header.Set(EdgeVerifiedHeader, strconv.FormatBool(result.Verified))
+	if signature != "" {
+		result.Header.Set(EdgeSignatureHeader, signature)
+	}
+
+	return result, nil
+}
+
+// postJobCallback posts the finished job to its callback url, with a few attempts
+func (j *TransparentProxy) postJobCallback(job *Job) {
+	payload, err := json.Marshal(job.view())
+	if err != nil {
+		return
+	}
+
+	delay := time.Second
+
+	for attempt := 1; attempt <= jobCallbackAttempts; attempt++ {
+		req, err := http.NewRequestWithContext(j.jobs.ctx, http.MethodPost, job.Callback, bytes.NewReader(payload))
+		if err != nil {
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(RequestIDHeader, job.Header.Get(RequestIDHeader))
+
+		resp, err := j.jobs.callbackClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+
+			if resp.StatusCode < http.StatusMultipleChoices {
+				return
+			}
+			err = fmt.Errorf("callback returned %d", resp.StatusCode)
+		}
+
+		j.logger.Warn("job callback failed", "JobID", job.ID, "attempt", attempt, "err", err)
+
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-j.jobs.ctx.Done():
+			return
+		}
+	}
+}
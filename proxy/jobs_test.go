@@ -0,0 +1,259 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestJobsProxy returns a proxy with the jobs stored in a temporary db.
+// Without workers, the submitted jobs stay queued.
+func newTestJobsProxy(t *testing.T, config *JobsConfig) *TransparentProxy {
+	t.Helper()
+
+	config.DBPath = t.TempDir()
+
+	jobs, err := NewJobs(hclog.NewNullLogger(), config)
+	require.NoError(t, err)
+	t.Cleanup(jobs.Close)
+
+	j := newTestProxy(&Config{})
+	j.jobs = jobs
+
+	return j
+}
+
+func submitJob(j *TransparentProxy, target string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(`{"prompt":"a lighthouse"}`))
+	for key, values := range header {
+		r.Header[key] = values
+	}
+
+	pathInfo := &EdgePath{NodeID: "node1", Port: 9527, InterfaceURL: "v1/render"}
+	r = r.WithContext(context.WithValue(r.Context(), "EdgePath", pathInfo))
+
+	w := httptest.NewRecorder()
+	j.handleJobSubmit(w, r)
+
+	return w
+}
+
+func TestRemoveCredentials(t *testing.T) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer token")
+	header.Set("Cookie", "session=1")
+	header.Set("Content-Type", "application/json")
+
+	credentials, query := removeCredentials(header, "stream=true&access_token=secret")
+	require.NotNil(t, credentials)
+
+	assert.Equal(t, http.Header{"Content-Type": {"application/json"}}, header)
+	assert.Equal(t, "stream=true", query)
+	assert.Equal(t, "Bearer token", credentials.header.Get("Authorization"))
+	assert.Equal(t, "session=1", credentials.header.Get("Cookie"))
+	assert.Equal(t, "stream=true&access_token=secret", credentials.query)
+
+	credentials, query = removeCredentials(http.Header{"Accept": {"*/*"}}, "stream=true")
+	assert.Nil(t, credentials)
+	assert.Equal(t, "stream=true", query)
+}
+
+func TestJobSubmitKeepsCredentialsOutOfDB(t *testing.T) {
+	j := newTestJobsProxy(t, &JobsConfig{MaxBodySize: 1024})
+
+	w := submitJob(j, JobsUrl+"node1/9527/v1/render?access_token=secret", http.Header{
+		"Authorization": {"Bearer api-token"},
+		"Cookie":        {"session=1"},
+	})
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	id := strings.TrimPrefix(w.Header().Get("Location"), JobsUrl)
+
+	value, err := j.jobs.db.Get(jobKey(id), nil)
+	require.NoError(t, err)
+	assert.NotContains(t, string(value), "api-token")
+	assert.NotContains(t, string(value), "session=1")
+	assert.NotContains(t, string(value), "secret")
+
+	job, err := j.jobs.get(id)
+	require.NoError(t, err)
+	assert.True(t, job.Credentials)
+
+	// the forward of the job gets the credentials from memory
+	credentials := j.jobs.getCredentials(id)
+	require.NotNil(t, credentials)
+	assert.Equal(t, "Bearer api-token", credentials.header.Get("Authorization"))
+	assert.Equal(t, "access_token=secret", credentials.query)
+}
+
+func TestJobCredentialsLostInRestart(t *testing.T) {
+	j := newTestJobsProxy(t, &JobsConfig{MaxBodySize: 1024, TTL: time.Hour})
+
+	now := time.Now().UTC()
+	require.NoError(t, j.jobs.put(&Job{
+		ID:          "resumed",
+		Status:      JobQueued,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		EdgePath:    EdgePath{NodeID: "node1"},
+		Method:      http.MethodPost,
+		Header:      http.Header{},
+		Credentials: true,
+	}))
+
+	// the job is failed without a forward, which would be refused by the edge node
+	j.runJob("resumed")
+
+	job, err := j.jobs.get("resumed")
+	require.NoError(t, err)
+	assert.Equal(t, JobFailed, job.Status)
+	assert.Equal(t, errJobCredentialsLost.Error(), job.Error)
+}
+
+func TestJobSubmitQueueFull(t *testing.T) {
+	j := newTestJobsProxy(t, &JobsConfig{MaxBodySize: 1024, MaxQueued: 2})
+
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusAccepted, submitJob(j, JobsUrl+"node1/9527/v1/render", nil).Code)
+	}
+
+	w := submitJob(j, JobsUrl+"node1/9527/v1/render", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+
+	// a refused submission doesn't take a place in the queue
+	assert.Equal(t, 2, j.jobs.queued)
+
+	// the place of a finished job is released
+	j.jobs.done("")
+	assert.Equal(t, http.StatusAccepted, submitJob(j, JobsUrl+"node1/9527/v1/render", nil).Code)
+}
+
+func TestJobSubmitRejectedBodyReleasesQueue(t *testing.T) {
+	j := newTestJobsProxy(t, &JobsConfig{MaxBodySize: 4, MaxQueued: 1})
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, submitJob(j, JobsUrl+"node1/9527/v1/render", nil).Code)
+	assert.Equal(t, 0, j.jobs.queued)
+}
+
+func TestJobCheckCallback(t *testing.T) {
+	j := newTestJobsProxy(t, &JobsConfig{MaxBodySize: 1024, CallbackAllowedHosts: []string{"hooks.internal", "127.0.0.2"}})
+
+	tests := []struct {
+		callback string
+		expected string
+	}{
+		{"ftp://example.com/hooks", "expected an http or https url"},
+		{"http:///hooks", "expected an http or https url"},
+		{"http://127.0.0.1:8080/hooks", "private address 127.0.0.1"},
+		{"http://localhost/hooks", "private address"},
+		{"http://[::1]/hooks", "private address ::1"},
+		{"http://10.0.0.1/hooks", "private address 10.0.0.1"},
+		{"http://192.168.1.1/hooks", "private address 192.168.1.1"},
+		{"http://169.254.169.254/latest/meta-data", "private address 169.254.169.254"},
+		{"http://0.0.0.0/hooks", "private address 0.0.0.0"},
+		{"http://[::ffff:127.0.0.1]/hooks", "private address 127.0.0.1"},
+		{"https://93.184.216.34/hooks", ""},
+		// allowed by the operator
+		{"http://127.0.0.2:8080/hooks", ""},
+	}
+
+	for _, test := range tests {
+		err := j.jobs.checkCallback(context.Background(), test.callback)
+		if test.expected == "" {
+			assert.NoError(t, err, test.callback)
+		} else {
+			assert.ErrorContains(t, err, test.expected, test.callback)
+		}
+	}
+
+	w := submitJob(j, JobsUrl+"node1/9527/v1/render", http.Header{JobCallbackHeader: {"http://127.0.0.1/hooks"}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "private address")
+}
+
+func TestJobCallbackDialsCheckedAddress(t *testing.T) {
+	callbacks := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callbacks++
+	}))
+	defer srv.Close()
+
+	// a callback redirected or resolved to a private address after the submission is not dialed
+	j := newTestJobsProxy(t, &JobsConfig{MaxBodySize: 1024})
+
+	_, err := j.jobs.callbackClient.Post(srv.URL, "application/json", nil)
+	assert.ErrorContains(t, err, "private address 127.0.0.1")
+	assert.Equal(t, 0, callbacks)
+
+	allowed := newTestJobsProxy(t, &JobsConfig{MaxBodySize: 1024, CallbackAllowedHosts: []string{"127.0.0.1"}})
+
+	resp, err := allowed.jobs.callbackClient.Post(srv.URL, "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, 1, callbacks)
+}
+
+func TestJobGetAuthorizedForNode(t *testing.T) {
+	j := newTestJobsProxy(t, &JobsConfig{MaxBodySize: 1024, TTL: time.Hour})
+
+	// the relay keys are not valid on the edge side
+	store := &relayKeyStore{testStore: &testStore{
+		keys:   map[string]bool{"key-1": true, "key-2": true},
+		scopes: map[string][]string{"key-1": {"node1"}, "key-2": {"node2"}},
+	}}
+	j.config.Store = store
+
+	now := time.Now().UTC()
+	require.NoError(t, j.jobs.put(&Job{
+		ID:        "job1",
+		Status:    JobQueued,
+		Key:       BearerPrincipal("key-1"),
+		CreatedAt: now,
+		UpdatedAt: now,
+		EdgePath:  EdgePath{NodeID: "node1", Port: 9527},
+		Header:    http.Header{},
+	}))
+
+	get := func(target string, bearer string, noAuth bool) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		if bearer != "" {
+			r.Header.Set("Authorization", "Bearer "+bearer)
+		}
+
+		w := httptest.NewRecorder()
+		j.jobGetHandler(noAuth).ServeHTTP(w, r)
+
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, get(JobsUrl+"job1", "", false).Code)
+
+	// the key of the job is authorized for the node and the port of the job
+	w := get(JobsUrl+"job1", "key-1", false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"queued"`)
+	assert.Equal(t, []string{"node1:9527"}, store.authorized)
+
+	assert.Equal(t, http.StatusConflict, get(JobsUrl+"job1/result", "key-1", false).Code)
+
+	// another key doesn't find the job, without a check with the store
+	store.authorized = nil
+
+	assert.Equal(t, http.StatusNotFound, get(JobsUrl+"job1", "key-2", false).Code)
+	assert.Equal(t, http.StatusNotFound, get(JobsUrl+"missing", "key-1", false).Code)
+	assert.Empty(t, store.authorized)
+
+	// a revoked key can't read its job anymore
+	delete(store.keys, "key-1")
+	assert.Equal(t, http.StatusUnauthorized, get(JobsUrl+"job1", "key-1", false).Code)
+
+	assert.Equal(t, http.StatusOK, get(JobsUrl+"job1", "", true).Code)
+}
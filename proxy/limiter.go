@@ -0,0 +1,223 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+)
+
+const (
+	// EdgeLoadHeader is the load of the service which served the request on the edge node,
+	// the requests in flight and queued over the concurrency limit, 1 or more when the service is saturated
+	EdgeLoadHeader = "X-Edge-Load"
+	// EdgeQueuePositionHeader is the position of a request in the queue when it was rejected
+	EdgeQueuePositionHeader = "X-Edge-Queue-Position"
+)
+
+// ErrQueueFull is returned when the queue of the service is full
+var ErrQueueFull = errors.New("the request queue of the service is full")
+
+// ErrQueueTimeout is returned when a request waited in the queue for longer than the maximum wait
+var ErrQueueTimeout = errors.New("the request waited too long in the queue of the service")
+
+// ConcurrencyConfig limits the requests served at once by a service
+type ConcurrencyConfig struct {
+	// MaxConcurrent is the number of requests served at once, unlimited if 0
+	MaxConcurrent int
+	// MaxQueue is the number of requests waiting for a slot, the others are rejected
+	MaxQueue int
+	// MaxWait is the maximum time a request waits in the queue, unlimited if 0
+	MaxWait time.Duration
+}
+
+// queueWaiter is a request waiting for a slot, ready is closed when it gets one
+type queueWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// ConcurrencyLimiter serves at most MaxConcurrent requests at once,
+// the other requests wait for a slot in a FIFO queue
+type ConcurrencyLimiter struct {
+	name   string
+	config *ConcurrencyConfig
+
+	lock     sync.Mutex
+	inFlight int
+	queue    []*queueWaiter
+}
+
+func NewConcurrencyLimiter(name string, config *ConcurrencyConfig) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		name:   name,
+		config: config,
+	}
+}
+
+// Acquire waits for a slot, the returned func releases it.
+// On failure, it returns the position the request had in the queue, 0 if it was not queued.
+// It is safe to call on a nil ConcurrencyLimiter, the requests are not limited.
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context) (func(), int, error) {
+	if l == nil {
+		return func() {}, 0, nil
+	}
+
+	l.lock.Lock()
+
+	if l.config.MaxConcurrent <= 0 || (l.inFlight < l.config.MaxConcurrent && len(l.queue) == 0) {
+		l.inFlight++
+		l.updateGauges()
+		l.lock.Unlock()
+
+		return l.release, 0, nil
+	}
+
+	if len(l.queue) >= l.config.MaxQueue {
+		l.lock.Unlock()
+		l.incrRejected("full")
+
+		return nil, 0, ErrQueueFull
+	}
+
+	waiter := &queueWaiter{ready: make(chan struct{})}
+	l.queue = append(l.queue, waiter)
+	position := len(l.queue)
+	l.updateGauges()
+	l.lock.Unlock()
+
+	var timeout <-chan time.Time
+	if l.config.MaxWait > 0 {
+		timer := time.NewTimer(l.config.MaxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	err := ErrQueueTimeout
+	select {
+	case <-waiter.ready:
+		return l.release, 0, nil
+	case <-timeout:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	// the slot may have been granted while the request stopped waiting
+	if waiter.granted {
+		l.inFlight--
+		l.grant()
+	} else {
+		l.remove(waiter)
+	}
+	l.updateGauges()
+
+	if err == ErrQueueTimeout {
+		l.incrRejected("timeout")
+	}
+
+	return nil, position, err
+}
+
+func (l *ConcurrencyLimiter) release() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.inFlight--
+	l.grant()
+	l.updateGauges()
+}
+
+// grant hands the free slots to the first requests of the queue
+func (l *ConcurrencyLimiter) grant() {
+	for len(l.queue) > 0 && l.inFlight < l.config.MaxConcurrent {
+		waiter := l.queue[0]
+		l.queue = l.queue[1:]
+
+		waiter.granted = true
+		close(waiter.ready)
+		l.inFlight++
+	}
+}
+
+func (l *ConcurrencyLimiter) remove(waiter *queueWaiter) {
+	for i, queued := range l.queue {
+		if queued == waiter {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+
+			return
+		}
+	}
+}
+
+func (l *ConcurrencyLimiter) updateGauges() {
+	labels := []metrics.Label{{Name: "upstream", Value: l.name}}
+	metrics.SetGaugeWithLabels([]string{proxyMetrics, "upstream_in_flight"}, float32(l.inFlight), labels)
+	metrics.SetGaugeWithLabels([]string{proxyMetrics, "upstream_queue_depth"}, float32(len(l.queue)), labels)
+}
+
+func (l *ConcurrencyLimiter) incrRejected(reason string) {
+	metrics.IncrCounterWithLabels([]string{proxyMetrics, "upstream_queue_rejected"}, 1,
+		[]metrics.Label{{Name: "upstream", Value: l.name}, {Name: "reason", Value: reason}})
+}
+
+// InFlight returns the number of requests served and queued
+func (l *ConcurrencyLimiter) InFlight() (int, int) {
+	if l == nil {
+		return 0, 0
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.inFlight, len(l.queue)
+}
+
+// Load returns the requests in flight and queued over the concurrency limit, 0 if unlimited
+func (l *ConcurrencyLimiter) Load() float64 {
+	if l == nil || l.config.MaxConcurrent <= 0 {
+		return 0
+	}
+
+	inFlight, queued := l.InFlight()
+
+	return float64(inFlight+queued) / float64(l.config.MaxConcurrent)
+}
+
+// SetLoadHeader advertises the load of the service to the relay
+func (l *ConcurrencyLimiter) SetLoadHeader(header http.Header) {
+	if l == nil || l.config.MaxConcurrent <= 0 {
+		return
+	}
+
+	header.Set(EdgeLoadHeader, strconv.FormatFloat(l.Load(), 'f', 2, 64))
+}
+
+// WriteRejection responds to a request which didn't get a slot: 429 if the queue is full,
+// 503 with its position if it waited too long in the queue
+func (l *ConcurrencyLimiter) WriteRejection(w http.ResponseWriter, position int, err error) {
+	l.SetLoadHeader(w.Header())
+
+	retryAfter := l.config.MaxWait
+	if retryAfter <= 0 {
+		retryAfter = time.Second
+	}
+	setRetryAfter(w, retryAfter)
+
+	if errors.Is(err, ErrQueueFull) {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+
+		return
+	}
+
+	if position > 0 {
+		w.Header().Set(EdgeQueuePositionHeader, strconv.Itoa(position))
+	}
+	http.Error(w, err.Error(), http.StatusServiceUnavailable)
+}
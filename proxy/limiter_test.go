@@ -0,0 +1,208 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitQueued waits until the limiter has the number of queued requests
+func waitQueued(t *testing.T, l *ConcurrencyLimiter, queued int) {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		_, current := l.InFlight()
+
+		return current == queued
+	}, time.Second, time.Millisecond)
+}
+
+func TestConcurrencyLimiterFIFO(t *testing.T) {
+	l := NewConcurrencyLimiter("app", &ConcurrencyConfig{MaxConcurrent: 1, MaxQueue: 3})
+
+	release, _, err := l.Acquire(context.Background())
+	require.NoError(t, err)
+
+	var (
+		lock  sync.Mutex
+		order []int
+		wg    sync.WaitGroup
+	)
+
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			release, _, err := l.Acquire(context.Background())
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			lock.Lock()
+			order = append(order, i)
+			lock.Unlock()
+
+			release()
+		}(i)
+
+		// the requests are queued one after the other
+		waitQueued(t, l, i+1)
+	}
+
+	release()
+	wg.Wait()
+
+	assert.Equal(t, []int{0, 1, 2}, order)
+
+	inFlight, queued := l.InFlight()
+	assert.Equal(t, 0, inFlight)
+	assert.Equal(t, 0, queued)
+}
+
+func TestConcurrencyLimiterGrantedWhileTimingOut(t *testing.T) {
+	l := NewConcurrencyLimiter("app", &ConcurrencyConfig{MaxConcurrent: 1, MaxQueue: 1, MaxWait: 20 * time.Millisecond})
+
+	_, _, err := l.Acquire(context.Background())
+	require.NoError(t, err)
+
+	type result struct {
+		position int
+		err      error
+	}
+	done := make(chan result)
+
+	go func() {
+		_, position, err := l.Acquire(context.Background())
+		done <- result{position, err}
+	}()
+	waitQueued(t, l, 1)
+
+	// the request times out while the slot is released and granted to it under the lock
+	l.lock.Lock()
+	time.Sleep(100 * time.Millisecond)
+	l.inFlight--
+	l.grant()
+	l.lock.Unlock()
+
+	res := <-done
+	assert.ErrorIs(t, res.err, ErrQueueTimeout)
+	assert.Equal(t, 1, res.position)
+
+	// the granted slot is not leaked
+	inFlight, queued := l.InFlight()
+	assert.Equal(t, 0, inFlight)
+	assert.Equal(t, 0, queued)
+
+	release, _, err := l.Acquire(context.Background())
+	require.NoError(t, err)
+	release()
+}
+
+func TestConcurrencyLimiterRejections(t *testing.T) {
+	l := NewConcurrencyLimiter("app", &ConcurrencyConfig{MaxConcurrent: 1, MaxQueue: 1, MaxWait: 50 * time.Millisecond})
+
+	release, _, err := l.Acquire(context.Background())
+	require.NoError(t, err)
+	defer release()
+
+	timedOut := make(chan error)
+
+	go func() {
+		_, position, err := l.Acquire(context.Background())
+
+		w := httptest.NewRecorder()
+		l.WriteRejection(w, position, err)
+
+		// a request which waited too long gets a 503 with its position in the queue
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "1", w.Header().Get(EdgeQueuePositionHeader))
+		assert.Equal(t, "1", w.Header().Get("Retry-After"))
+		assert.Equal(t, "1.00", w.Header().Get(EdgeLoadHeader))
+
+		timedOut <- err
+	}()
+	waitQueued(t, l, 1)
+
+	// a request refused by a full queue gets a 429 without a position
+	_, position, err := l.Acquire(context.Background())
+	assert.ErrorIs(t, err, ErrQueueFull)
+	assert.Equal(t, 0, position)
+
+	w := httptest.NewRecorder()
+	l.WriteRejection(w, position, err)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Empty(t, w.Header().Get(EdgeQueuePositionHeader))
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	assert.ErrorIs(t, <-timedOut, ErrQueueTimeout)
+}
+
+func TestConcurrencyLimiterCanceled(t *testing.T) {
+	l := NewConcurrencyLimiter("app", &ConcurrencyConfig{MaxConcurrent: 1, MaxQueue: 2})
+
+	release, _, err := l.Acquire(context.Background())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := make(chan error)
+
+	go func() {
+		_, _, err := l.Acquire(ctx)
+		canceled <- err
+	}()
+	waitQueued(t, l, 1)
+
+	cancel()
+	assert.ErrorIs(t, <-canceled, context.Canceled)
+
+	// the canceled request left the queue
+	_, queued := l.InFlight()
+	assert.Equal(t, 0, queued)
+
+	release()
+}
+
+func TestConcurrencyLimiterNoQueue(t *testing.T) {
+	l := NewConcurrencyLimiter("app", &ConcurrencyConfig{MaxConcurrent: 1, MaxQueue: 0})
+
+	release, _, err := l.Acquire(context.Background())
+	require.NoError(t, err)
+
+	// without a queue, the requests over the limit are rejected at once
+	_, position, err := l.Acquire(context.Background())
+	assert.ErrorIs(t, err, ErrQueueFull)
+	assert.Equal(t, 0, position)
+
+	release()
+
+	release, _, err = l.Acquire(context.Background())
+	require.NoError(t, err)
+	release()
+}
+
+func TestConcurrencyLimiterUnlimited(t *testing.T) {
+	l := NewConcurrencyLimiter("app", &ConcurrencyConfig{})
+
+	for i := 0; i < 10; i++ {
+		_, _, err := l.Acquire(context.Background())
+		require.NoError(t, err)
+	}
+
+	inFlight, _ := l.InFlight()
+	assert.Equal(t, 10, inFlight)
+	assert.Equal(t, float64(0), l.Load())
+
+	var nilLimiter *ConcurrencyLimiter
+
+	release, _, err := nilLimiter.Acquire(context.Background())
+	require.NoError(t, err)
+	release()
+}
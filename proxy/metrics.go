@@ -0,0 +1,124 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// proxyMetrics is a prefix used for the transparent proxy and edge forward metrics
+const proxyMetrics = "proxy"
+
+// requestDuration is registered directly with prometheus, as go-metrics only exports summaries
+var requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "edge",
+	Subsystem: proxyMetrics,
+	Name:      "request_duration_seconds",
+	Help:      "Duration of the proxied requests by hop, node and status",
+	Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+}, []string{"hop", "node_id", "status"})
+
+func init() {
+	prometheus.MustRegister(requestDuration)
+}
+
+// requestMetrics holds the labels of a request which are known after routing
+type requestMetrics struct {
+	nodeID string
+}
+
+// MetricsHandler counts the requests of the hop, their duration and the bytes proxied
+func MetricsHandler(hop string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		labels := &requestMetrics{}
+		recorder := &responseRecorder{ResponseWriter: w}
+		body := &countingBody{ReadCloser: r.Body}
+		r.Body = body
+
+		next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), "RequestMetrics", labels)))
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		metrics.IncrCounterWithLabels([]string{proxyMetrics, "requests"}, 1, []metrics.Label{
+			{Name: "hop", Value: hop},
+			{Name: "node_id", Value: labels.nodeID},
+			{Name: "status", Value: strconv.Itoa(status)},
+		})
+		requestDuration.WithLabelValues(hop, labels.nodeID, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+
+		metrics.IncrCounterWithLabels([]string{proxyMetrics, "bytes"}, float32(body.bytes), []metrics.Label{
+			{Name: "hop", Value: hop},
+			{Name: "direction", Value: "in"},
+		})
+		metrics.IncrCounterWithLabels([]string{proxyMetrics, "bytes"}, float32(recorder.bytes), []metrics.Label{
+			{Name: "hop", Value: hop},
+			{Name: "direction", Value: "out"},
+		})
+	})
+}
+
+// countingBody counts the bytes read from the request body
+type countingBody struct {
+	io.ReadCloser
+	bytes int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.bytes += int64(n)
+
+	return n, err
+}
+
+// SetRequestTarget records the target node of the request in its access log, span and metrics
+func SetRequestTarget(r *http.Request, nodeID string, port int) {
+	setAccessLogTarget(r, nodeID, port)
+	setSpanTarget(r, nodeID, port)
+	setUsageTarget(r, nodeID, port)
+
+	if labels, ok := r.Context().Value("RequestMetrics").(*requestMetrics); ok {
+		labels.nodeID = nodeID
+	}
+}
+
+// SetRequestPrincipal records the authenticated principal of the request in its access log and usage record
+func SetRequestPrincipal(r *http.Request, principal string) {
+	setAccessLogPrincipal(r, principal)
+	setUsagePrincipal(r, principal)
+}
+
+// activeStreams holds the number of active streamed responses by hop
+var activeStreams sync.Map
+
+// TrackStream increments the active streamed responses (SSE, NDJSON, chunked) of the hop,
+// the returned func decrements them
+func TrackStream(hop string) func() {
+	value, _ := activeStreams.LoadOrStore(hop, new(int64))
+	active := value.(*int64)
+	labels := []metrics.Label{{Name: "hop", Value: hop}}
+
+	metrics.SetGaugeWithLabels([]string{proxyMetrics, "streams"}, float32(atomic.AddInt64(active, 1)), labels)
+
+	return func() {
+		metrics.SetGaugeWithLabels([]string{proxyMetrics, "streams"}, float32(atomic.AddInt64(active, -1)), labels)
+	}
+}
+
+// IncrAuthFailure counts a rejected bearer on the hop
+func IncrAuthFailure(hop string, reason string) {
+	metrics.IncrCounterWithLabels([]string{proxyMetrics, "auth_failures"}, 1, []metrics.Label{
+		{Name: "hop", Value: hop},
+		{Name: "reason", Value: reason},
+	})
+}
@@ -0,0 +1,134 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordMetrics sends the metrics of the test to an in-memory sink
+func recordMetrics(t *testing.T) *metrics.InmemSink {
+	t.Helper()
+
+	conf := metrics.DefaultConfig("edge")
+	conf.EnableHostname = false
+	conf.EnableRuntimeMetrics = false
+
+	inm := metrics.NewInmemSink(time.Minute, time.Minute)
+	_, err := metrics.NewGlobal(conf, inm)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_, _ = metrics.NewGlobal(conf, &metrics.BlackholeSink{})
+	})
+
+	return inm
+}
+
+// currentMetrics returns the metrics of the current interval
+func currentMetrics(inm *metrics.InmemSink) *metrics.IntervalMetrics {
+	data := inm.Data()
+
+	return data[len(data)-1]
+}
+
+// durationSamples returns the number of observations of the request duration histogram with the labels
+func durationSamples(t *testing.T, hop string, nodeID string, status string) uint64 {
+	t.Helper()
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+
+	for _, family := range families {
+		if family.GetName() != "edge_proxy_request_duration_seconds" {
+			continue
+		}
+
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+
+			if labels["hop"] == hop && labels["node_id"] == nodeID && labels["status"] == status {
+				return metric.GetHistogram().GetSampleCount()
+			}
+		}
+	}
+
+	return 0
+}
+
+func TestMetricsHandler(t *testing.T) {
+	inm := recordMetrics(t)
+	samples := durationSamples(t, "relay", "node1", "201")
+
+	handler := MetricsHandler("relay", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		SetRequestTarget(r, "node1", 9527)
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hello"))
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/edge/node1/9527/v1", strings.NewReader(`{"a":1}`)))
+
+	// the requests are labeled with the node set by the handler, an unrouted request has no node
+	handler = MetricsHandler("relay", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	interval := currentMetrics(inm)
+	interval.RLock()
+	defer interval.RUnlock()
+
+	assert.Equal(t, 1, interval.Counters["edge.proxy.requests;hop=relay;node_id=node1;status=201"].Count)
+	assert.Equal(t, 1, interval.Counters["edge.proxy.requests;hop=relay;node_id=;status=200"].Count)
+	assert.Equal(t, float64(7), interval.Counters["edge.proxy.bytes;hop=relay;direction=in"].Sum)
+	assert.Equal(t, float64(5), interval.Counters["edge.proxy.bytes;hop=relay;direction=out"].Sum)
+
+	assert.Equal(t, samples+1, durationSamples(t, "relay", "node1", "201"))
+}
+
+func TestTrackStream(t *testing.T) {
+	inm := recordMetrics(t)
+
+	gauge := func() float32 {
+		interval := currentMetrics(inm)
+		interval.RLock()
+		defer interval.RUnlock()
+
+		return interval.Gauges["edge.proxy.streams;hop=edge"].Value
+	}
+
+	done1 := TrackStream("edge")
+	done2 := TrackStream("edge")
+	assert.Equal(t, float32(2), gauge())
+
+	done1()
+	assert.Equal(t, float32(1), gauge())
+
+	done2()
+	assert.Equal(t, float32(0), gauge())
+}
+
+func TestIncrAuthFailure(t *testing.T) {
+	inm := recordMetrics(t)
+
+	IncrAuthFailure("relay", "invalid")
+	IncrAuthFailure("relay", "invalid")
+	IncrAuthFailure("edge", "missing")
+
+	interval := currentMetrics(inm)
+	interval.RLock()
+	defer interval.RUnlock()
+
+	assert.Equal(t, 2, interval.Counters["edge.proxy.auth_failures;hop=relay;reason=invalid"].Count)
+	assert.Equal(t, 1, interval.Counters["edge.proxy.auth_failures;hop=edge;reason=missing"].Count)
+}
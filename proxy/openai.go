@@ -0,0 +1,463 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/armon/go-metrics"
+)
+
+const (
+	OpenAIModelsUrl          = "/v1/models"
+	OpenAIChatCompletionsUrl = "/v1/chat/completions"
+	OpenAICompletionsUrl     = "/v1/completions"
+)
+
+// maxOpenAIRequestSize is the largest completion request read by the gateway to find its model
+const maxOpenAIRequestSize = 16 << 20
+
+// maxOpenAIUsageBody is the largest non-streamed response parsed for its usage
+const maxOpenAIUsageBody = 4 << 20
+
+// openAIMetrics is a prefix used for the OpenAI gateway metrics
+const openAIMetrics = "openai"
+
+// OpenAIConfig enables the OpenAI-compatible gateway of the transparent proxy.
+// A model is routed to the nodes advertising the app of the same name, unless it is mapped to another app.
+type OpenAIConfig struct {
+	// Models maps a model name to the app name advertised by the nodes serving it
+	Models map[string]string
+}
+
+// OpenAIUsage is the usage reported by the OpenAI-compatible webapps in their responses
+type OpenAIUsage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+// openAIRequest holds the fields of a completion request used for routing
+type openAIRequest struct {
+	Model   string `json:"model"`
+	AppName string `json:"-"`
+	Path    string `json:"-"`
+}
+
+type openAIModel struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+type openAIModelList struct {
+	Object string        `json:"object"`
+	Data   []openAIModel `json:"data"`
+}
+
+// writeOpenAIError writes an error in the format of the OpenAI API, so the SDKs can surface it
+func writeOpenAIError(w http.ResponseWriter, status int, errType string, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": message,
+			"type":    errType,
+			"code":    nil,
+		},
+	})
+}
+
+// appName returns the app name of the nodes serving the model
+func (c *OpenAIConfig) appName(model string) string {
+	if appName, ok := c.Models[model]; ok {
+		return appName
+	}
+
+	return model
+}
+
+// openAICompletionsPaths are the interface paths of the completions, a model is listed if one of them can be reached
+var openAICompletionsPaths = []string{
+	strings.TrimPrefix(OpenAIChatCompletionsUrl, "/"),
+	strings.TrimPrefix(OpenAICompletionsUrl, "/"),
+}
+
+// setupOpenAI registers the OpenAI-compatible endpoints, the completions go through the proxy middleware
+func (j *TransparentProxy) setupOpenAI(mux *http.ServeMux, middleware func(http.Handler) http.Handler, noAuth bool) {
+	completionsHandler := j.openAIRequestHandler(middleware(j.openAIUsageHandler(http.HandlerFunc(j.handle))), noAuth)
+
+	mux.Handle(OpenAIChatCompletionsUrl, completionsHandler)
+	mux.Handle(OpenAICompletionsUrl, completionsHandler)
+	mux.HandleFunc(OpenAIModelsUrl, j.openAIModelsHandler(noAuth))
+}
+
+// checkOpenAIBearer rejects the requests without a bearer before anything else is read.
+// The bearer is authorized by the relay for the nodes it reaches, as the other requests.
+func (j *TransparentProxy) checkOpenAIBearer(w http.ResponseWriter, r *http.Request) (string, bool) {
+	bearer := getBearer(r)
+	if bearer == "" {
+		IncrAuthFailure("relay", "missing")
+		writeOpenAIError(w, http.StatusUnauthorized, "invalid_request_error", "you didn't provide an api key")
+
+		return "", false
+	}
+
+	return bearer, true
+}
+
+// openAIRequestHandler reads the model of the completion request and keeps it in the context for routing.
+// A request without a bearer is rejected first, the middleware then authorizes the bearer for the node picked for the model.
+func (j *TransparentProxy) openAIRequestHandler(next http.Handler, noAuth bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if IsPreflightRequest(r) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), "OpenAIRequest", &openAIRequest{Path: r.URL.Path})))
+
+			return
+		}
+
+		if !noAuth {
+			j.setCORSHeaders(w, r)
+			if _, ok := j.checkOpenAIBearer(w, r); !ok {
+				return
+			}
+		}
+
+		if r.Method != http.MethodPost {
+			writeOpenAIError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method not allowed")
+
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxOpenAIRequestSize+1))
+		r.Body.Close()
+
+		if err != nil {
+			writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "failed to read the request")
+
+			return
+		}
+
+		if len(body) > maxOpenAIRequestSize {
+			writeOpenAIError(w, http.StatusRequestEntityTooLarge, "invalid_request_error", "the request is too large")
+
+			return
+		}
+
+		request := &openAIRequest{Path: r.URL.Path}
+		if err := json.Unmarshal(body, request); err != nil {
+			writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("invalid request: %s", err))
+
+			return
+		}
+
+		if request.Model == "" {
+			writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "you must provide a model parameter")
+
+			return
+		}
+
+		request.AppName = j.config.OpenAI.appName(request.Model)
+		if len(j.config.Store.GetAppPeers(request.AppName)) == 0 {
+			writeOpenAIError(w, http.StatusNotFound, "invalid_request_error", fmt.Sprintf("the model '%s' does not exist", request.Model))
+
+			return
+		}
+
+		// the body is forwarded unchanged
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), "OpenAIRequest", request)))
+	})
+}
+
+// parseOpenAIPath routes a completion request to the app of its model, the webapp serves the same path
+func parseOpenAIPath(r *http.Request) (*EdgePath, error) {
+	request, ok := r.Context().Value("OpenAIRequest").(*openAIRequest)
+	if !ok {
+		return nil, errors.New("invalid OpenAI request")
+	}
+
+	if IsPreflightRequest(r) {
+		return &EdgePath{}, nil
+	}
+
+	return &EdgePath{
+		AppName:      request.AppName,
+		InterfaceURL: strings.TrimPrefix(request.Path, "/"),
+	}, nil
+}
+
+// openAIUsageHandler records the usage of the completion responses by API key and model
+func (j *TransparentProxy) openAIUsageHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request, ok := r.Context().Value("OpenAIRequest").(*openAIRequest)
+		if !ok || IsPreflightRequest(r) {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		recorder := &usageRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+
+		if usage := recorder.usage(); usage != nil {
+			bearer, _ := r.Context().Value("Bearer").(string)
+			j.recordOpenAIUsage(requestPrincipal(r, bearer), request.Model, usage)
+			setUsageTokens(r, usage)
+		}
+	})
+}
+
+// recordOpenAIUsage counts the tokens used by the principal of an API key
+func (j *TransparentProxy) recordOpenAIUsage(principal string, model string, usage *OpenAIUsage) {
+	labels := []metrics.Label{
+		{Name: "key", Value: principal},
+		{Name: "model", Value: model},
+	}
+
+	metrics.IncrCounterWithLabels([]string{openAIMetrics, "requests"}, 1, labels)
+	metrics.IncrCounterWithLabels([]string{openAIMetrics, "prompt_tokens"}, float32(usage.PromptTokens), labels)
+	metrics.IncrCounterWithLabels([]string{openAIMetrics, "completion_tokens"}, float32(usage.CompletionTokens), labels)
+	metrics.IncrCounterWithLabels([]string{openAIMetrics, "total_tokens"}, float32(usage.TotalTokens), labels)
+
+	j.logger.Debug("openai usage", "principal", principal, "model", model,
+		"prompt_tokens", usage.PromptTokens, "completion_tokens", usage.CompletionTokens, "total_tokens", usage.TotalTokens)
+}
+
+// nodePort is a node and the port of its app, a bearer is authorized once for each in a listing
+type nodePort struct {
+	nodeID string
+	port   int
+}
+
+// modelAllowed returns true if a node serving the app can be reached for a completion:
+// the policy allows one of the completions paths on the port of the app and the bearer is authorized for the node.
+// The results of the authorizations are kept in authorized.
+func (j *TransparentProxy) modelAllowed(r *http.Request, bearer string, appName string, noAuth bool, authorized map[nodePort]bool) bool {
+	// the completions of an app without a declared port can't be routed
+	port, err := j.appPort(appName)
+	if err != nil {
+		return false
+	}
+
+	nodeIDs := make([]string, 0)
+	for nodeID := range j.config.Store.GetAppPeers(appName) {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+
+	reachable := make(map[string]bool)
+	for _, interfaceURL := range openAICompletionsPaths {
+		for _, nodeID := range j.allowedNodes(nodeIDs, &EdgePath{Port: port, InterfaceURL: interfaceURL}, clientIdentity(r)) {
+			reachable[nodeID] = true
+		}
+	}
+
+	for _, nodeID := range nodeIDs {
+		if !reachable[nodeID] {
+			continue
+		}
+
+		if noAuth {
+			return true
+		}
+
+		key := nodePort{nodeID: nodeID, port: port}
+		ok, checked := authorized[key]
+		if !checked {
+			ok, _ = j.authBearer(r.Context(), bearer, nodeID, port)
+			authorized[key] = ok
+		}
+
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// openAIModelsHandler lists the models served by the app nodes which the client can reach
+func (j *TransparentProxy) openAIModelsHandler(noAuth bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		j.setCORSHeaders(w, r)
+		if IsPreflightRequest(r) {
+			return
+		}
+
+		if r.Method != http.MethodGet {
+			writeOpenAIError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method not allowed")
+
+			return
+		}
+
+		clientLimit, ok := j.checkClientRateLimit(w, r)
+		if !ok {
+			return
+		}
+
+		bearer := ""
+		if !noAuth {
+			if bearer, ok = j.checkOpenAIBearer(w, r); !ok {
+				return
+			}
+		}
+
+		appNames := make(map[string]bool)
+		for _, appPeer := range j.config.Store.ListAppPeers() {
+			if appPeer.AppName != "" {
+				appNames[appPeer.AppName] = true
+			}
+		}
+
+		// an app is checked once, several models can be mapped to it
+		allowed := make(map[string]bool)
+		authorized := make(map[nodePort]bool)
+		for appName := range appNames {
+			allowed[appName] = j.modelAllowed(r, bearer, appName, noAuth, authorized)
+		}
+
+		// a bearer authorized for none of the nodes is rejected
+		if !noAuth && len(authorized) > 0 && !anyAuthorized(authorized) {
+			IncrAuthFailure("relay", "invalid")
+			writeOpenAIError(w, http.StatusUnauthorized, "invalid_request_error", "invalid api key")
+
+			return
+		}
+		SetRequestPrincipal(r, requestPrincipal(r, bearer))
+
+		if !j.checkRateLimit(w, r, bearer, &EdgePath{InterfaceURL: strings.TrimPrefix(OpenAIModelsUrl, "/")}, clientLimit) {
+			return
+		}
+
+		models := make([]string, 0)
+		if len(j.config.OpenAI.Models) > 0 {
+			for model, appName := range j.config.OpenAI.Models {
+				if allowed[appName] {
+					models = append(models, model)
+				}
+			}
+		} else {
+			for appName := range appNames {
+				if allowed[appName] {
+					models = append(models, appName)
+				}
+			}
+		}
+		sort.Strings(models)
+
+		list := &openAIModelList{Object: "list", Data: make([]openAIModel, 0, len(models))}
+		for _, model := range models {
+			list.Data = append(list.Data, openAIModel{ID: model, Object: "model", OwnedBy: j.config.NetworkName})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(list)
+	}
+}
+
+func anyAuthorized(authorized map[nodePort]bool) bool {
+	for _, ok := range authorized {
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// usageRecorder reads the usage of the response while it is written to the client:
+// from the last SSE event with a usage for the streams, from the JSON body otherwise
+type usageRecorder struct {
+	http.ResponseWriter
+
+	started   bool
+	eventType bool
+	body      bytes.Buffer
+	last      *OpenAIUsage
+}
+
+func (u *usageRecorder) Write(b []byte) (int, error) {
+	if !u.started {
+		u.started = true
+		mediaType, _, _ := mime.ParseMediaType(u.Header().Get("Content-Type"))
+		u.eventType = strings.EqualFold(mediaType, "text/event-stream")
+	}
+
+	n, err := u.ResponseWriter.Write(b)
+
+	if u.eventType {
+		u.body.Write(b[:n])
+		u.parseEvents()
+	} else if u.body.Len() <= maxOpenAIUsageBody {
+		u.body.Write(b[:n])
+	}
+
+	return n, err
+}
+
+func (u *usageRecorder) Flush() {
+	if flusher, ok := u.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// parseEvents parses the complete data lines of the stream, the last incomplete line is kept
+func (u *usageRecorder) parseEvents() {
+	for {
+		line, err := u.body.ReadBytes('\n')
+		if err != nil {
+			// not a complete line yet, an event larger than the limit is skipped
+			rest := append([]byte(nil), line...)
+			u.body.Reset()
+			if len(rest) <= maxOpenAIUsageBody {
+				u.body.Write(rest)
+			}
+
+			return
+		}
+
+		data, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:"))
+		if !ok {
+			continue
+		}
+
+		if usage := parseUsage(bytes.TrimSpace(data)); usage != nil {
+			u.last = usage
+		}
+	}
+}
+
+func (u *usageRecorder) usage() *OpenAIUsage {
+	if u.eventType {
+		return u.last
+	}
+
+	if u.body.Len() > maxOpenAIUsageBody {
+		return nil
+	}
+
+	return parseUsage(u.body.Bytes())
+}
+
+func parseUsage(data []byte) *OpenAIUsage {
+	var response struct {
+		Usage *OpenAIUsage `json:"usage"`
+	}
+
+	if len(data) == 0 || data[0] != '{' || json.Unmarshal(data, &response) != nil {
+		return nil
+	}
+
+	return response.Usage
+}
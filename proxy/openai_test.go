@@ -0,0 +1,272 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/EdgeMatrixChain/edge-matrix-core/core/application"
+	"github.com/hashicorp/go-hclog"
+	"github.com/libp2p/go-libp2p"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openAIAppPorts are the ports of the apps of newOpenAIStore
+var openAIAppPorts = map[string]int{"llama": 8080, "mistral": 8081, "qwen": 8082}
+
+func newOpenAIStore() *testStore {
+	return &testStore{
+		appPeers: map[string]*application.AppPeer{
+			"node-a": {AppName: "llama"},
+			"node-b": {AppName: "mistral"},
+			"node-c": {AppName: "qwen"},
+		},
+		keys: map[string]bool{"key-1": true, "key-2": true},
+		scopes: map[string][]string{
+			"key-1": {"node-a", "node-b"},
+		},
+	}
+}
+
+func TestOpenAIRequestBearerBeforeBody(t *testing.T) {
+	j := newTestProxy(&Config{Store: newOpenAIStore(), AppPorts: openAIAppPorts, OpenAI: &OpenAIConfig{}})
+
+	var routed *openAIRequest
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		routed, _ = r.Context().Value("OpenAIRequest").(*openAIRequest)
+	})
+
+	testCases := []struct {
+		name   string
+		noAuth bool
+		bearer string
+		body   string
+		status int
+	}{
+		{"missing bearer, unknown model", false, "", `{"model":"gpt-x"}`, http.StatusUnauthorized},
+		{"missing bearer, malformed body", false, "", `{`, http.StatusUnauthorized},
+		// the bearer is authorized by the middleware for the node picked for the model
+		{"unchecked bearer", false, "bad", `{"model":"llama"}`, http.StatusOK},
+		{"valid bearer, unknown model", false, "key-1", `{"model":"gpt-x"}`, http.StatusNotFound},
+		{"valid bearer, no model", false, "key-1", `{}`, http.StatusBadRequest},
+		{"valid bearer", false, "key-1", `{"model":"llama"}`, http.StatusOK},
+		{"no auth", true, "", `{"model":"llama"}`, http.StatusOK},
+		{"no auth, unknown model", true, "", `{"model":"gpt-x"}`, http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			routed = nil
+
+			req := httptest.NewRequest(http.MethodPost, OpenAIChatCompletionsUrl, strings.NewReader(tc.body))
+			if tc.bearer != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.bearer)
+			}
+
+			w := httptest.NewRecorder()
+			j.openAIRequestHandler(next, tc.noAuth).ServeHTTP(w, req)
+
+			assert.Equal(t, tc.status, w.Code)
+			if tc.status == http.StatusOK {
+				require.NotNil(t, routed)
+				assert.Equal(t, "llama", routed.AppName)
+			} else {
+				assert.Nil(t, routed)
+			}
+		})
+	}
+}
+
+func getModels(t *testing.T, j *TransparentProxy, noAuth bool, bearer string) (int, []string) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, OpenAIModelsUrl, nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+
+	w := httptest.NewRecorder()
+	j.openAIModelsHandler(noAuth).ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		return w.Code, nil
+	}
+
+	list := &openAIModelList{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), list))
+
+	models := make([]string, 0, len(list.Data))
+	for _, model := range list.Data {
+		models = append(models, model.ID)
+	}
+
+	return w.Code, models
+}
+
+func TestOpenAIModelsFiltered(t *testing.T) {
+	j := newTestProxy(&Config{Store: newOpenAIStore(), AppPorts: openAIAppPorts, OpenAI: &OpenAIConfig{}})
+	j.policy = &PolicyEngine{policy: &Policy{Rules: []PolicyRule{
+		{Name: "no-mistral-chat", Action: PolicyDeny, NodeID: "node-b", Path: "/v1/chat/completions"},
+		{Name: "no-mistral", Action: PolicyDeny, NodeID: "node-b", Path: "/v1/completions"},
+	}}}
+
+	status, _ := getModels(t, j, false, "")
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	status, _ = getModels(t, j, false, "bad")
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	// key-1 can reach node-a and node-b, but node-b is denied by the policy
+	status, models := getModels(t, j, false, "key-1")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []string{"llama"}, models)
+
+	status, models = getModels(t, j, false, "key-2")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []string{"llama", "qwen"}, models)
+
+	// without auth, only the policy filters the models
+	_, models = getModels(t, j, true, "")
+	assert.Equal(t, []string{"llama", "qwen"}, models)
+}
+
+func TestOpenAIModelsOnePathAllowed(t *testing.T) {
+	j := newTestProxy(&Config{Store: newOpenAIStore(), AppPorts: openAIAppPorts, OpenAI: &OpenAIConfig{}})
+	j.policy = &PolicyEngine{policy: &Policy{Rules: []PolicyRule{
+		{Action: PolicyDeny, NodeID: "node-b", Path: "/v1/chat/completions"},
+	}}}
+
+	// mistral is still served by the legacy completions
+	_, models := getModels(t, j, false, "key-1")
+	assert.Equal(t, []string{"llama", "mistral"}, models)
+}
+
+func TestOpenAIModelsMapped(t *testing.T) {
+	j := newTestProxy(&Config{Store: newOpenAIStore(), AppPorts: openAIAppPorts, OpenAI: &OpenAIConfig{Models: map[string]string{
+		"llama-3-8b": "llama",
+		"qwen-2":     "qwen",
+		"gpt-x":      "missing",
+	}}})
+
+	_, models := getModels(t, j, false, "key-1")
+	assert.Equal(t, []string{"llama-3-8b"}, models)
+
+	_, models = getModels(t, j, false, "key-2")
+	assert.Equal(t, []string{"llama-3-8b", "qwen-2"}, models)
+}
+
+func TestOpenAIModelsRateLimited(t *testing.T) {
+	j := newTestProxy(&Config{Store: newOpenAIStore(), AppPorts: openAIAppPorts, OpenAI: &OpenAIConfig{}})
+
+	rateLimiter, err := NewRateLimiter(hclog.NewNullLogger(), &RateLimitConfig{Key: &RateLimit{Rate: 0.01, Burst: 1}})
+	require.NoError(t, err)
+	defer rateLimiter.Close()
+	j.rateLimiter = rateLimiter
+
+	status, _ := getModels(t, j, false, "key-1")
+	assert.Equal(t, http.StatusOK, status)
+
+	status, _ = getModels(t, j, false, "key-1")
+	assert.Equal(t, http.StatusTooManyRequests, status)
+
+	// the limit is per key
+	status, _ = getModels(t, j, false, "key-2")
+	assert.Equal(t, http.StatusOK, status)
+}
+
+// relayKeyStore holds relay keys which are mapped to an apiToken, so they are not valid on the edge side,
+// and records the nodes and ports the keys are authorized for
+type relayKeyStore struct {
+	*testStore
+	authorized []string
+}
+
+func (s *relayKeyStore) ValidateBearer(bearer string) bool {
+	return false
+}
+
+func (s *relayKeyStore) AuthBearer(bearer string, nodeId string, port int) (bool, string) {
+	s.authorized = append(s.authorized, fmt.Sprintf("%s:%d", nodeId, port))
+
+	ok, _ := s.testStore.AuthBearer(bearer, nodeId, port)
+	if !ok {
+		return false, ""
+	}
+
+	return true, "api-token-" + nodeId
+}
+
+func TestOpenAIRelayKeys(t *testing.T) {
+	relayHost, err := libp2p.New(libp2p.NoListenAddrs)
+	require.NoError(t, err)
+	defer relayHost.Close()
+
+	store := &relayKeyStore{testStore: newOpenAIStore()}
+	store.relayHost = relayHost
+	j := newTestProxy(&Config{Store: store, AppPorts: openAIAppPorts, OpenAI: &OpenAIConfig{}})
+
+	var (
+		routed        *EdgePath
+		authorization string
+	)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		routed, _ = r.Context().Value("EdgePath").(*EdgePath)
+		authorization = r.Header.Get("Authorization")
+	})
+	handler := j.openAIRequestHandler(j.bearerMiddlewareFactory(parseOpenAIPath)(next), false)
+
+	complete := func(bearer string, model string) int {
+		routed = nil
+
+		req := httptest.NewRequest(http.MethodPost, OpenAIChatCompletionsUrl, strings.NewReader(`{"model":"`+model+`"}`))
+		req.Header.Set("Authorization", "Bearer "+bearer)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		return w.Code
+	}
+
+	// the relay key is authorized for the node and the port of the app
+	require.Equal(t, http.StatusOK, complete("key-1", "llama"))
+	require.NotNil(t, routed)
+	assert.Equal(t, "node-a", routed.NodeID)
+	assert.Equal(t, 8080, routed.Port)
+	assert.Equal(t, "Bearer api-token-node-a", authorization)
+	assert.Equal(t, []string{"node-a:8080"}, store.authorized)
+
+	// key-1 is not scoped to node-c
+	assert.Equal(t, http.StatusUnauthorized, complete("key-1", "qwen"))
+	assert.Equal(t, http.StatusUnauthorized, complete("bad", "llama"))
+	assert.Nil(t, routed)
+
+	// the listing authorizes the key once for each node and port
+	store.authorized = nil
+
+	status, models := getModels(t, j, false, "key-1")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []string{"llama", "mistral"}, models)
+	assert.ElementsMatch(t, []string{"node-a:8080", "node-b:8081", "node-c:8082"}, store.authorized)
+
+	store.authorized = nil
+
+	status, _ = getModels(t, j, false, "bad")
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.ElementsMatch(t, []string{"node-a:8080", "node-b:8081", "node-c:8082"}, store.authorized)
+}
+
+func TestOpenAIModelsWithoutAppPort(t *testing.T) {
+	store := &relayKeyStore{testStore: newOpenAIStore()}
+	j := newTestProxy(&Config{Store: store, AppPorts: map[string]int{"llama": 8080}, OpenAI: &OpenAIConfig{}})
+
+	// the apps without a declared port can't be routed, they are not listed nor authorized
+	_, models := getModels(t, j, false, "key-2")
+	assert.Equal(t, []string{"llama"}, models)
+	assert.Equal(t, []string{"node-a:8080"}, store.authorized)
+}
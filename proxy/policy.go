@@ -0,0 +1,366 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"gopkg.in/yaml.v3"
+)
+
+type PolicyAction string
+
+const (
+	PolicyAllow PolicyAction = "allow"
+	PolicyDeny  PolicyAction = "deny"
+)
+
+// policyCheckInterval is the interval between two checks of the modification time of the policy file
+const policyCheckInterval = 5 * time.Second
+
+// PolicyRule matches the requests by NodeID, port, interface path and client certificate identity.
+// An empty field matches everything.
+type PolicyRule struct {
+	Name   string       `json:"name,omitempty" yaml:"name,omitempty"`
+	Action PolicyAction `json:"action" yaml:"action"`
+	// NodeID is a glob on the NodeID, e.g. 16Uiu2HAm*
+	NodeID string `json:"node_id,omitempty" yaml:"node_id,omitempty"`
+	// Ports is the list of edge ports, 0 is the default port of an app routed by name
+	Ports []int `json:"ports,omitempty" yaml:"ports,omitempty"`
+	// Path is a glob on the interface path, e.g. /v1/*, a trailing /** matches any sub path
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	// Identity is a glob on the identity of the client certificate, it never matches requests without one
+	Identity string `json:"identity,omitempty" yaml:"identity,omitempty"`
+}
+
+// Policy is a list of rules, the first matching rule wins
+type Policy struct {
+	// Default is the action taken when no rule matches, allow if empty
+	Default PolicyAction `json:"default,omitempty" yaml:"default,omitempty"`
+	Rules   []PolicyRule `json:"rules" yaml:"rules"`
+}
+
+// PolicyDecision is the result of the evaluation of a request
+type PolicyDecision struct {
+	Allowed bool
+	// Rule is the name (or the index) of the matching rule, empty if the default action was taken
+	Rule string
+}
+
+func (r *PolicyRule) matches(nodeID string, port int, interfaceURL string, identity string) bool {
+	if r.Identity != "" {
+		if ok, _ := path.Match(r.Identity, identity); !ok || identity == "" {
+			return false
+		}
+	}
+
+	if r.NodeID != "" {
+		if ok, _ := path.Match(r.NodeID, nodeID); !ok {
+			return false
+		}
+	}
+
+	if len(r.Ports) > 0 {
+		found := false
+		for _, p := range r.Ports {
+			if p == port {
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if r.Path != "" && !matchPathGlob(r.Path, interfaceURL) {
+		return false
+	}
+
+	return true
+}
+
+// matchPathGlob matches a path.Match pattern, a trailing /** matches the prefix and any sub path.
+// The path is cleaned first, so /v1//./admin matches /v1/admin, a path with a dot-dot segment never matches.
+func matchPathGlob(pattern string, name string) bool {
+	if HasDotDotSegment(name) {
+		return false
+	}
+
+	pattern = "/" + strings.TrimPrefix(pattern, "/")
+	name = path.Clean("/" + name)
+
+	if prefix, ok := strings.CutSuffix(pattern, "/**"); ok {
+		parts := strings.Split(name, "/")
+		for i := len(parts); i > 0; i-- {
+			if matched, _ := path.Match(prefix, strings.Join(parts[:i], "/")); matched {
+				return true
+			}
+		}
+
+		return prefix == ""
+	}
+
+	matched, _ := path.Match(pattern, name)
+
+	return matched
+}
+
+func (p *Policy) validate() error {
+	switch p.Default {
+	case "", PolicyAllow, PolicyDeny:
+	default:
+		return fmt.Errorf("invalid default action '%s'", p.Default)
+	}
+
+	for i, rule := range p.Rules {
+		if rule.Action != PolicyAllow && rule.Action != PolicyDeny {
+			return fmt.Errorf("rule %d: invalid action '%s'", i, rule.Action)
+		}
+
+		if _, err := path.Match(rule.NodeID, ""); err != nil {
+			return fmt.Errorf("rule %d: invalid node_id pattern: %w", i, err)
+		}
+
+		if _, err := path.Match(strings.TrimSuffix(rule.Path, "/**"), ""); err != nil {
+			return fmt.Errorf("rule %d: invalid path pattern: %w", i, err)
+		}
+
+		if _, err := path.Match(rule.Identity, ""); err != nil {
+			return fmt.Errorf("rule %d: invalid identity pattern: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// pathTraversalRule is the rule of the decision for the paths with a dot-dot segment
+const pathTraversalRule = "path-traversal"
+
+// Evaluate returns the decision of the first rule matching the request,
+// the paths with a dot-dot segment are always denied since they could leave the path of a rule
+func (p *Policy) Evaluate(nodeID string, port int, interfaceURL string, identity string) PolicyDecision {
+	if HasDotDotSegment(interfaceURL) {
+		return PolicyDecision{Allowed: false, Rule: pathTraversalRule}
+	}
+
+	for i, rule := range p.Rules {
+		if rule.matches(nodeID, port, interfaceURL, identity) {
+			name := rule.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i)
+			}
+
+			return PolicyDecision{Allowed: rule.Action == PolicyAllow, Rule: name}
+		}
+	}
+
+	return PolicyDecision{Allowed: p.Default != PolicyDeny}
+}
+
+// ReadPolicyFile reads a policy from a .json, .yaml or .yml file
+func ReadPolicyFile(filePath string) (*Policy, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var unmarshalFunc func([]byte, interface{}) error
+
+	switch {
+	case strings.HasSuffix(filePath, ".json"):
+		unmarshalFunc = json.Unmarshal
+	case strings.HasSuffix(filePath, ".yaml"), strings.HasSuffix(filePath, ".yml"):
+		unmarshalFunc = yaml.Unmarshal
+	default:
+		return nil, fmt.Errorf("suffix of %s is neither json, yaml nor yml", filePath)
+	}
+
+	policy := &Policy{}
+	if err := unmarshalFunc(data, policy); err != nil {
+		return nil, err
+	}
+
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// PolicyEngine holds the policy loaded from a file, the file is reloaded when it changes or on SIGHUP
+type PolicyEngine struct {
+	logger   hclog.Logger
+	filePath string
+
+	lock     sync.RWMutex
+	policy   *Policy
+	modTime  time.Time
+	loadedAt time.Time
+
+	closeCh chan struct{}
+}
+
+// NewPolicyEngine loads the policy file and starts watching it
+func NewPolicyEngine(logger hclog.Logger, filePath string) (*PolicyEngine, error) {
+	e := &PolicyEngine{
+		logger:   logger.Named("policy"),
+		filePath: filePath,
+		closeCh:  make(chan struct{}),
+	}
+
+	if err := e.Reload(); err != nil {
+		return nil, err
+	}
+
+	go e.watch()
+
+	return e, nil
+}
+
+// Reload reads the policy file again, the active policy is kept if the file is invalid
+func (e *PolicyEngine) Reload() error {
+	info, err := os.Stat(e.filePath)
+	if err != nil {
+		return err
+	}
+
+	policy, err := ReadPolicyFile(e.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to load policy %s: %w", e.filePath, err)
+	}
+
+	e.lock.Lock()
+	e.policy = policy
+	e.modTime = info.ModTime()
+	e.loadedAt = time.Now()
+	e.lock.Unlock()
+
+	e.logger.Info("policy loaded", "file", e.filePath, "rules", len(policy.Rules), "default", policy.Default)
+
+	return nil
+}
+
+func (e *PolicyEngine) watch() {
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	defer signal.Stop(hupCh)
+
+	ticker := time.NewTicker(policyCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-hupCh:
+			if err := e.Reload(); err != nil {
+				e.logger.Error("failed to reload policy", "err", err)
+			}
+		case <-ticker.C:
+			info, err := os.Stat(e.filePath)
+			if err != nil {
+				e.logger.Warn("failed to check policy file", "err", err)
+
+				continue
+			}
+
+			e.lock.RLock()
+			changed := !info.ModTime().Equal(e.modTime)
+			e.lock.RUnlock()
+
+			if changed {
+				if err := e.Reload(); err != nil {
+					e.logger.Error("failed to reload policy", "err", err)
+				}
+			}
+		case <-e.closeCh:
+			return
+		}
+	}
+}
+
+// Evaluate applies the active policy to the request
+func (e *PolicyEngine) Evaluate(nodeID string, port int, interfaceURL string, identity string) PolicyDecision {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	return e.policy.Evaluate(nodeID, port, interfaceURL, identity)
+}
+
+// Policy returns the active policy, its file and the time it was loaded
+func (e *PolicyEngine) Policy() (*Policy, string, time.Time) {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	return e.policy, e.filePath, e.loadedAt
+}
+
+func (e *PolicyEngine) Close() {
+	close(e.closeCh)
+}
+
+// PolicyDeniedResponse is the body of the 403 returned for a request denied by the policy
+type PolicyDeniedResponse struct {
+	Error        string `json:"error"`
+	Message      string `json:"message"`
+	NodeID       string `json:"node_id"`
+	Port         int    `json:"port"`
+	InterfaceURL string `json:"interface_url"`
+	Rule         string `json:"rule,omitempty"`
+}
+
+func writePolicyDenied(w http.ResponseWriter, pathInfo *EdgePath, decision PolicyDecision) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+
+	_ = json.NewEncoder(w).Encode(&PolicyDeniedResponse{
+		Error:        "forbidden",
+		Message:      "the node is denied by the proxy policy",
+		NodeID:       pathInfo.NodeID,
+		Port:         pathInfo.Port,
+		InterfaceURL: pathInfo.InterfaceURL,
+		Rule:         decision.Rule,
+	})
+}
+
+// checkPolicy returns true if the request to the node is allowed, otherwise the 403 is written
+func (j *TransparentProxy) checkPolicy(w http.ResponseWriter, r *http.Request, pathInfo *EdgePath) bool {
+	if j.policy == nil || pathInfo.NodeID == "" {
+		return true
+	}
+
+	decision := j.policy.Evaluate(pathInfo.NodeID, pathInfo.Port, pathInfo.InterfaceURL, clientIdentity(r))
+	if !decision.Allowed {
+		j.logger.Info("policy denied", "NodeID", pathInfo.NodeID, "Port", pathInfo.Port, "InterfaceURL", pathInfo.InterfaceURL, "rule", decision.Rule)
+		writePolicyDenied(w, pathInfo, decision)
+
+		return false
+	}
+
+	return true
+}
+
+// allowedNodes filters the nodes which can be reached for the request
+func (j *TransparentProxy) allowedNodes(nodeIDs []string, pathInfo *EdgePath, identity string) []string {
+	if j.policy == nil {
+		return nodeIDs
+	}
+
+	allowed := make([]string, 0, len(nodeIDs))
+	for _, nodeID := range nodeIDs {
+		if j.policy.Evaluate(nodeID, pathInfo.Port, pathInfo.InterfaceURL, identity).Allowed {
+			allowed = append(allowed, nodeID)
+		}
+	}
+
+	return allowed
+}
@@ -0,0 +1,169 @@
+package proxy
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchPathGlob(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{"/v1/*", "/v1/chat", true},
+		{"v1/*", "v1/chat", true},
+		{"/v1/*", "/v1/chat/completions", false},
+		{"/v1/**", "/v1", true},
+		{"/v1/**", "/v1/chat/completions", true},
+		{"/v1/**", "/v10/chat", false},
+		{"/**", "/anything/at/all", true},
+		{"/*/health", "/llm/health", true},
+		{"/admin", "/admin", true},
+		// the path is cleaned before matching
+		{"/admin/**", "/v1/..%2fadmin", false},
+		{"/admin/**", "//admin/users", true},
+		{"/admin/**", "/./admin/users", true},
+		{"/admin/*", "/admin/./users", true},
+		// a path with a dot-dot segment never matches
+		{"/v1/**", "/v1/../admin", false},
+		{"/v1/**", "/v1/%2e%2e/admin", false},
+		{"/v1/**", "/v1/%252e%252e/admin", false},
+		{"/**", "/..", false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, matchPathGlob(test.pattern, test.name), "%s %s", test.pattern, test.name)
+	}
+}
+
+func TestHasDotDotSegment(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"v1/chat", false},
+		{"v1/..chat/a..b", false},
+		{"v1/../admin", true},
+		{"..", true},
+		{"v1/%2e%2e/admin", true},
+		{"v1/%2E%2E/admin", true},
+		{"v1/%252e%252e/admin", true},
+		{"v1/..%2fadmin", true},
+		{"v1/%2e%2e%2fadmin", true},
+		{"v1\\..\\admin", true},
+		{"v1/%zz", false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, HasDotDotSegment(test.path), test.path)
+	}
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	policy := &Policy{
+		Default: PolicyDeny,
+		Rules: []PolicyRule{
+			{Name: "deny-admin", Action: PolicyDeny, Path: "/admin/**"},
+			{Name: "blocked-node", Action: PolicyDeny, NodeID: "16Uiu2HAmBad*"},
+			{Name: "partner", Action: PolicyAllow, Identity: "partner-*", Ports: []int{8080}},
+			{Name: "api", Action: PolicyAllow, Path: "/v1/**", Ports: []int{0, 9527}},
+		},
+	}
+	require.NoError(t, policy.validate())
+
+	tests := []struct {
+		name         string
+		nodeID       string
+		port         int
+		interfaceURL string
+		identity     string
+		allowed      bool
+		rule         string
+	}{
+		{"api", "16Uiu2HAmGood", 9527, "v1/chat", "", true, "api"},
+		{"app default port", "16Uiu2HAmGood", 0, "v1/chat", "", true, "api"},
+		{"other port", "16Uiu2HAmGood", 9528, "v1/chat", "", false, ""},
+		{"admin", "16Uiu2HAmGood", 9527, "admin/users", "", false, "deny-admin"},
+		{"blocked node", "16Uiu2HAmBadNode", 9527, "v1/chat", "", false, "blocked-node"},
+		{"partner", "16Uiu2HAmGood", 8080, "reports", "partner-acme", true, "partner"},
+		{"no identity", "16Uiu2HAmGood", 8080, "reports", "", false, ""},
+		{"unmatched", "16Uiu2HAmGood", 9527, "metrics", "", false, ""},
+		// the cleaned path is matched, so the deny rule applies
+		{"dot segment", "16Uiu2HAmGood", 9527, "./admin/users", "", false, "deny-admin"},
+		{"double slash", "16Uiu2HAmGood", 9527, "/admin//users", "", false, "deny-admin"},
+		// the traversals are denied before any rule
+		{"traversal", "16Uiu2HAmGood", 9527, "v1/../admin", "", false, pathTraversalRule},
+		{"encoded traversal", "16Uiu2HAmGood", 9527, "v1/%2e%2e/admin", "", false, pathTraversalRule},
+		{"double encoded traversal", "16Uiu2HAmGood", 9527, "v1/%252e%252e/admin", "", false, pathTraversalRule},
+	}
+
+	for _, test := range tests {
+		decision := policy.Evaluate(test.nodeID, test.port, test.interfaceURL, test.identity)
+		assert.Equal(t, test.allowed, decision.Allowed, test.name)
+		assert.Equal(t, test.rule, decision.Rule, test.name)
+	}
+
+	// the default action is allow when it is not set
+	assert.True(t, (&Policy{}).Evaluate("16Uiu2HAmGood", 9527, "v1/chat", "").Allowed)
+	assert.False(t, (&Policy{}).Evaluate("16Uiu2HAmGood", 9527, "v1/../admin", "").Allowed)
+}
+
+func TestPolicyValidate(t *testing.T) {
+	assert.Error(t, (&Policy{Default: "maybe"}).validate())
+	assert.Error(t, (&Policy{Rules: []PolicyRule{{Action: "drop"}}}).validate())
+	assert.Error(t, (&Policy{Rules: []PolicyRule{{Action: PolicyDeny, NodeID: "[a"}}}).validate())
+	assert.Error(t, (&Policy{Rules: []PolicyRule{{Action: PolicyDeny, Path: "/v1/[a/**"}}}).validate())
+}
+
+func TestReadPolicyFile(t *testing.T) {
+	dir := t.TempDir()
+
+	filePath := filepath.Join(dir, "policy.yaml")
+	require.NoError(t, os.WriteFile(filePath, []byte(`
+default: deny
+rules:
+  - name: api
+    action: allow
+    path: /v1/**
+`), 0600))
+
+	policy, err := ReadPolicyFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, PolicyDeny, policy.Default)
+	assert.True(t, policy.Evaluate("16Uiu2HAmGood", 9527, "v1/chat", "").Allowed)
+
+	_, err = ReadPolicyFile(filepath.Join(dir, "policy.txt"))
+	assert.Error(t, err)
+}
+
+func TestParseEdgePathTraversal(t *testing.T) {
+	tests := []struct {
+		path string
+		err  bool
+	}{
+		{"/edge/16Uiu2HAmGood/9527/v1/chat", false},
+		{"/edge/16Uiu2HAmGood/9527/v1/../admin", true},
+		{"/edge/16Uiu2HAmGood/9527/v1/%2e%2e/admin", true},
+		{"/edge/16Uiu2HAmGood/9527/v1/%252e%252e/admin", true},
+		{"/app/llm/v1/chat", false},
+		{"/app/llm/v1/%252e%252e/admin", true},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "http://relay"+test.path, nil)
+
+		pathInfo, err := ParseEdgePath(req)
+		if test.err {
+			assert.ErrorIs(t, err, ErrPathTraversal, test.path)
+		} else {
+			require.NoError(t, err, test.path)
+			assert.NotEmpty(t, pathInfo.InterfaceURL, test.path)
+		}
+	}
+}
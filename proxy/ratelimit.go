@@ -0,0 +1,506 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	RateLimitLimitHeader     = "X-RateLimit-Limit"
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	RateLimitResetHeader     = "X-RateLimit-Reset"
+)
+
+// quotaPrefix is the prefix of the quota counters in the db
+var quotaPrefix = []byte("quota/")
+
+// bucketSweepInterval is the interval between two removals of the idle token buckets
+const bucketSweepInterval = time.Minute
+
+// quotaFlushInterval is the interval between two writes of the changed quota counters to the db,
+// the requests don't wait for the disk, at most this interval of counts is lost on a crash
+const quotaFlushInterval = time.Second
+
+// RateLimit defines the token bucket and the quotas of one dimension, zero values are unlimited
+type RateLimit struct {
+	// Rate is the number of requests per second refilled in the bucket
+	Rate float64
+	// Burst is the size of the bucket, Rate is used if it is 0
+	Burst int
+	// DailyQuota is the number of requests per UTC day
+	DailyQuota uint64
+	// MonthlyQuota is the number of requests per UTC month
+	MonthlyQuota uint64
+}
+
+// RateLimitConfig defines the limits per API key, per client IP and per target NodeID
+type RateLimitConfig struct {
+	Key  *RateLimit
+	IP   *RateLimit
+	Node *RateLimit
+	// DBPath is the directory of the quota counters, counters are not persisted if empty
+	DBPath string
+}
+
+func (l *RateLimit) burst() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+
+	return math.Max(1, math.Ceil(l.Rate))
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimitResult holds the values of the X-RateLimit-* headers of the most restrictive limit
+type rateLimitResult struct {
+	allowed    bool
+	limit      uint64
+	remaining  uint64
+	reset      time.Duration
+	retryAfter time.Duration
+	reason     string
+	// refill is the time to refill one token, 0 for the quotas
+	refill time.Duration
+}
+
+// tighter returns true if r leaves less room than other
+func (r *rateLimitResult) tighter(other *rateLimitResult) bool {
+	if other == nil {
+		return true
+	}
+
+	return r.remaining < other.remaining
+}
+
+// RateLimiter applies token bucket rate limits and daily/monthly quotas
+type RateLimiter struct {
+	logger hclog.Logger
+	config *RateLimitConfig
+	db     *leveldb.DB
+
+	lock      sync.Mutex
+	buckets   map[string]*tokenBucket
+	quotas    map[string]uint64
+	lastSweep time.Time
+	// dirty holds the quota counters changed since the last flush
+	dirty map[string]struct{}
+
+	closeCh chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewRateLimiter opens the quota counters db and drops the counters of the past periods
+func NewRateLimiter(logger hclog.Logger, config *RateLimitConfig) (*RateLimiter, error) {
+	l := &RateLimiter{
+		logger:    logger.Named("ratelimit"),
+		config:    config,
+		buckets:   make(map[string]*tokenBucket),
+		quotas:    make(map[string]uint64),
+		lastSweep: time.Now(),
+		dirty:     make(map[string]struct{}),
+		closeCh:   make(chan struct{}),
+	}
+
+	if config.DBPath == "" {
+		return l, nil
+	}
+
+	db, err := leveldb.OpenFile(config.DBPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open rate limit db: %w", err)
+	}
+	l.db = db
+
+	if err := l.loadQuotas(time.Now().UTC()); err != nil {
+		db.Close()
+
+		return nil, err
+	}
+
+	l.wg.Add(1)
+	go l.runFlush()
+
+	return l, nil
+}
+
+// runFlush writes the changed quota counters to the db on every interval
+func (l *RateLimiter) runFlush() {
+	defer l.wg.Done()
+
+	ticker := time.NewTicker(quotaFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			l.flush()
+		case <-l.closeCh:
+			return
+		}
+	}
+}
+
+// flush writes the quota counters changed since the last flush in one batch, outside the lock of the requests
+func (l *RateLimiter) flush() {
+	if l.db == nil {
+		return
+	}
+
+	l.lock.Lock()
+	batch := new(leveldb.Batch)
+	for key := range l.dirty {
+		// the counters of the past periods are dropped from memory, they are deleted from the db on the next start
+		used, ok := l.quotas[key]
+		if !ok {
+			continue
+		}
+
+		value := make([]byte, 8)
+		binary.BigEndian.PutUint64(value, used)
+		batch.Put(append(append([]byte{}, quotaPrefix...), key...), value)
+	}
+	l.dirty = make(map[string]struct{})
+	l.lock.Unlock()
+
+	if batch.Len() == 0 {
+		return
+	}
+
+	if err := l.db.Write(batch, nil); err != nil {
+		l.logger.Error("failed to persist quotas", "err", err)
+	}
+}
+
+// loadQuotas reads the counters of the current periods and deletes the others
+func (l *RateLimiter) loadQuotas(now time.Time) error {
+	batch := new(leveldb.Batch)
+	current := []string{dayPeriod(now), monthPeriod(now)}
+
+	iter := l.db.NewIterator(util.BytesPrefix(quotaPrefix), nil)
+	for iter.Next() {
+		key := string(iter.Key()[len(quotaPrefix):])
+
+		isCurrent := false
+		for _, period := range current {
+			if strings.HasPrefix(key, period+"/") {
+				isCurrent = true
+
+				break
+			}
+		}
+
+		if !isCurrent || len(iter.Value()) != 8 {
+			batch.Delete(append([]byte{}, iter.Key()...))
+
+			continue
+		}
+
+		l.quotas[key] = binary.BigEndian.Uint64(iter.Value())
+	}
+	iter.Release()
+
+	if err := iter.Error(); err != nil {
+		return fmt.Errorf("failed to load quotas: %w", err)
+	}
+
+	l.logger.Info("quotas loaded", "counters", len(l.quotas), "expired", batch.Len())
+
+	return l.db.Write(batch, nil)
+}
+
+func dayPeriod(now time.Time) string {
+	return now.Format("d20060102")
+}
+
+func monthPeriod(now time.Time) string {
+	return now.Format("m200601")
+}
+
+// hashKey avoids keeping the API keys in memory dumps and on disk
+func hashKey(bearer string) string {
+	sum := sha256.Sum256([]byte(bearer))
+
+	return hex.EncodeToString(sum[:16])
+}
+
+// Allow checks all the limits of the request and consumes them only if none is exceeded
+func (l *RateLimiter) Allow(bearer string, clientIP string, nodeID string) *rateLimitResult {
+	return l.allowAt(time.Now(), bearer, clientIP, nodeID)
+}
+
+func (l *RateLimiter) allowAt(now time.Time, bearer string, clientIP string, nodeID string) *rateLimitResult {
+	type dimension struct {
+		name  string
+		id    string
+		limit *RateLimit
+	}
+
+	dimensions := make([]dimension, 0, 3)
+	if l.config.Key != nil && bearer != "" {
+		dimensions = append(dimensions, dimension{"key", hashKey(bearer), l.config.Key})
+	}
+	if l.config.IP != nil && clientIP != "" {
+		dimensions = append(dimensions, dimension{"ip", clientIP, l.config.IP})
+	}
+	if l.config.Node != nil && nodeID != "" {
+		dimensions = append(dimensions, dimension{"node", nodeID, l.config.Node})
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.sweepBuckets(now)
+
+	var tightest *rateLimitResult
+
+	// check all the limits first, a rejected request must not consume any of them
+	for _, d := range dimensions {
+		for _, result := range l.check(now, d.name, d.id, d.limit) {
+			if !result.allowed {
+				return result
+			}
+
+			if result.tighter(tightest) {
+				tightest = result
+			}
+		}
+	}
+
+	// the consumed quotas are written to the db by the next flush
+	for _, d := range dimensions {
+		l.consume(now, d.name, d.id, d.limit)
+	}
+
+	if tightest == nil {
+		return &rateLimitResult{allowed: true}
+	}
+
+	// the request consumed one unit of the tightest limit
+	if tightest.remaining > 0 {
+		tightest.remaining--
+	}
+	tightest.reset += tightest.refill
+
+	return tightest
+}
+
+// check returns the state of the token bucket and the quotas of one dimension
+func (l *RateLimiter) check(now time.Time, name string, id string, limit *RateLimit) []*rateLimitResult {
+	results := make([]*rateLimitResult, 0, 3)
+
+	if limit.Rate > 0 {
+		burst := limit.burst()
+		bucket := l.refill(now, name+"/"+id, limit)
+
+		result := &rateLimitResult{
+			allowed:   bucket.tokens >= 1,
+			limit:     uint64(burst),
+			remaining: uint64(math.Max(0, math.Floor(bucket.tokens))),
+			reset:     time.Duration((burst - bucket.tokens) / limit.Rate * float64(time.Second)),
+			reason:    fmt.Sprintf("%s rate limit exceeded", name),
+			refill:    time.Duration(float64(time.Second) / limit.Rate),
+		}
+		if !result.allowed {
+			result.retryAfter = time.Duration((1 - bucket.tokens) / limit.Rate * float64(time.Second))
+		}
+
+		results = append(results, result)
+	}
+
+	utcNow := now.UTC()
+	year, month, day := utcNow.Date()
+
+	if limit.DailyQuota > 0 {
+		nextDay := time.Date(year, month, day+1, 0, 0, 0, 0, time.UTC)
+		results = append(results, l.checkQuota(dayPeriod(utcNow)+"/"+name+"/"+id, limit.DailyQuota, nextDay.Sub(utcNow), name+" daily"))
+	}
+
+	if limit.MonthlyQuota > 0 {
+		nextMonth := time.Date(year, month+1, 1, 0, 0, 0, 0, time.UTC)
+		results = append(results, l.checkQuota(monthPeriod(utcNow)+"/"+name+"/"+id, limit.MonthlyQuota, nextMonth.Sub(utcNow), name+" monthly"))
+	}
+
+	return results
+}
+
+func (l *RateLimiter) checkQuota(key string, quota uint64, reset time.Duration, quotaName string) *rateLimitResult {
+	used := l.quotas[key]
+
+	result := &rateLimitResult{
+		allowed: used < quota,
+		limit:   quota,
+		reset:   reset,
+		reason:  fmt.Sprintf("%s quota exceeded", quotaName),
+	}
+
+	if result.allowed {
+		result.remaining = quota - used
+	} else {
+		result.retryAfter = reset
+	}
+
+	return result
+}
+
+func (l *RateLimiter) consume(now time.Time, name string, id string, limit *RateLimit) {
+	if limit.Rate > 0 {
+		l.buckets[name+"/"+id].tokens--
+	}
+
+	utcNow := now.UTC()
+	keys := make([]string, 0, 2)
+
+	if limit.DailyQuota > 0 {
+		keys = append(keys, dayPeriod(utcNow)+"/"+name+"/"+id)
+	}
+
+	if limit.MonthlyQuota > 0 {
+		keys = append(keys, monthPeriod(utcNow)+"/"+name+"/"+id)
+	}
+
+	for _, key := range keys {
+		l.quotas[key]++
+		l.dirty[key] = struct{}{}
+	}
+}
+
+func (l *RateLimiter) refill(now time.Time, key string, limit *RateLimit) *tokenBucket {
+	burst := limit.burst()
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: burst, last: now}
+		l.buckets[key] = bucket
+
+		return bucket
+	}
+
+	bucket.tokens = math.Min(burst, bucket.tokens+now.Sub(bucket.last).Seconds()*limit.Rate)
+	bucket.last = now
+
+	return bucket
+}
+
+// sweepBuckets removes the buckets which would be full by now, they hold no state
+func (l *RateLimiter) sweepBuckets(now time.Time) {
+	if now.Sub(l.lastSweep) < bucketSweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.last) >= bucketSweepInterval {
+			delete(l.buckets, key)
+		}
+	}
+
+	// the counters of the past periods are only kept in the db until the next restart
+	utcNow := now.UTC()
+	day, month := dayPeriod(utcNow)+"/", monthPeriod(utcNow)+"/"
+
+	for key := range l.quotas {
+		if !strings.HasPrefix(key, day) && !strings.HasPrefix(key, month) {
+			delete(l.quotas, key)
+		}
+	}
+}
+
+// Close writes the changed quota counters and closes the db
+func (l *RateLimiter) Close() {
+	close(l.closeCh)
+	l.wg.Wait()
+
+	if l.db != nil {
+		l.flush()
+
+		if err := l.db.Close(); err != nil {
+			l.logger.Error("failed to close rate limit db", "err", err)
+		}
+	}
+}
+
+// writeRateLimitHeaders sets the X-RateLimit-* headers and Retry-After if the request is rejected
+func writeRateLimitHeaders(w http.ResponseWriter, result *rateLimitResult) {
+	if result.limit == 0 {
+		return
+	}
+
+	w.Header().Set(RateLimitLimitHeader, strconv.FormatUint(result.limit, 10))
+	w.Header().Set(RateLimitRemainingHeader, strconv.FormatUint(result.remaining, 10))
+	w.Header().Set(RateLimitResetHeader, strconv.FormatInt(int64(math.Ceil(result.reset.Seconds())), 10))
+
+	if !result.allowed {
+		w.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(result.retryAfter.Seconds())), 10))
+	}
+}
+
+// clientIP returns the host of the remote address of the request
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// checkClientRateLimit applies the limits of the client IP before the request is authenticated,
+// so the requests with an invalid bearer are limited too. It returns false if the 429 is written.
+func (j *TransparentProxy) checkClientRateLimit(w http.ResponseWriter, r *http.Request) (*rateLimitResult, bool) {
+	if j.rateLimiter == nil {
+		return nil, true
+	}
+
+	result := j.rateLimiter.Allow("", clientIP(r), "")
+	writeRateLimitHeaders(w, result)
+
+	if !result.allowed {
+		j.logger.Info("rate limited", "client", clientIP(r), "reason", result.reason)
+		http.Error(w, result.reason, http.StatusTooManyRequests)
+
+		return nil, false
+	}
+
+	return result, true
+}
+
+// checkRateLimit applies the limits of the API key and of the target node once the request is authenticated,
+// the headers keep the client limit if it is tighter. It returns false if the 429 is written.
+func (j *TransparentProxy) checkRateLimit(w http.ResponseWriter, r *http.Request, bearer string, pathInfo *EdgePath, client *rateLimitResult) bool {
+	if j.rateLimiter == nil {
+		return true
+	}
+
+	result := j.rateLimiter.Allow(bearer, "", pathInfo.NodeID)
+
+	if !result.allowed {
+		writeRateLimitHeaders(w, result)
+		j.logger.Info("rate limited", "NodeID", pathInfo.NodeID, "client", clientIP(r), "reason", result.reason)
+		http.Error(w, result.reason, http.StatusTooManyRequests)
+
+		return false
+	}
+
+	if client == nil || client.limit == 0 || (result.limit > 0 && result.remaining < client.remaining) {
+		writeRateLimitHeaders(w, result)
+	}
+
+	return true
+}
@@ -0,0 +1,256 @@
+package proxy
+
+import (
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/libp2p/go-libp2p"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func newTestRateLimiter(t *testing.T, config *RateLimitConfig) *RateLimiter {
+	t.Helper()
+
+	limiter, err := NewRateLimiter(hclog.NewNullLogger(), config)
+	require.NoError(t, err)
+
+	return limiter
+}
+
+func TestRateLimiterTokenBucket(t *testing.T) {
+	limiter := newTestRateLimiter(t, &RateLimitConfig{Key: &RateLimit{Rate: 1, Burst: 2}})
+	defer limiter.Close()
+
+	now := time.Now()
+
+	result := limiter.allowAt(now, "key-1", "", "")
+	assert.True(t, result.allowed)
+	assert.Equal(t, uint64(2), result.limit)
+	assert.Equal(t, uint64(1), result.remaining)
+
+	assert.True(t, limiter.allowAt(now, "key-1", "", "").allowed)
+
+	result = limiter.allowAt(now, "key-1", "", "")
+	assert.False(t, result.allowed)
+	assert.Equal(t, time.Second, result.retryAfter)
+	assert.Equal(t, "key rate limit exceeded", result.reason)
+
+	// each key has its own bucket
+	assert.True(t, limiter.allowAt(now, "key-2", "", "").allowed)
+
+	// one token is refilled per second
+	assert.True(t, limiter.allowAt(now.Add(time.Second), "key-1", "", "").allowed)
+	assert.False(t, limiter.allowAt(now.Add(time.Second), "key-1", "", "").allowed)
+}
+
+func TestRateLimiterBurstDefaultsToRate(t *testing.T) {
+	limit := &RateLimit{Rate: 2.5}
+	assert.Equal(t, float64(3), limit.burst())
+
+	limit = &RateLimit{Rate: 0.1}
+	assert.Equal(t, float64(1), limit.burst())
+}
+
+func TestRateLimiterRejectionConsumesNothing(t *testing.T) {
+	limiter := newTestRateLimiter(t, &RateLimitConfig{
+		Key: &RateLimit{DailyQuota: 10},
+		IP:  &RateLimit{Rate: 1, Burst: 1},
+	})
+	defer limiter.Close()
+
+	now := time.Now()
+
+	assert.True(t, limiter.allowAt(now, "key-1", "10.0.0.1", "").allowed)
+
+	result := limiter.allowAt(now, "key-1", "10.0.0.1", "")
+	assert.False(t, result.allowed)
+	assert.Equal(t, "ip rate limit exceeded", result.reason)
+
+	// the rejected request didn't consume the quota of the key
+	assert.Equal(t, uint64(1), limiter.quotas[dayPeriod(now.UTC())+"/key/"+hashKey("key-1")])
+}
+
+func TestRateLimiterQuotas(t *testing.T) {
+	limiter := newTestRateLimiter(t, &RateLimitConfig{Node: &RateLimit{DailyQuota: 2, MonthlyQuota: 3}})
+	defer limiter.Close()
+
+	day := time.Date(2026, 3, 10, 23, 0, 0, 0, time.UTC)
+
+	result := limiter.allowAt(day, "", "", "node-1")
+	assert.True(t, result.allowed)
+	assert.Equal(t, uint64(2), result.limit)
+	assert.Equal(t, uint64(1), result.remaining)
+
+	assert.True(t, limiter.allowAt(day, "", "", "node-1").allowed)
+
+	result = limiter.allowAt(day, "", "", "node-1")
+	assert.False(t, result.allowed)
+	assert.Equal(t, "node daily quota exceeded", result.reason)
+	// the daily quota is reset at midnight UTC
+	assert.Equal(t, time.Hour, result.retryAfter)
+
+	// the next day, the monthly quota is the tightest
+	nextDay := day.Add(2 * time.Hour)
+	assert.True(t, limiter.allowAt(nextDay, "", "", "node-1").allowed)
+
+	result = limiter.allowAt(nextDay, "", "", "node-1")
+	assert.False(t, result.allowed)
+	assert.Equal(t, "node monthly quota exceeded", result.reason)
+}
+
+func TestRateLimiterUnlimited(t *testing.T) {
+	limiter := newTestRateLimiter(t, &RateLimitConfig{Key: &RateLimit{Rate: 1}})
+	defer limiter.Close()
+
+	// a request without a bearer has no key limit
+	result := limiter.allowAt(time.Now(), "", "10.0.0.1", "node-1")
+	assert.True(t, result.allowed)
+	assert.Equal(t, uint64(0), result.limit)
+}
+
+func TestRateLimiterSweepBuckets(t *testing.T) {
+	limiter := newTestRateLimiter(t, &RateLimitConfig{Key: &RateLimit{Rate: 1, DailyQuota: 5}})
+	defer limiter.Close()
+
+	now := time.Now()
+	limiter.allowAt(now, "key-1", "", "")
+	assert.Len(t, limiter.buckets, 1)
+
+	// the idle buckets and the counters of the past days are removed
+	later := now.Add(48 * time.Hour)
+	limiter.allowAt(later, "key-2", "", "")
+	assert.Len(t, limiter.buckets, 1)
+	assert.NotContains(t, limiter.quotas, dayPeriod(now.UTC())+"/key/"+hashKey("key-1"))
+}
+
+func TestRateLimiterPersistsQuotas(t *testing.T) {
+	dbPath := t.TempDir()
+	config := &RateLimitConfig{Key: &RateLimit{DailyQuota: 3}, DBPath: dbPath}
+
+	limiter := newTestRateLimiter(t, config)
+	now := time.Now()
+	assert.True(t, limiter.allowAt(now, "key-1", "", "").allowed)
+	assert.True(t, limiter.allowAt(now, "key-1", "", "").allowed)
+	// the counters are written on close
+	limiter.Close()
+
+	// the counters are loaded after a restart
+	limiter = newTestRateLimiter(t, config)
+	assert.True(t, limiter.allowAt(now, "key-1", "", "").allowed)
+	assert.False(t, limiter.allowAt(now, "key-1", "", "").allowed)
+	limiter.Close()
+}
+
+func TestRateLimiterFlush(t *testing.T) {
+	limiter := newTestRateLimiter(t, &RateLimitConfig{Key: &RateLimit{DailyQuota: 3}, DBPath: t.TempDir()})
+	defer limiter.Close()
+
+	now := time.Now()
+	key := append(append([]byte{}, quotaPrefix...), dayPeriod(now.UTC())+"/key/"+hashKey("key-1")...)
+
+	limiter.allowAt(now, "key-1", "", "")
+	limiter.allowAt(now, "key-1", "", "")
+	limiter.flush()
+
+	value, err := limiter.db.Get(key, nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), binary.BigEndian.Uint64(value))
+	assert.Empty(t, limiter.dirty)
+
+	// the raw key is never stored
+	iter := limiter.db.NewIterator(nil, nil)
+	for iter.Next() {
+		assert.NotContains(t, string(iter.Key()), "key-1")
+	}
+	iter.Release()
+}
+
+func TestRateLimiterDropsPastPeriods(t *testing.T) {
+	dbPath := t.TempDir()
+
+	db, err := leveldb.OpenFile(dbPath, nil)
+	require.NoError(t, err)
+
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, 7)
+	past := append(append([]byte{}, quotaPrefix...), "d20000101/key/abc"...)
+	require.NoError(t, db.Put(past, value, nil))
+	require.NoError(t, db.Close())
+
+	limiter := newTestRateLimiter(t, &RateLimitConfig{Key: &RateLimit{DailyQuota: 3}, DBPath: dbPath})
+	defer limiter.Close()
+
+	assert.Empty(t, limiter.quotas)
+	_, err = limiter.db.Get(past, nil)
+	assert.ErrorIs(t, err, leveldb.ErrNotFound)
+}
+
+func TestWriteRateLimitHeaders(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeRateLimitHeaders(w, &rateLimitResult{allowed: false, limit: 10, remaining: 0, reset: 1500 * time.Millisecond, retryAfter: 200 * time.Millisecond})
+
+	assert.Equal(t, "10", w.Header().Get(RateLimitLimitHeader))
+	assert.Equal(t, "0", w.Header().Get(RateLimitRemainingHeader))
+	assert.Equal(t, "2", w.Header().Get(RateLimitResetHeader))
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	// no header without a limit
+	w = httptest.NewRecorder()
+	writeRateLimitHeaders(w, &rateLimitResult{allowed: true})
+	assert.Empty(t, w.Header())
+}
+
+func TestRateLimitBeforeAuth(t *testing.T) {
+	relayHost, err := libp2p.New(libp2p.NoListenAddrs)
+	require.NoError(t, err)
+	defer relayHost.Close()
+
+	limiter := newTestRateLimiter(t, &RateLimitConfig{IP: &RateLimit{Rate: 0.001, Burst: 2}, Key: &RateLimit{Rate: 0.001, Burst: 1}})
+	defer limiter.Close()
+
+	j := newTestProxy(&Config{Store: &testStore{relayHost: relayHost, keys: map[string]bool{"good": true}}})
+	j.rateLimiter = limiter
+
+	handler := j.bearerMiddlewareFactory(ParseEdgePath)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func(remoteAddr string, bearer string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "http://relay/edge/16Uiu2HAmGood/9527/v1/chat", nil)
+		r.RemoteAddr = remoteAddr
+		r.Header.Set("Authorization", "Bearer "+bearer)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		return w
+	}
+
+	// the guesses of a client are limited by its IP bucket, before the bearer is checked
+	assert.Equal(t, http.StatusUnauthorized, serve("10.0.0.1:1234", "guess-1").Code)
+	assert.Equal(t, http.StatusUnauthorized, serve("10.0.0.1:1234", "guess-2").Code)
+
+	w := serve("10.0.0.1:1234", "guess-3")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "ip rate limit exceeded\n", w.Body.String())
+	assert.Equal(t, "2", w.Header().Get(RateLimitLimitHeader))
+
+	// the rejected bearers didn't consume a key bucket
+	for key := range limiter.buckets {
+		assert.NotContains(t, key, "key/")
+	}
+
+	// the key bucket is applied once the bearer is valid, the headers show the tightest limit
+	w = serve("10.0.0.2:1234", "good")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get(RateLimitLimitHeader))
+	assert.Equal(t, "0", w.Header().Get(RateLimitRemainingHeader))
+
+	w = serve("10.0.0.3:1234", "good")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "key rate limit exceeded\n", w.Body.String())
+}
@@ -0,0 +1,201 @@
+package proxy
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// AttemptsHeader is the response header holding the number of forward attempts
+const AttemptsHeader = "X-Proxy-Attempts"
+
+const (
+	DefaultRetryMax         = 2
+	DefaultRetryBackoff     = 200 * time.Millisecond
+	DefaultRetryBudget      = 5 * time.Second
+	DefaultRetryMaxBodySize = 1 << 20 // 1MB
+)
+
+// RetryConfig defines when a failed forward is retried
+type RetryConfig struct {
+	// MaxRetries is the number of retries after the first attempt, 0 disables retries
+	MaxRetries int
+	// Backoff is the delay before the first retry, doubled on each retry
+	Backoff time.Duration
+	// Budget is the maximum time spent on all the attempts of a request
+	Budget time.Duration
+	// MaxBodySize is the maximum size of a request body buffered for replay
+	MaxBodySize int64
+	// NonIdempotent allows to retry POST and PATCH requests without an Idempotency-Key header
+	NonIdempotent bool
+	// Failover allows to retry a request routed by app name on another node advertising the app,
+	// the requests addressed to a NodeID are only retried on that node
+	Failover bool
+}
+
+// DefaultRetryConfig returns the default retry config
+func DefaultRetryConfig() *RetryConfig {
+	return &RetryConfig{
+		MaxRetries:  DefaultRetryMax,
+		Backoff:     DefaultRetryBackoff,
+		Budget:      DefaultRetryBudget,
+		MaxBodySize: DefaultRetryMaxBodySize,
+	}
+}
+
+// isIdempotent returns true if the request can be sent more than once
+func (c *RetryConfig) isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	return c.NonIdempotent || req.Header.Get("Idempotency-Key") != ""
+}
+
+// retryState tracks the attempts of a single request
+type retryState struct {
+	config   *RetryConfig
+	start    time.Time
+	attempts int
+	// replayable is false when the body could not be buffered or the request is not idempotent
+	replayable bool
+	payload    []byte
+	tried      map[string]bool
+}
+
+// newRetryState buffers the body of the request if it may be replayed
+func newRetryState(config *RetryConfig, req *http.Request) (*retryState, error) {
+	state := &retryState{
+		config: config,
+		start:  time.Now(),
+		tried:  make(map[string]bool),
+	}
+
+	if config == nil || config.MaxRetries <= 0 || !config.isIdempotent(req) {
+		return state, nil
+	}
+
+	if req.Body == nil || req.Body == http.NoBody {
+		state.replayable = true
+
+		return state, nil
+	}
+
+	if req.ContentLength < 0 || req.ContentLength > config.MaxBodySize {
+		return state, nil
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(req.Body, config.MaxBodySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+
+	if int64(len(payload)) > config.MaxBodySize {
+		// the body is larger than announced, keep forwarding it once
+		req.Body = io.NopCloser(io.MultiReader(bytes.NewReader(payload), req.Body))
+
+		return state, nil
+	}
+
+	state.payload = payload
+	state.replayable = true
+
+	return state, nil
+}
+
+// body returns the request body for the next attempt
+func (s *retryState) body(req *http.Request) io.Reader {
+	if s.replayable && s.payload != nil {
+		return bytes.NewReader(s.payload)
+	}
+
+	return req.Body
+}
+
+// next returns the backoff delay before the next attempt, ok is false if the request can't be retried
+func (s *retryState) next() (delay time.Duration, ok bool) {
+	if !s.replayable || s.attempts > s.config.MaxRetries {
+		return 0, false
+	}
+
+	delay = s.config.Backoff << (s.attempts - 1)
+	if time.Since(s.start)+delay > s.config.Budget {
+		return 0, false
+	}
+
+	return delay, true
+}
+
+func (s *retryState) setAttemptsHeader(w http.ResponseWriter) {
+	w.Header().Set(AttemptsHeader, strconv.Itoa(s.attempts))
+}
+
+// failoverNode picks another node advertising the app of a request routed by app name,
+// it returns false if there is none left or the request is addressed to a NodeID
+func (j *TransparentProxy) failoverNode(req *http.Request, pathInfo *EdgePath, state *retryState) (string, bool) {
+	// the client chose the node, so the request is not sent to another one
+	appName := pathInfo.AppName
+	if appName == "" {
+		return "", false
+	}
+
+	candidates := make([]string, 0)
+	for nodeID := range j.config.Store.GetAppPeers(appName) {
+		if !state.tried[nodeID] {
+			candidates = append(candidates, nodeID)
+		}
+	}
+
+	nodeID, err := j.balancer.pick(appName, j.balancer.filterBusy(j.breakers.filterOpen(j.allowedNodes(candidates, pathInfo, clientIdentity(req)))))
+	if err != nil {
+		return "", false
+	}
+
+	return nodeID, true
+}
+
+// prepareRetry waits for the backoff delay and switches to another node if failover is enabled.
+// It returns false if the request must not be retried.
+func (j *TransparentProxy) prepareRetry(req *http.Request, pathInfo *EdgePath, state *retryState, nodeUnreachable bool) bool {
+	delay, ok := state.next()
+	if !ok {
+		return false
+	}
+
+	nodeID := pathInfo.NodeID
+	if state.config.Failover {
+		if failoverID, found := j.failoverNode(req, pathInfo, state); found {
+			nodeID = failoverID
+		}
+	}
+
+	// there is no point in retrying a node which can't be resolved
+	if nodeUnreachable && nodeID == pathInfo.NodeID {
+		return false
+	}
+
+	if nodeID != pathInfo.NodeID {
+		if bearer, ok := req.Context().Value("Bearer").(string); ok {
+			authorized, apiToken := j.authBearer(req.Context(), bearer, nodeID, pathInfo.Port)
+			if !authorized {
+				return false
+			}
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiToken))
+		}
+
+		j.logger.Info("handleRequest", "msg", "failover", "RequestID", RequestID(req), "from", pathInfo.NodeID, "to", nodeID)
+		pathInfo.NodeID = nodeID
+		SetRequestTarget(req, pathInfo.NodeID, pathInfo.Port)
+	}
+
+	select {
+	case <-time.After(delay):
+		return true
+	case <-req.Context().Done():
+		return false
+	}
+}
@@ -0,0 +1,297 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EdgeMatrixChain/edge-matrix-core/core/application"
+	"github.com/hashicorp/go-hclog"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testStore serves a fixed set of app peers and accepts the bearers of its keys
+type testStore struct {
+	appPeers map[string]*application.AppPeer
+	keys     map[string]bool
+	// scopes limits the nodes a key can reach, a key without a scope reaches all of them
+	scopes    map[string][]string
+	relayHost host.Host
+}
+
+func (s *testStore) GetRelayHost() host.Host   { return s.relayHost }
+func (s *testStore) GetNetworkHost() host.Host { return nil }
+
+func (s *testStore) GetAppPeer(id string) *application.AppPeer {
+	return s.appPeers[id]
+}
+
+func (s *testStore) GetAppPeers(appName string) map[string]*application.AppPeer {
+	appPeers := make(map[string]*application.AppPeer)
+	for nodeID, appPeer := range s.appPeers {
+		if appPeer.AppName == appName {
+			appPeers[nodeID] = appPeer
+		}
+	}
+
+	return appPeers
+}
+
+func (s *testStore) ListAppPeers() map[string]*application.AppPeer {
+	return s.appPeers
+}
+
+func (s *testStore) ResolveAppPeerID(label string) (string, bool) {
+	for nodeID := range s.appPeers {
+		if strings.EqualFold(nodeID, label) {
+			return nodeID, true
+		}
+	}
+
+	return "", false
+}
+
+func (s *testStore) ValidateBearer(bearer string) bool {
+	return s.keys[bearer]
+}
+
+func (s *testStore) AuthBearer(bearer string, nodeId string, port int) (bool, string) {
+	if scope, ok := s.scopes[bearer]; ok {
+		found := false
+		for _, id := range scope {
+			found = found || id == nodeId
+		}
+
+		if !found {
+			return false, ""
+		}
+	}
+
+	return s.keys[bearer], bearer
+}
+
+func newTestProxy(config *Config) *TransparentProxy {
+	if config.Store == nil {
+		config.Store = &testStore{}
+	}
+
+	return &TransparentProxy{
+		logger:   hclog.NewNullLogger(),
+		config:   config,
+		balancer: newAppBalancer(BalanceRoundRobin),
+	}
+}
+
+func TestRetryIsIdempotent(t *testing.T) {
+	tests := []struct {
+		method        string
+		key           string
+		nonIdempotent bool
+		expected      bool
+	}{
+		{http.MethodGet, "", false, true},
+		{http.MethodHead, "", false, true},
+		{http.MethodOptions, "", false, true},
+		{http.MethodPut, "", false, true},
+		{http.MethodDelete, "", false, true},
+		{http.MethodPost, "", false, false},
+		{http.MethodPatch, "", false, false},
+		{http.MethodPost, "order-1", false, true},
+		{http.MethodPatch, "", true, true},
+	}
+
+	for _, test := range tests {
+		config := &RetryConfig{NonIdempotent: test.nonIdempotent}
+		req := httptest.NewRequest(test.method, "/", nil)
+		if test.key != "" {
+			req.Header.Set("Idempotency-Key", test.key)
+		}
+
+		assert.Equal(t, test.expected, config.isIdempotent(req), "%s key=%q", test.method, test.key)
+	}
+}
+
+func TestRetryStateBuffersBody(t *testing.T) {
+	config := DefaultRetryConfig()
+
+	req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader("payload"))
+	state, err := newRetryState(config, req)
+	require.NoError(t, err)
+	assert.True(t, state.replayable)
+
+	// each attempt gets the whole body
+	for i := 0; i < 2; i++ {
+		body, err := io.ReadAll(state.body(req))
+		require.NoError(t, err)
+		assert.Equal(t, "payload", string(body))
+	}
+}
+
+func TestRetryStateNotReplayable(t *testing.T) {
+	config := DefaultRetryConfig()
+	config.MaxBodySize = 4
+
+	tests := []struct {
+		name    string
+		config  *RetryConfig
+		method  string
+		body    string
+		unsized bool
+	}{
+		{"retries disabled", &RetryConfig{}, http.MethodGet, "payload", false},
+		{"not idempotent", config, http.MethodPost, "payload", false},
+		{"body too large", config, http.MethodPut, "payload", false},
+		{"unknown length", config, http.MethodPut, "payload", true},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, "/", strings.NewReader(test.body))
+		if test.unsized {
+			req.ContentLength = -1
+		}
+
+		state, err := newRetryState(test.config, req)
+		require.NoError(t, err, test.name)
+		assert.False(t, state.replayable, test.name)
+
+		// the body is still forwarded once
+		body, err := io.ReadAll(state.body(req))
+		require.NoError(t, err, test.name)
+		assert.Equal(t, test.body, string(body), test.name)
+
+		_, ok := state.next()
+		assert.False(t, ok, test.name)
+	}
+}
+
+func TestRetryStateBodyLargerThanAnnounced(t *testing.T) {
+	config := DefaultRetryConfig()
+	config.MaxBodySize = 4
+
+	req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader("payload"))
+	req.ContentLength = 2
+
+	state, err := newRetryState(config, req)
+	require.NoError(t, err)
+	assert.False(t, state.replayable)
+
+	body, err := io.ReadAll(state.body(req))
+	require.NoError(t, err)
+	assert.Equal(t, "payload", string(body))
+}
+
+func TestRetryStateBackoff(t *testing.T) {
+	config := &RetryConfig{MaxRetries: 3, Backoff: 100 * time.Millisecond, Budget: time.Hour, MaxBodySize: 1}
+
+	state, err := newRetryState(config, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.NoError(t, err)
+
+	delays := make([]time.Duration, 0)
+	for {
+		state.attempts++
+		delay, ok := state.next()
+		if !ok {
+			break
+		}
+		delays = append(delays, delay)
+	}
+
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond}, delays)
+	assert.Equal(t, 4, state.attempts)
+}
+
+func TestRetryStateBudget(t *testing.T) {
+	config := &RetryConfig{MaxRetries: 5, Backoff: time.Second, Budget: 1500 * time.Millisecond}
+
+	state, err := newRetryState(config, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.NoError(t, err)
+
+	state.attempts = 1
+	_, ok := state.next()
+	assert.True(t, ok)
+
+	// the next delay would exceed the budget
+	state.attempts = 2
+	_, ok = state.next()
+	assert.False(t, ok)
+}
+
+func TestRetryFailoverNode(t *testing.T) {
+	j := newTestProxy(&Config{Store: &testStore{appPeers: map[string]*application.AppPeer{
+		"a": {AppName: "llm"},
+		"b": {AppName: "llm"},
+		"c": {AppName: "sd"},
+	}}})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	state, err := newRetryState(DefaultRetryConfig(), req)
+	require.NoError(t, err)
+
+	// routed by app name, the untried node of the app is picked
+	state.tried["a"] = true
+	nodeID, ok := j.failoverNode(req, &EdgePath{AppName: "llm", NodeID: "a"}, state)
+	assert.True(t, ok)
+	assert.Equal(t, "b", nodeID)
+
+	// no node is left
+	state.tried["b"] = true
+	_, ok = j.failoverNode(req, &EdgePath{AppName: "llm", NodeID: "b"}, state)
+	assert.False(t, ok)
+
+	// addressed to a NodeID, the request never leaves the node
+	state.tried = map[string]bool{"a": true}
+	_, ok = j.failoverNode(req, &EdgePath{NodeID: "a"}, state)
+	assert.False(t, ok)
+}
+
+func TestRetryFailoverSkipsOpenBreakers(t *testing.T) {
+	j := newTestProxy(&Config{Store: &testStore{appPeers: map[string]*application.AppPeer{
+		"a": {AppName: "llm"},
+		"b": {AppName: "llm"},
+		"c": {AppName: "llm"},
+	}}})
+	j.breakers = NewBreakers(&BreakerConfig{ConsecutiveFailures: 1, Window: time.Minute, OpenDuration: time.Minute})
+	j.breakers.record("b", false)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	state, err := newRetryState(DefaultRetryConfig(), req)
+	require.NoError(t, err)
+	state.tried["a"] = true
+
+	nodeID, ok := j.failoverNode(req, &EdgePath{AppName: "llm", NodeID: "a"}, state)
+	assert.True(t, ok)
+	assert.Equal(t, "c", nodeID)
+}
+
+func TestRetryPrepareRetryKeepsNodeID(t *testing.T) {
+	config := DefaultRetryConfig()
+	config.Failover = true
+	config.Backoff = time.Millisecond
+
+	j := newTestProxy(&Config{Retry: config, Store: &testStore{appPeers: map[string]*application.AppPeer{
+		"a": {AppName: "llm"},
+		"b": {AppName: "llm"},
+	}}})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	state, err := newRetryState(config, req)
+	require.NoError(t, err)
+	state.attempts = 1
+	state.tried["a"] = true
+
+	pathInfo := &EdgePath{NodeID: "a"}
+	assert.True(t, j.prepareRetry(req, pathInfo, state, false))
+	assert.Equal(t, "a", pathInfo.NodeID)
+
+	// an unreachable node addressed by NodeID is not retried
+	assert.False(t, j.prepareRetry(req, pathInfo, state, true))
+
+	pathInfo = &EdgePath{AppName: "llm", NodeID: "a"}
+	assert.True(t, j.prepareRetry(req, pathInfo, state, true))
+	assert.Equal(t, "b", pathInfo.NodeID)
+}
@@ -0,0 +1,241 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const (
+	// EdgeNodeHeader is the NodeID of the edge node which served the response
+	EdgeNodeHeader = "X-Edge-Node"
+	// EdgeSignatureHeader is the signature of the response by the edge node, a header or a trailer
+	EdgeSignatureHeader = "X-Edge-Signature"
+	// EdgeVerifiedHeader is set by the relay to true if the signature matches the key of the node
+	EdgeVerifiedHeader = "X-Edge-Verified"
+)
+
+// maxHeaderSignedBody is the largest body signed in the headers, larger and streamed bodies are signed in the trailers
+const maxHeaderSignedBody = 1 << 20
+
+// responseMessage is the message signed by the edge node, it binds the body to the node, the request and the status
+func responseMessage(nodeID string, requestID string, status int, bodyHash []byte) []byte {
+	return []byte(fmt.Sprintf("edge-response/1\n%s\n%s\n%d\n%x", nodeID, requestID, status, bodyHash))
+}
+
+// ResponseSigner signs the responses forwarded by the edge node with the key of its libp2p host
+type ResponseSigner struct {
+	key    crypto.PrivKey
+	nodeID string
+}
+
+func NewResponseSigner(h host.Host) (*ResponseSigner, error) {
+	key := h.Peerstore().PrivKey(h.ID())
+	if key == nil {
+		return nil, errors.New("the private key of the host is unknown")
+	}
+
+	return &ResponseSigner{key: key, nodeID: h.ID().String()}, nil
+}
+
+func (s *ResponseSigner) sign(requestID string, status int, bodyHash []byte) (string, error) {
+	signature, err := s.key.Sign(responseMessage(s.nodeID, requestID, status, bodyHash))
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// VerifyResponse checks the signature of a response against the public key embedded in the NodeID
+func VerifyResponse(nodeID string, requestID string, status int, bodyHash []byte, signature string) bool {
+	id, err := peer.Decode(nodeID)
+	if err != nil {
+		return false
+	}
+
+	pubKey, err := id.ExtractPublicKey()
+	if err != nil {
+		return false
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	ok, err := pubKey.Verify(responseMessage(nodeID, requestID, status, bodyHash), sig)
+
+	return err == nil && ok
+}
+
+// canSignInHeaders returns true if the body is small enough to be read before responding
+func canSignInHeaders(resp *http.Response, contentTypes []string) bool {
+	return !IsStreamingResponse(resp, contentTypes) && resp.ContentLength >= 0 && resp.ContentLength <= maxHeaderSignedBody
+}
+
+// copyBody writes the body to the client, it is streamed if the response is a stream
+func copyBody(w http.ResponseWriter, resp *http.Response, body io.Reader, contentTypes []string, hop string) error {
+	if IsStreamingResponse(resp, contentTypes) {
+		defer TrackStream(hop)()
+
+		return StreamBody(w, body)
+	}
+
+	_, err := io.Copy(w, body)
+
+	return err
+}
+
+// ForwardResponse writes the response of the webapp with its signature.
+// Small bodies are read first and signed in the headers, the others are signed in the trailers.
+// The response is forwarded unsigned if the signer is nil.
+func (s *ResponseSigner) ForwardResponse(w http.ResponseWriter, r *http.Request, resp *http.Response, contentTypes []string) error {
+	// the provenance headers can't be set by the webapp
+	CopyHeader(w.Header(), resp.Header, RequestIDHeader, EdgeNodeHeader, EdgeSignatureHeader, EdgeVerifiedHeader)
+
+	hasBody := ResponseHasBody(r, resp.StatusCode)
+
+	if s == nil {
+		AnnounceTrailers(w, resp)
+		w.WriteHeader(resp.StatusCode)
+		defer CopyTrailers(w, resp)
+
+		if !hasBody {
+			return nil
+		}
+
+		return copyBody(w, resp, resp.Body, contentTypes, "edge")
+	}
+
+	requestID := RequestID(r)
+	w.Header().Set(EdgeNodeHeader, s.nodeID)
+
+	if !hasBody || canSignInHeaders(resp, contentTypes) {
+		var body []byte
+		if hasBody {
+			var err error
+			if body, err = io.ReadAll(resp.Body); err != nil {
+				http.Error(w, "Failed to read the response of the target server", http.StatusBadGateway)
+
+				return err
+			}
+		}
+
+		bodyHash := sha256.Sum256(body)
+		signature, err := s.sign(requestID, resp.StatusCode, bodyHash[:])
+		if err != nil {
+			return err
+		}
+		w.Header().Set(EdgeSignatureHeader, signature)
+
+		AnnounceTrailers(w, resp)
+		w.WriteHeader(resp.StatusCode)
+		defer CopyTrailers(w, resp)
+
+		_, err = w.Write(body)
+
+		return err
+	}
+
+	// the trailers require a chunked response
+	w.Header().Del("Content-Length")
+	w.Header().Add("Trailer", EdgeSignatureHeader)
+	AnnounceTrailers(w, resp)
+	w.WriteHeader(resp.StatusCode)
+
+	hasher := sha256.New()
+	if err := copyBody(w, resp, io.TeeReader(resp.Body, hasher), contentTypes, "edge"); err != nil {
+		return err
+	}
+
+	CopyTrailers(w, resp)
+
+	signature, err := s.sign(requestID, resp.StatusCode, hasher.Sum(nil))
+	if err != nil {
+		return err
+	}
+	w.Header().Set(EdgeSignatureHeader, signature)
+
+	return nil
+}
+
+// writeVerifiedResponse writes the response of the edge node with the provenance headers.
+// The signature is checked before responding if it's in the headers, otherwise the signature
+// and the result of the check are sent in the trailers.
+func (j *TransparentProxy) writeVerifiedResponse(w http.ResponseWriter, req *http.Request, resp *http.Response, nodeID string) error {
+	CopyHeader(w.Header(), resp.Header, "Access-Control-Allow-Origin", RequestIDHeader, EdgeNodeHeader, EdgeSignatureHeader, EdgeVerifiedHeader)
+	w.Header().Set(EdgeNodeHeader, nodeID)
+
+	hasBody := ResponseHasBody(req, resp.StatusCode)
+	requestID := RequestID(req)
+
+	if signature := resp.Header.Get(EdgeSignatureHeader); signature != "" {
+		var body []byte
+		if hasBody {
+			var err error
+			// a larger body was not signed in the headers, it is forwarded but not verified
+			if body, err = io.ReadAll(io.LimitReader(resp.Body, maxHeaderSignedBody+1)); err != nil {
+				http.Error(w, "Failed to read the response of the edge node", http.StatusBadGateway)
+
+				return err
+			}
+		}
+
+		bodyHash := sha256.Sum256(body)
+		verified := len(body) <= maxHeaderSignedBody && VerifyResponse(nodeID, requestID, resp.StatusCode, bodyHash[:], signature)
+		w.Header().Set(EdgeSignatureHeader, signature)
+		w.Header().Set(EdgeVerifiedHeader, strconv.FormatBool(verified))
+
+		AnnounceTrailers(w, resp)
+		w.WriteHeader(resp.StatusCode)
+		defer CopyTrailers(w, resp)
+
+		if _, err := w.Write(body); err != nil {
+			return err
+		}
+
+		_, err := io.Copy(w, resp.Body)
+
+		return err
+	}
+
+	if _, signed := resp.Trailer[http.CanonicalHeaderKey(EdgeSignatureHeader)]; !signed || !hasBody {
+		w.Header().Set(EdgeVerifiedHeader, "false")
+		AnnounceTrailers(w, resp)
+		w.WriteHeader(resp.StatusCode)
+		defer CopyTrailers(w, resp)
+
+		if !hasBody {
+			return nil
+		}
+
+		return copyBody(w, resp, resp.Body, j.config.StreamContentTypes, "relay")
+	}
+
+	w.Header().Del("Content-Length")
+	w.Header().Add("Trailer", EdgeVerifiedHeader)
+	AnnounceTrailers(w, resp)
+	w.WriteHeader(resp.StatusCode)
+
+	hasher := sha256.New()
+	copyErr := copyBody(w, resp, io.TeeReader(resp.Body, hasher), j.config.StreamContentTypes, "relay")
+
+	signature := resp.Trailer.Get(EdgeSignatureHeader)
+	resp.Trailer.Del(EdgeSignatureHeader)
+	CopyTrailers(w, resp)
+
+	w.Header().Set(EdgeSignatureHeader, signature)
+	verified := copyErr == nil && signature != "" && VerifyResponse(nodeID, requestID, resp.StatusCode, hasher.Sum(nil), signature)
+	w.Header().Set(EdgeVerifiedHeader, strconv.FormatBool(verified))
+
+	return copyErr
+}
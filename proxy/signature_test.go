@@ -0,0 +1,245 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSigner(t *testing.T) *ResponseSigner {
+	t.Helper()
+
+	key, _, err := crypto.GenerateSecp256k1Key(rand.Reader)
+	require.NoError(t, err)
+
+	id, err := peer.IDFromPrivateKey(key)
+	require.NoError(t, err)
+
+	return &ResponseSigner{key: key, nodeID: id.String()}
+}
+
+// tamperWriter alters the body between the edge node and the relay
+type tamperWriter struct {
+	http.ResponseWriter
+}
+
+func (w *tamperWriter) Write(p []byte) (int, error) {
+	if _, err := w.ResponseWriter.Write(bytes.ReplaceAll(p, []byte("hello"), []byte("HELLO"))); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+func (w *tamperWriter) Flush() {
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+type signedRoundTrip struct {
+	signer *ResponseSigner
+	// nodeID is the NodeID the relay verifies against
+	nodeID string
+	tamper bool
+	// relayRequestID replaces the request id of the relay if set
+	relayRequestID string
+}
+
+// run serves the webapp handler through an edge node signing the responses and a relay verifying them,
+// and returns the response of the relay with its body read
+func (rt *signedRoundTrip) run(t *testing.T, method string, webapp http.HandlerFunc) (*http.Response, string) {
+	t.Helper()
+
+	webappServer := httptest.NewServer(webapp)
+	defer webappServer.Close()
+
+	edgeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequest(r.Method, webappServer.URL+r.URL.Path, nil)
+
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer resp.Body.Close()
+
+		if rt.tamper {
+			w = &tamperWriter{ResponseWriter: w}
+		}
+
+		assert.NoError(t, rt.signer.ForwardResponse(w, r, resp, DefaultStreamContentTypes))
+	}))
+	defer edgeServer.Close()
+
+	j := newTestProxy(&Config{StreamContentTypes: DefaultStreamContentTypes})
+	relayServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequest(r.Method, edgeServer.URL+r.URL.Path, nil)
+		req.Header.Set(RequestIDHeader, r.Header.Get(RequestIDHeader))
+
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer resp.Body.Close()
+
+		if rt.relayRequestID != "" {
+			r.Header.Set(RequestIDHeader, rt.relayRequestID)
+		}
+
+		assert.NoError(t, j.writeVerifiedResponse(w, r, resp, rt.nodeID))
+	}))
+	defer relayServer.Close()
+
+	req, err := http.NewRequest(method, relayServer.URL+"/api", nil)
+	require.NoError(t, err)
+	req.Header.Set(RequestIDHeader, "req-1")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp, string(body)
+}
+
+func smallHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(`{"message":"hello"}`))
+}
+
+var largeBody = strings.Repeat("hello world ", maxHeaderSignedBody/8)
+
+func largeHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	_, _ = w.Write([]byte(largeBody))
+}
+
+func streamHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/event-stream")
+
+	for i := 0; i < 3; i++ {
+		_, _ = w.Write([]byte("data: hello\n\n"))
+		w.(http.Flusher).Flush()
+	}
+}
+
+func TestSignatureRoundTrip(t *testing.T) {
+	signer := newTestSigner(t)
+
+	testCases := []struct {
+		name    string
+		method  string
+		handler http.HandlerFunc
+		body    string
+		trailer bool
+	}{
+		{"header", http.MethodGet, smallHandler, `{"message":"hello"}`, false},
+		{"no body", http.MethodHead, smallHandler, "", false},
+		{"large body in the trailers", http.MethodGet, largeHandler, largeBody, true},
+		{"stream in the trailers", http.MethodGet, streamHandler, strings.Repeat("data: hello\n\n", 3), true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := &signedRoundTrip{signer: signer, nodeID: signer.nodeID}
+			resp, body := rt.run(t, tc.method, tc.handler)
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, tc.body, body)
+			assert.Equal(t, signer.nodeID, resp.Header.Get(EdgeNodeHeader))
+
+			values := resp.Header
+			if tc.trailer {
+				values = resp.Trailer
+				assert.Empty(t, resp.Header.Get(EdgeVerifiedHeader))
+			}
+
+			assert.Equal(t, "true", values.Get(EdgeVerifiedHeader))
+			assert.NotEmpty(t, values.Get(EdgeSignatureHeader))
+		})
+	}
+}
+
+func TestSignatureRejected(t *testing.T) {
+	signer := newTestSigner(t)
+	other := newTestSigner(t)
+
+	testCases := []struct {
+		name    string
+		rt      *signedRoundTrip
+		handler http.HandlerFunc
+		trailer bool
+	}{
+		{"tampered header-signed body", &signedRoundTrip{signer: signer, nodeID: signer.nodeID, tamper: true}, smallHandler, false},
+		{"tampered trailer-signed body", &signedRoundTrip{signer: signer, nodeID: signer.nodeID, tamper: true}, largeHandler, true},
+		{"tampered stream", &signedRoundTrip{signer: signer, nodeID: signer.nodeID, tamper: true}, streamHandler, true},
+		{"wrong NodeID in the headers", &signedRoundTrip{signer: signer, nodeID: other.nodeID}, smallHandler, false},
+		{"wrong NodeID in the trailers", &signedRoundTrip{signer: signer, nodeID: other.nodeID}, largeHandler, true},
+		{"another request", &signedRoundTrip{signer: signer, nodeID: signer.nodeID, relayRequestID: "req-2"}, smallHandler, false},
+		{"unsigned", &signedRoundTrip{nodeID: signer.nodeID}, smallHandler, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, _ := tc.rt.run(t, http.MethodGet, tc.handler)
+
+			values := resp.Header
+			if tc.trailer {
+				values = resp.Trailer
+			}
+
+			assert.Equal(t, "false", values.Get(EdgeVerifiedHeader))
+			// the relay reports the NodeID it routed to, not the one claimed by the response
+			assert.Equal(t, tc.rt.nodeID, resp.Header.Get(EdgeNodeHeader))
+		})
+	}
+}
+
+func TestSignatureWebappHeadersDropped(t *testing.T) {
+	signer := newTestSigner(t)
+
+	rt := &signedRoundTrip{signer: signer, nodeID: signer.nodeID}
+	resp, _ := rt.run(t, http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+		// the webapp can't forge the provenance headers
+		w.Header().Set(EdgeVerifiedHeader, "true")
+		w.Header().Set(EdgeNodeHeader, "forged")
+		w.Header().Set(EdgeSignatureHeader, "forged")
+		w.Header().Set("Content-Length", "0")
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, signer.nodeID, resp.Header.Get(EdgeNodeHeader))
+	assert.NotEqual(t, "forged", resp.Header.Get(EdgeSignatureHeader))
+	assert.Equal(t, "true", resp.Header.Get(EdgeVerifiedHeader))
+}
+
+func TestVerifyResponse(t *testing.T) {
+	signer := newTestSigner(t)
+	bodyHash := sha256.Sum256([]byte("hello"))
+
+	signature, err := signer.sign("req-1", http.StatusOK, bodyHash[:])
+	require.NoError(t, err)
+
+	assert.True(t, VerifyResponse(signer.nodeID, "req-1", http.StatusOK, bodyHash[:], signature))
+
+	// the signature binds the status, the request and the body
+	assert.False(t, VerifyResponse(signer.nodeID, "req-1", http.StatusCreated, bodyHash[:], signature))
+	assert.False(t, VerifyResponse(signer.nodeID, "req-2", http.StatusOK, bodyHash[:], signature))
+
+	otherHash := sha256.Sum256([]byte("HELLO"))
+	assert.False(t, VerifyResponse(signer.nodeID, "req-1", http.StatusOK, otherHash[:], signature))
+
+	assert.False(t, VerifyResponse("not-a-node-id", "req-1", http.StatusOK, bodyHash[:], signature))
+	assert.False(t, VerifyResponse(signer.nodeID, "req-1", http.StatusOK, bodyHash[:], "not base64!"))
+}
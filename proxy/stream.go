@@ -0,0 +1,70 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// streamBufferSize is the size of the reads of a streamed body, each read is flushed to the client
+const streamBufferSize = 32 * 1024
+
+// DefaultStreamContentTypes are the media types streamed to the client as they are read
+var DefaultStreamContentTypes = []string{
+	"text/event-stream",
+	"application/x-ndjson",
+	"application/jsonl",
+	"application/stream+json",
+}
+
+// IsStreamingResponse returns true if the body of the response must be streamed:
+// its media type is one of contentTypes, whatever its parameters, or its length is unknown (chunked)
+func IsStreamingResponse(resp *http.Response, contentTypes []string) bool {
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+		for _, contentType := range contentTypes {
+			if strings.EqualFold(mediaType, contentType) {
+				return true
+			}
+		}
+	}
+
+	for _, encoding := range resp.TransferEncoding {
+		if strings.EqualFold(encoding, "chunked") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// StreamBody copies the body to the client and flushes after every read.
+// The bytes are forwarded as read, so any binary framing is kept.
+func StreamBody(w http.ResponseWriter, body io.Reader) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return errors.New("response writer does not support flushing")
+	}
+
+	buf := make([]byte, streamBufferSize)
+	for {
+		n, readErr := body.Read(buf)
+		if n > 0 {
+			if _, err := w.Write(buf[:n]); err != nil {
+				return fmt.Errorf("failed to write to client: %w", err)
+			}
+
+			flusher.Flush()
+		}
+
+		if readErr != nil {
+			if readErr == io.EOF {
+				return nil
+			}
+
+			return fmt.Errorf("failed to read stream: %w", readErr)
+		}
+	}
+}
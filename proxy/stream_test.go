@@ -0,0 +1,141 @@
+package proxy
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsStreamingResponse(t *testing.T) {
+	tests := []struct {
+		name             string
+		contentType      string
+		transferEncoding []string
+		streaming        bool
+	}{
+		{"sse", "text/event-stream", nil, true},
+		{"sse with charset", "text/event-stream; charset=utf-8", nil, true},
+		{"sse in upper case", "Text/Event-Stream;charset=UTF-8", nil, true},
+		{"ndjson", "application/x-ndjson", nil, true},
+		{"jsonl", "application/jsonl", nil, true},
+		{"stream json", "application/stream+json", nil, true},
+		{"json", "application/json", nil, false},
+		{"json chunked", "application/json", []string{"chunked"}, true},
+		{"no content type chunked", "", []string{"Chunked"}, true},
+		{"no content type", "", nil, false},
+		{"invalid media type", "text/event-stream; charset", nil, false},
+		{"prefix of a media type", "text/event-stream-v2", nil, false},
+	}
+
+	for _, test := range tests {
+		resp := &http.Response{Header: http.Header{}, TransferEncoding: test.transferEncoding}
+		if test.contentType != "" {
+			resp.Header.Set("Content-Type", test.contentType)
+		}
+
+		assert.Equal(t, test.streaming, IsStreamingResponse(resp, DefaultStreamContentTypes), test.name)
+	}
+
+	// the media types are configurable
+	resp := &http.Response{Header: http.Header{"Content-Type": {"application/grpc-web+proto"}}}
+	assert.True(t, IsStreamingResponse(resp, []string{"application/grpc-web+proto"}))
+	assert.False(t, IsStreamingResponse(resp, DefaultStreamContentTypes))
+}
+
+// chunkReader returns one chunk per read
+type chunkReader struct {
+	chunks []string
+	err    error
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, r.err
+	}
+
+	n := copy(p, r.chunks[0])
+	r.chunks = r.chunks[1:]
+
+	return n, nil
+}
+
+// flushRecorder records the body written before each flush
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushed []string
+	written int
+}
+
+func (r *flushRecorder) Flush() {
+	body := r.Body.String()
+	r.flushed = append(r.flushed, body[r.written:])
+	r.written = len(body)
+}
+
+func TestStreamBodyFlushPerRead(t *testing.T) {
+	w := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	chunks := []string{"data: 1\n\n", "data: 2\n\n", `{"done":true}` + "\n"}
+
+	require.NoError(t, StreamBody(w, &chunkReader{chunks: chunks, err: io.EOF}))
+	assert.Equal(t, chunks, w.flushed)
+
+	// a failed read is returned once the bytes read before are flushed
+	w = &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	readErr := errors.New("stream reset")
+
+	err := StreamBody(w, &chunkReader{chunks: chunks[:1], err: readErr})
+	assert.ErrorIs(t, err, readErr)
+	assert.Equal(t, chunks[:1], w.flushed)
+
+	// a writer which can't flush is refused
+	err = StreamBody(struct{ http.ResponseWriter }{httptest.NewRecorder()}, &chunkReader{err: io.EOF})
+	assert.ErrorContains(t, err, "does not support flushing")
+}
+
+func TestStreamBodyOverHTTP(t *testing.T) {
+	// the headers are sent with the first event, so it is buffered
+	events := make(chan string, 1)
+	events <- "data: 1\n"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reader, writer := io.Pipe()
+		go func() {
+			for event := range events {
+				_, _ = writer.Write([]byte(event))
+			}
+			writer.Close()
+		}()
+
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		assert.NoError(t, StreamBody(w, reader))
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	// each event is received before the next one is sent
+	reader := bufio.NewReader(resp.Body)
+	for i, event := range []string{"data: 1\n", "data: 2\n"} {
+		if i > 0 {
+			events <- event
+		}
+
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, event, line)
+	}
+	close(events)
+
+	rest, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Empty(t, rest)
+	assert.True(t, IsStreamingResponse(resp, DefaultStreamContentTypes))
+}
@@ -0,0 +1,194 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// ClientIdentityHeader carries the identity of the client certificate to the edge node
+const ClientIdentityHeader = "X-Forwarded-Client-Identity"
+
+// certCheckInterval is the minimum interval between two checks of the certificate files
+const certCheckInterval = 10 * time.Second
+
+type ClientAuthMode string
+
+const (
+	// ClientAuthNone doesn't ask for a client certificate
+	ClientAuthNone ClientAuthMode = ""
+	// ClientAuthOptional verifies the client certificate if one is sent
+	ClientAuthOptional ClientAuthMode = "optional"
+	// ClientAuthRequire rejects the connections without a valid client certificate
+	ClientAuthRequire ClientAuthMode = "require"
+)
+
+// TLSConfig defines the HTTPS listener of the transparent proxy
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile holds the CAs of the client certificates, required by the client auth modes
+	ClientCAFile string
+	ClientAuth   ClientAuthMode
+}
+
+// certReloader serves the certificate and reloads it when the files change
+type certReloader struct {
+	logger   hclog.Logger
+	certFile string
+	keyFile  string
+
+	lock      sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	lastCheck time.Time
+}
+
+func newCertReloader(logger hclog.Logger, certFile string, keyFile string) (*certReloader, error) {
+	r := &certReloader{
+		logger:   logger,
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+
+	modTime, err := r.filesModTime()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.load(modTime); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// filesModTime returns the latest modification time of the certificate and the key
+func (r *certReloader) filesModTime() (time.Time, error) {
+	var latest time.Time
+
+	for _, file := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	return latest, nil
+}
+
+func (r *certReloader) load(modTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate: %w", err)
+	}
+
+	r.cert = &cert
+	r.modTime = modTime
+	r.logger.Info("certificate loaded", "cert", r.certFile)
+
+	return nil
+}
+
+// getCertificate reloads the certificate if the files changed, the previous one is kept on error
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if now := time.Now(); now.Sub(r.lastCheck) >= certCheckInterval {
+		r.lastCheck = now
+
+		modTime, err := r.filesModTime()
+		if err != nil {
+			r.logger.Warn("failed to check certificate", "err", err)
+		} else if !modTime.Equal(r.modTime) {
+			if err := r.load(modTime); err != nil {
+				r.logger.Error("failed to reload certificate", "err", err)
+			}
+		}
+	}
+
+	return r.cert, nil
+}
+
+// newTLSConfig builds the tls config of the listener
+func newTLSConfig(logger hclog.Logger, config *TLSConfig) (*tls.Config, error) {
+	reloader, err := newCertReloader(logger, config.CertFile, config.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.getCertificate,
+	}
+
+	switch config.ClientAuth {
+	case ClientAuthNone:
+		return tlsConfig, nil
+	case ClientAuthOptional:
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("unknown client auth mode '%s'", config.ClientAuth)
+	}
+
+	if config.ClientCAFile == "" {
+		return nil, errors.New("client auth requires a client CA file")
+	}
+
+	caPEM, err := os.ReadFile(config.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificate found in %s", config.ClientCAFile)
+	}
+	tlsConfig.ClientCAs = clientCAs
+
+	return tlsConfig, nil
+}
+
+// clientIdentity returns the identity of the verified client certificate:
+// the subject common name, else the first DNS name, else the first URI
+func clientIdentity(r *http.Request) string {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return ""
+	}
+
+	cert := r.TLS.VerifiedChains[0][0]
+
+	switch {
+	case cert.Subject.CommonName != "":
+		return cert.Subject.CommonName
+	case len(cert.DNSNames) > 0:
+		return cert.DNSNames[0]
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String()
+	default:
+		return ""
+	}
+}
+
+// forwardedProto returns the protocol of the Forwarded header
+func forwardedProto(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+
+	return "http"
+}
@@ -0,0 +1,311 @@
+package proxy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCA issues the certificates of the tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns the PEM certificate and key of the template signed by the CA
+func (ca *testCA) issue(t *testing.T, template *x509.Certificate) ([]byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// issueKeyPair returns the certificate of the template as a tls client certificate
+func (ca *testCA) issueKeyPair(t *testing.T, template *x509.Certificate) tls.Certificate {
+	t.Helper()
+
+	certPEM, keyPEM := ca.issue(t, template)
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	return cert
+}
+
+// writeServerCert writes the certificate of the relay and its key, with the modification time
+func writeServerCert(t *testing.T, ca *testCA, dir string, commonName string, modTime time.Time) (string, string) {
+	t.Helper()
+
+	certPEM, keyPEM := ca.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: commonName},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, certPEM, 0600))
+	require.NoError(t, os.WriteFile(keyFile, keyPEM, 0600))
+	require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+
+	return certFile, keyFile
+}
+
+// servedName returns the common name of the certificate served by the reloader
+func servedName(t *testing.T, r *certReloader) string {
+	t.Helper()
+
+	cert, err := r.getCertificate(nil)
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+
+	return leaf.Subject.CommonName
+}
+
+func TestCertReloaderRotation(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	now := time.Now()
+
+	certFile, keyFile := writeServerCert(t, ca, dir, "relay-1", now.Add(-time.Hour))
+
+	reloader, err := newCertReloader(hclog.NewNullLogger(), certFile, keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, "relay-1", servedName(t, reloader))
+
+	// the rotated certificate is served after the check interval
+	writeServerCert(t, ca, dir, "relay-2", now)
+	assert.Equal(t, "relay-1", servedName(t, reloader))
+
+	reloader.lastCheck = time.Time{}
+	assert.Equal(t, "relay-2", servedName(t, reloader))
+
+	// a broken rotation keeps the previous certificate
+	require.NoError(t, os.WriteFile(certFile, []byte("not a certificate"), 0600))
+	require.NoError(t, os.Chtimes(certFile, now.Add(time.Minute), now.Add(time.Minute)))
+
+	reloader.lastCheck = time.Time{}
+	assert.Equal(t, "relay-2", servedName(t, reloader))
+
+	// so does a missing file
+	require.NoError(t, os.Remove(keyFile))
+
+	reloader.lastCheck = time.Time{}
+	assert.Equal(t, "relay-2", servedName(t, reloader))
+}
+
+func TestNewTLSConfigErrors(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile := writeServerCert(t, ca, dir, "relay", time.Now())
+
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, ca.pem, 0600))
+
+	emptyFile := filepath.Join(dir, "empty.pem")
+	require.NoError(t, os.WriteFile(emptyFile, []byte("no certificate"), 0600))
+
+	tests := []struct {
+		name     string
+		config   *TLSConfig
+		expected string
+	}{
+		{"missing certificate", &TLSConfig{CertFile: filepath.Join(dir, "missing.pem"), KeyFile: keyFile}, "no such file"},
+		{"unknown client auth", &TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientAuth: "maybe"}, "unknown client auth mode"},
+		{"client auth without CA", &TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientAuth: ClientAuthRequire}, "requires a client CA file"},
+		{"CA file without certificate", &TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientAuth: ClientAuthOptional, ClientCAFile: emptyFile}, "no certificate found"},
+	}
+
+	for _, test := range tests {
+		_, err := newTLSConfig(hclog.NewNullLogger(), test.config)
+		assert.ErrorContains(t, err, test.expected, test.name)
+	}
+
+	tlsConfig, err := newTLSConfig(hclog.NewNullLogger(), &TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientAuth: ClientAuthRequire, ClientCAFile: caFile})
+	require.NoError(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, tlsConfig.ClientAuth)
+	assert.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MinVersion)
+}
+
+// startTLSServer serves the identity and the forwarded headers of the requests over https
+func startTLSServer(t *testing.T, config *TLSConfig) string {
+	t.Helper()
+
+	tlsConfig, err := newTLSConfig(hclog.NewNullLogger(), config)
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		setForwardedHeaders(r, &EdgePath{})
+		fmt.Fprintf(w, "%s|%s|%s", clientIdentity(r), r.Header.Get("Forwarded"), r.Header.Get("X-Forwarded-Proto"))
+	})}
+	go srv.Serve(tls.NewListener(listener, tlsConfig)) //nolint:errcheck
+	t.Cleanup(func() { srv.Close() })
+
+	return "https://" + listener.Addr().String()
+}
+
+func getTLS(ca *testCA, serverURL string, clientCert *tls.Certificate) (string, error) {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	tlsConfig := &tls.Config{RootCAs: roots}
+	if clientCert != nil {
+		tlsConfig.Certificates = []tls.Certificate{*clientCert}
+	}
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	defer client.CloseIdleConnections()
+
+	resp, err := client.Get(serverURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+
+	return string(body), err
+}
+
+func TestClientIdentity(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile := writeServerCert(t, ca, dir, "relay", time.Now())
+
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, ca.pem, 0600))
+
+	serverURL := startTLSServer(t, &TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientAuth: ClientAuthOptional, ClientCAFile: caFile})
+
+	spiffe, err := url.Parse("spiffe://edge/partner")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		template *x509.Certificate
+		identity string
+	}{
+		{"common name", &x509.Certificate{Subject: pkix.Name{CommonName: "partner-acme"}, DNSNames: []string{"acme.example.com"}}, "partner-acme"},
+		{"dns name", &x509.Certificate{DNSNames: []string{"acme.example.com", "other.example.com"}}, "acme.example.com"},
+		{"uri", &x509.Certificate{URIs: []*url.URL{spiffe}}, "spiffe://edge/partner"},
+	}
+
+	for _, test := range tests {
+		test.template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+		clientCert := ca.issueKeyPair(t, test.template)
+
+		body, err := getTLS(ca, serverURL, &clientCert)
+		require.NoError(t, err, test.name)
+		assert.Regexp(t, `^`+regexp.QuoteMeta(test.identity)+`\|proto=https;host=".+";for="127\.0\.0\.1:\d+"\|https$`, body, test.name)
+	}
+
+	// without a certificate, the optional mode has no identity
+	body, err := getTLS(ca, serverURL, nil)
+	require.NoError(t, err)
+	assert.Regexp(t, `^\|proto=https;host=".+";for="127\.0\.0\.1:\d+"\|https$`, body)
+
+	// a certificate of another CA is rejected
+	other := newTestCA(t).issueKeyPair(t, &x509.Certificate{Subject: pkix.Name{CommonName: "intruder"}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+	_, err = getTLS(ca, serverURL, &other)
+	assert.Error(t, err)
+}
+
+func TestClientAuthRequire(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile := writeServerCert(t, ca, dir, "relay", time.Now())
+
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, ca.pem, 0600))
+
+	serverURL := startTLSServer(t, &TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientAuth: ClientAuthRequire, ClientCAFile: caFile})
+
+	_, err := getTLS(ca, serverURL, nil)
+	assert.Error(t, err)
+
+	clientCert := ca.issueKeyPair(t, &x509.Certificate{Subject: pkix.Name{CommonName: "partner-acme"}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+
+	body, err := getTLS(ca, serverURL, &clientCert)
+	require.NoError(t, err)
+	assert.Regexp(t, `^partner-acme\|proto=https;`, body)
+}
+
+func TestForwardedProtoHTTP(t *testing.T) {
+	r, err := http.NewRequest(http.MethodGet, "http://relay/edge/node/9527/v1", nil)
+	require.NoError(t, err)
+	r.Host = "relay"
+	r.RemoteAddr = "10.0.0.1:1234"
+
+	setForwardedHeaders(r, &EdgePath{Prefix: "/edge/node/9527"})
+
+	assert.Equal(t, `proto=http;host="relay";for="10.0.0.1:1234"`, r.Header.Get("Forwarded"))
+	assert.Equal(t, "http", r.Header.Get("X-Forwarded-Proto"))
+	assert.Equal(t, "/edge/node/9527", r.Header.Get("X-Forwarded-Prefix"))
+	assert.Empty(t, clientIdentity(r))
+}
@@ -0,0 +1,95 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the instrumentation name of the spans of the transparent proxy
+const TracerName = "github.com/EdgeMatrixChain/edge-matrix-computing/proxy"
+
+var tracer = otel.Tracer(TracerName)
+
+// TracingHandler starts the span of the request on the hop, as a child of the W3C traceparent of the request
+func TracingHandler(spanName string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, spanName,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+				attribute.String("request.id", RequestID(r)),
+			),
+		)
+		defer span.End()
+
+		recorder := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+// InjectTraceContext sets the traceparent of the span of the context in the header of the next hop
+func InjectTraceContext(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// setSpanTarget records the target node of the request in its span
+func setSpanTarget(r *http.Request, nodeID string, port int) {
+	trace.SpanFromContext(r.Context()).SetAttributes(
+		attribute.String("edge.node_id", nodeID),
+		attribute.Int("edge.port", port),
+	)
+}
+
+// endSpan records the error, if any, and ends the span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// spanBody ends the span of the forward when the body of the response is closed
+type spanBody struct {
+	io.ReadCloser
+	span trace.Span
+}
+
+func (b *spanBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.span.End()
+
+	return err
+}
+
+// authBearer checks the bearer with the store in a span
+func (j *TransparentProxy) authBearer(ctx context.Context, bearer string, nodeID string, port int) (bool, string) {
+	_, span := tracer.Start(ctx, "proxy.auth", trace.WithAttributes(
+		attribute.String("edge.node_id", nodeID),
+		attribute.Int("edge.port", port),
+	))
+	defer span.End()
+
+	ok, apiToken := j.config.Store.AuthBearer(bearer, nodeID, port)
+	span.SetAttributes(attribute.Bool("auth.ok", ok))
+
+	return ok, apiToken
+}
@@ -0,0 +1,159 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/EdgeMatrixChain/edge-matrix-core/core/application"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	testSpansOnce sync.Once
+	testSpans     = tracetest.NewSpanRecorder()
+)
+
+// recordSpans records the spans of the tests in memory.
+// The tracer of the package is bound to the first global provider, so it is set once for all the tests.
+func recordSpans() {
+	testSpansOnce.Do(func() {
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(testSpans)))
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	})
+}
+
+// endedSpans returns the ended spans of the trace by name
+func endedSpans(traceID trace.TraceID) map[string]sdktrace.ReadOnlySpan {
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range testSpans.Ended() {
+		if span.SpanContext().TraceID() == traceID {
+			spans[span.Name()] = span
+		}
+	}
+
+	return spans
+}
+
+// newRemoteParent returns the traceparent header of a span of the client
+func newRemoteParent(t *testing.T) (trace.SpanContext, http.Header) {
+	t.Helper()
+
+	ctx, span := otel.Tracer("client").Start(context.Background(), "client.request")
+	span.End()
+
+	header := http.Header{}
+	InjectTraceContext(ctx, header)
+	require.NotEmpty(t, header.Get("traceparent"))
+
+	return span.SpanContext(), header
+}
+
+// traceparent returns the traceparent header of the span
+func traceparent(spanContext trace.SpanContext) string {
+	return fmt.Sprintf("00-%s-%s-01", spanContext.TraceID(), spanContext.SpanID())
+}
+
+func TestTracingHandler(t *testing.T) {
+	recordSpans()
+
+	parent, header := newRemoteParent(t)
+
+	var injected string
+	handler := TracingHandler("proxy.request", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next := http.Header{}
+		InjectTraceContext(r.Context(), next)
+		injected = next.Get("traceparent")
+
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/edge/node1/9527/v1", nil)
+	r.Header = header
+	r.Header.Set(RequestIDHeader, "req-1")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	span := endedSpans(parent.TraceID())["proxy.request"]
+	require.NotNil(t, span)
+
+	// the span is a child of the traceparent, and the traceparent of the next hop is the span
+	assert.Equal(t, parent.SpanID(), span.Parent().SpanID())
+	assert.True(t, span.Parent().IsRemote())
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+	assert.Equal(t, traceparent(span.SpanContext()), injected)
+
+	attributes := make(map[string]string)
+	for _, attribute := range span.Attributes() {
+		attributes[string(attribute.Key)] = attribute.Value.Emit()
+	}
+	assert.Equal(t, "POST", attributes["http.request.method"])
+	assert.Equal(t, "req-1", attributes["request.id"])
+	assert.Equal(t, "502", attributes["http.response.status_code"])
+	assert.Equal(t, "Error", span.Status().Code.String())
+
+	// without a traceparent, the span starts a new trace
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.NotContains(t, injected, parent.TraceID().String())
+}
+
+func TestTracingForward(t *testing.T) {
+	recordSpans()
+
+	// the edge node continues the trace of the relay
+	var received string
+	edgeHandler := TracingHandler("edge.forward", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("traceparent")
+	}))
+	edgeHost, relayHost, _ := newForwardHosts(t, edgeHandler.ServeHTTP)
+	nodeID := edgeHost.ID().String()
+
+	j := newTestProxy(&Config{Store: &testStore{
+		relayHost: relayHost,
+		keys:      map[string]bool{"good": true},
+		appPeers:  map[string]*application.AppPeer{nodeID: {Addr: edgeHost.Addrs()[0].String()}},
+	}})
+	j.clients = newP2PClients(nil)
+	defer j.clients.close()
+
+	handler := TracingHandler("proxy.request", j.bearerMiddlewareFactory(ParseEdgePath)(http.HandlerFunc(j.handle)))
+
+	parent, header := newRemoteParent(t)
+
+	r := httptest.NewRequest(http.MethodGet, "http://relay/edge/"+nodeID+"/9527/v1/chat", nil)
+	r.Header = header
+	r.Header.Set("Authorization", "Bearer good")
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var spans map[string]sdktrace.ReadOnlySpan
+	require.Eventually(t, func() bool {
+		spans = endedSpans(parent.TraceID())
+
+		// the client span and the spans of the relay and of the edge node
+		return len(spans) == 6
+	}, time.Second, 10*time.Millisecond)
+
+	request, auth, forward, dial, edge := spans["proxy.request"], spans["proxy.auth"], spans["proxy.forward"], spans["libp2p.dial"], spans["edge.forward"]
+
+	assert.Equal(t, parent.SpanID(), request.Parent().SpanID())
+	assert.Equal(t, request.SpanContext().SpanID(), auth.Parent().SpanID())
+	assert.Equal(t, request.SpanContext().SpanID(), forward.Parent().SpanID())
+	assert.Equal(t, forward.SpanContext().SpanID(), dial.Parent().SpanID())
+
+	// the traceparent sent over the stream is the forward span
+	assert.Equal(t, traceparent(forward.SpanContext()), received)
+	assert.Equal(t, forward.SpanContext().SpanID(), edge.Parent().SpanID())
+	assert.True(t, edge.Parent().IsRemote())
+}
@@ -1,12 +1,12 @@
 package proxy
 
 import (
-	"bufio"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/EdgeMatrixChain/edge-matrix-core/core/application"
-	p2phttp "github.com/libp2p/go-libp2p-http"
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/libp2p/go-libp2p/core/peerstore"
@@ -19,6 +19,8 @@ import (
 	"time"
 
 	"github.com/hashicorp/go-hclog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const TransparentForwardUrl = "/transparent_forward"
@@ -46,8 +48,16 @@ func (s serverType) String() string {
 
 // TransparentProxy is an API consensus
 type TransparentProxy struct {
-	logger hclog.Logger
-	config *Config
+	logger   hclog.Logger
+	config   *Config
+	balancer *appBalancer
+	policy   *PolicyEngine
+
+	rateLimiter *RateLimiter
+	clients     *p2pClients
+	breakers    *Breakers
+	usage       *UsageMeter
+	jobs        *Jobs
 }
 
 // TransparentProxyStore defines all the methods required
@@ -56,6 +66,10 @@ type TransparentProxyStore interface {
 	GetRelayHost() host.Host
 	GetNetworkHost() host.Host
 	GetAppPeer(id string) *application.AppPeer
+	GetAppPeers(appName string) map[string]*application.AppPeer
+	ListAppPeers() map[string]*application.AppPeer
+	// ResolveAppPeerID returns the NodeID of a known app peer matching the label case-insensitively
+	ResolveAppPeerID(label string) (string, bool)
 	ValidateBearer(bearer string) bool
 	AuthBearer(bearer string, nodeId string, port int) (bool, string)
 }
@@ -67,13 +81,75 @@ type Config struct {
 	NetworkName              string
 	Version                  string
 	AccessControlAllowOrigin []string
+	BalancePolicy            BalancePolicy
+	Retry                    *RetryConfig
+	PolicyFile               string
+	RateLimit                *RateLimitConfig
+	TLS                      *TLSConfig
+	Transport                *TransportConfig
+	AccessLogger             *AccessLogger
+	// StreamContentTypes are the media types streamed to the client, see DefaultStreamContentTypes
+	StreamContentTypes []string
+	// OpenAI enables the OpenAI-compatible gateway, disabled if nil
+	OpenAI *OpenAIConfig
+	// Breaker defines the circuit breaker of the nodes, disabled if nil
+	Breaker *BreakerConfig
+	// HostDomain enables the routing by Host header, <port>--<nodeId>.<HostDomain>, disabled if empty
+	HostDomain string
+	// AppPathPrefix enables the routing by app name, /<AppPathPrefix>/<appName>/<path>, disabled if empty.
+	// It shadows the legacy routes using the same prefix, /<AppPathPrefix>/<nodeId>/<port>/<path>.
+	AppPathPrefix string
+	// AppPorts is the port of each app routed by name, an app without a port is not routed
+	AppPorts map[string]int
+	// Usage enables the usage records of the forwarded requests, disabled if nil
+	Usage *UsageConfig
+	// Jobs enables the async job API, disabled if nil
+	Jobs *JobsConfig
 }
 
 // NewTransportProxy returns the TransparentProxy http server
 func NewTransportProxy(logger hclog.Logger, config *Config, noAuth bool) (*TransparentProxy, error) {
 	srv := &TransparentProxy{
-		logger: logger.Named("transport-proxy"),
-		config: config,
+		logger:   logger.Named("transport-proxy"),
+		config:   config,
+		balancer: newAppBalancer(config.BalancePolicy),
+		clients:  newP2PClients(config.Transport),
+	}
+
+	if config.PolicyFile != "" {
+		policy, err := NewPolicyEngine(srv.logger, config.PolicyFile)
+		if err != nil {
+			return nil, err
+		}
+		srv.policy = policy
+	}
+
+	if config.Breaker != nil {
+		srv.breakers = NewBreakers(config.Breaker)
+	}
+
+	if config.RateLimit != nil {
+		rateLimiter, err := NewRateLimiter(srv.logger, config.RateLimit)
+		if err != nil {
+			return nil, err
+		}
+		srv.rateLimiter = rateLimiter
+	}
+
+	if config.Usage != nil {
+		usage, err := NewUsageMeter(srv.logger, config.Usage)
+		if err != nil {
+			return nil, err
+		}
+		srv.usage = usage
+	}
+
+	if config.Jobs != nil {
+		jobs, err := NewJobs(srv.logger, config.Jobs)
+		if err != nil {
+			return nil, err
+		}
+		srv.jobs = jobs
 	}
 
 	// start http server
@@ -84,6 +160,37 @@ func NewTransportProxy(logger hclog.Logger, config *Config, noAuth bool) (*Trans
 	return srv, nil
 }
 
+// Policy returns the NodeID policy engine, nil if no policy file is set
+func (j *TransparentProxy) Policy() *PolicyEngine {
+	return j.policy
+}
+
+// Breakers returns the circuit breakers of the nodes, nil if they are disabled
+func (j *TransparentProxy) Breakers() *Breakers {
+	return j.breakers
+}
+
+// Usage returns the usage meter, nil if the usage records are disabled
+func (j *TransparentProxy) Usage() *UsageMeter {
+	return j.usage
+}
+
+func (j *TransparentProxy) Close() {
+	if j.policy != nil {
+		j.policy.Close()
+	}
+
+	if j.rateLimiter != nil {
+		j.rateLimiter.Close()
+	}
+
+	j.jobs.Close()
+
+	j.usage.Close()
+
+	j.clients.close()
+}
+
 type MiddlewareFactory func(config *Config) func(http.Handler) http.Handler
 
 func (j *TransparentProxy) setupHTTP(noAuth bool) error {
@@ -102,20 +209,53 @@ func (j *TransparentProxy) setupHTTP(noAuth bool) error {
 	// The middleware factory returns a handler, so we need to wrap the handler function properly.
 	proxyHandler := http.HandlerFunc(j.handle)
 
+	wsHandler := http.HandlerFunc(j.handleWs)
+
+	middlewareFactory := j.defaultMiddlewareFactory
 	if !noAuth {
-		mux.Handle("/", j.bearerMiddlewareFactory()(proxyHandler))
-	} else {
-		mux.Handle("/", j.defaultMiddlewareFactory()(proxyHandler))
+		middlewareFactory = j.bearerMiddlewareFactory
+	}
+
+	parseEdgePath := newEdgePathParser(j.config.AppPathPrefix)
+
+	mux.Handle("/", middlewareFactory(parseEdgePath)(proxyHandler))
+	mux.Handle(EdgeWsUrl, middlewareFactory(parseEdgePath)(wsHandler))
+
+	// the requests to the node subdomains keep their path, so they are not routed by the mux
+	handler := http.Handler(mux)
+	if j.config.HostDomain != "" {
+		handler = j.hostRoutingHandler(middlewareFactory(j.parseHostPath)(proxyHandler), mux)
+		j.logger.Info("host routing enabled", "domain", j.config.HostDomain)
+	}
+
+	if j.config.OpenAI != nil {
+		j.setupOpenAI(mux, middlewareFactory(parseOpenAIPath), noAuth)
+		j.logger.Info("openai gateway enabled", "models", len(j.config.OpenAI.Models))
 	}
 
-	// TODO implement websocket handler
-	//mux.HandleFunc("/edge_ws", j.handleWs)
+	if j.jobs != nil {
+		j.setupJobs(mux, middlewareFactory(parseEdgePath), noAuth)
+		j.logger.Info("async jobs enabled", "workers", j.jobs.config.Workers, "ttl", j.jobs.config.TTL)
+	}
 
 	srv := http.Server{
-		Handler:           mux,
+		Handler:           j.config.AccessLogger.Handler("relay", TracingHandler("proxy.request", MetricsHandler("relay", j.usage.Handler(handler)))),
 		ReadHeaderTimeout: 60 * time.Second,
 	}
 
+	if j.config.TLS != nil {
+		tlsConfig, err := newTLSConfig(j.logger, j.config.TLS)
+		if err != nil {
+			lis.Close()
+
+			return err
+		}
+
+		srv.TLSConfig = tlsConfig
+		lis = tls.NewListener(lis, tlsConfig)
+		j.logger.Info("https enabled", "clientAuth", j.config.TLS.ClientAuth)
+	}
+
 	go func() {
 		if err := srv.Serve(lis); err != nil {
 			j.logger.Error("closed http connection", "err", err)
@@ -129,6 +269,11 @@ func (j *TransparentProxy) setupHTTP(noAuth bool) error {
 func getBearer(r *http.Request) string {
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
+		// browsers can not set headers on the websocket handshake
+		if IsWebSocketUpgrade(r) {
+			return r.URL.Query().Get("access_token")
+		}
+
 		return ""
 	}
 
@@ -140,56 +285,101 @@ func getBearer(r *http.Request) string {
 	return parts[1]
 }
 
-// The bearerMiddlewareFactory builds a middleware which enables authorization with Bearer.
-func (j *TransparentProxy) bearerMiddlewareFactory() func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "*")
-
-			origin := r.Header.Get("Origin")
-			for _, allowedOrigin := range j.config.AccessControlAllowOrigin {
-				if allowedOrigin == "*" {
-					w.Header().Set("Access-Control-Allow-Origin", "*")
+// setCORSHeaders enables CORS using the provided config.
+// An allowed origin can be a wildcard subdomain, e.g. https://*.edge.example.com for the apps routed by host.
+func (j *TransparentProxy) setCORSHeaders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Methods", AllowedMethods)
+	w.Header().Set("Access-Control-Allow-Headers", "*")
 
-					break
-				}
+	origin := r.Header.Get("Origin")
+	for _, allowedOrigin := range j.config.AccessControlAllowOrigin {
+		if allowedOrigin == "*" {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
 
-				if allowedOrigin == origin {
-					w.Header().Set("Access-Control-Allow-Origin", origin)
+			break
+		}
 
-					break
-				}
+		if origin != "" && matchOrigin(allowedOrigin, origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			// the headers may already be set by a handler in front of the middleware
+			if !headerHasValue(w.Header(), "Vary", "Origin") {
+				w.Header().Add("Vary", "Origin")
 			}
 
-			// add Header: Forwarded(RFC 7239),
-			// e.g Forwarded: proto=http; host="example.com:8080"; for="client_ip"
-			r.Header.Add("Forwarded", fmt.Sprintf("proto=%s; host=%s; for=%s", "http", r.Host, r.RemoteAddr))
+			break
+		}
+	}
+}
+
+// setForwardedHeaders adds the Forwarded header (RFC 7239) and the X-Forwarded-* headers of the client request,
+// e.g Forwarded: proto=https;host="9527--<nodeId>.edge.example.com";for="client_ip:port".
+// The path prefix removed by the path routing is sent in X-Forwarded-Prefix, there is none with the host routing.
+func setForwardedHeaders(r *http.Request, pathInfo *EdgePath) {
+	r.Header.Add("Forwarded", fmt.Sprintf("proto=%s;host=%q;for=%q", forwardedProto(r), r.Host, r.RemoteAddr))
+	r.Header.Set("X-Forwarded-Proto", forwardedProto(r))
+
+	r.Header.Del("X-Forwarded-Prefix")
+	if pathInfo.Prefix != "" {
+		r.Header.Set("X-Forwarded-Prefix", pathInfo.Prefix)
+	}
+}
+
+// The bearerMiddlewareFactory builds a middleware which enables authorization with Bearer.
+// parsePath returns the edge path of the request, see ParseEdgePath.
+func (j *TransparentProxy) bearerMiddlewareFactory(parsePath func(*http.Request) (*EdgePath, error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			j.setCORSHeaders(w, r)
 
-			pathInfo, err := ParseEdgePath(r)
+			pathInfo, err := parsePath(r)
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusBadRequest)
 				return
 			}
-			j.logger.Info("handle", "NodeID", pathInfo.NodeID, "Port", pathInfo.Port, "InterfaceURL", pathInfo.InterfaceURL)
+			setForwardedHeaders(r, pathInfo)
+			if err := j.resolveAppNode(pathInfo, clientIdentity(r)); err != nil {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+			SetRequestTarget(r, pathInfo.NodeID, pathInfo.Port)
+			j.logger.Info("handle", "RequestID", RequestID(r), "NodeID", pathInfo.NodeID, "Port", pathInfo.Port, "InterfaceURL", pathInfo.InterfaceURL)
+			if !j.checkPolicy(w, r, pathInfo) {
+				return
+			}
+
+			if !IsPreflightRequest(r) {
+				// the client IP is limited before the bearer is checked
+				clientLimit, ok := j.checkClientRateLimit(w, r)
+				if !ok {
+					return
+				}
 
-			if r.Method != "OPTIONS" {
 				// verify bearer
 				bearer := getBearer(r)
 				if bearer == "" {
+					IncrAuthFailure("relay", "missing")
 					http.Error(w, "Unauthorized", http.StatusUnauthorized)
 
 					return
 				}
-				ok, apiToken := j.config.Store.AuthBearer(bearer, pathInfo.NodeID, pathInfo.Port)
+				ok, apiToken := j.authBearer(r.Context(), bearer, pathInfo.NodeID, pathInfo.Port)
 				if !ok {
+					IncrAuthFailure("relay", "invalid")
 					http.Error(w, "Unauthorized", http.StatusUnauthorized)
 
 					return
 				}
+				SetRequestPrincipal(r, requestPrincipal(r, bearer))
+
+				if !j.checkRateLimit(w, r, bearer, pathInfo, clientLimit) {
+					return
+				}
 
 				// replace Bearer with apiToken
 				r.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiToken))
+
+				// keep the bearer for authorizing a failover node
+				r = r.WithContext(context.WithValue(r.Context(), "Bearer", bearer))
 			}
 
 			// add Header: X-Forwarded-*
@@ -197,6 +387,11 @@ func (j *TransparentProxy) bearerMiddlewareFactory() func(http.Handler) http.Han
 			r.Header.Add("X-Forwarded-EdgePort", strconv.Itoa(pathInfo.Port))
 			r.Header.Add("X-Forwarded-NodeID", pathInfo.NodeID)
 			r.Header.Add("X-Forwarded-Interface", pathInfo.InterfaceURL)
+			// the identity can't be set by the client
+			r.Header.Del(ClientIdentityHeader)
+			if identity := clientIdentity(r); identity != "" {
+				r.Header.Set(ClientIdentityHeader, identity)
+			}
 
 			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), "EdgePath", pathInfo)))
 		})
@@ -204,41 +399,43 @@ func (j *TransparentProxy) bearerMiddlewareFactory() func(http.Handler) http.Han
 }
 
 // The defaultMiddlewareFactory builds a middleware which enables CORS using the provided config.
-func (j *TransparentProxy) defaultMiddlewareFactory() func(http.Handler) http.Handler {
+func (j *TransparentProxy) defaultMiddlewareFactory(parsePath func(*http.Request) (*EdgePath, error)) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "*")
+			j.setCORSHeaders(w, r)
 
-			origin := r.Header.Get("Origin")
-
-			for _, allowedOrigin := range j.config.AccessControlAllowOrigin {
-				if allowedOrigin == "*" {
-					w.Header().Set("Access-Control-Allow-Origin", "*")
-					break
-				}
-
-				if allowedOrigin == origin {
-					w.Header().Set("Access-Control-Allow-Origin", origin)
-
-					break
-				}
-			}
-			// add Header: Forwarded(RFC 7239),
-			// e.g Forwarded: proto=http; host="example.com:8080"; for="client_ip"
-			r.Header.Add("Forwarded", fmt.Sprintf("proto=%s; host=%s; for=%s", "http", r.Host, r.RemoteAddr))
-
-			pathInfo, err := ParseEdgePath(r)
+			pathInfo, err := parsePath(r)
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusBadRequest)
 				return
 			}
+			setForwardedHeaders(r, pathInfo)
+			if err := j.resolveAppNode(pathInfo, clientIdentity(r)); err != nil {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+			SetRequestTarget(r, pathInfo.NodeID, pathInfo.Port)
+			if !j.checkPolicy(w, r, pathInfo) {
+				return
+			}
+			if !IsPreflightRequest(r) {
+				clientLimit, ok := j.checkClientRateLimit(w, r)
+				if !ok || !j.checkRateLimit(w, r, "", pathInfo, clientLimit) {
+					return
+				}
+			}
+			SetRequestPrincipal(r, requestPrincipal(r, ""))
 
 			// add Header: X-Forwarded-*
 			r.Header.Add("X-Forwarded-Host", j.config.Store.GetRelayHost().ID().String())
 			r.Header.Add("X-Forwarded-EdgePort", strconv.Itoa(pathInfo.Port))
 			r.Header.Add("X-Forwarded-NodeID", pathInfo.NodeID)
 			r.Header.Add("X-Forwarded-Interface", pathInfo.InterfaceURL)
+			// the identity can't be set by the client
+			r.Header.Del(ClientIdentityHeader)
+			if identity := clientIdentity(r); identity != "" {
+				r.Header.Set(ClientIdentityHeader, identity)
+			}
 
 			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), "EdgePath", pathInfo)))
 		})
@@ -246,13 +443,13 @@ func (j *TransparentProxy) defaultMiddlewareFactory() func(http.Handler) http.Ha
 }
 
 func (j *TransparentProxy) handle(w http.ResponseWriter, req *http.Request) {
-	switch req.Method {
-	case "POST", "GET":
-		j.handleRequest(w, req)
-	case "OPTIONS":
+	switch {
+	case IsPreflightRequest(req):
 		// nothing to return
+	case req.Method == http.MethodGet && IsWebSocketUpgrade(req):
+		j.handleWs(w, req)
 	default:
-		http.Error(w, fmt.Sprintf("method %s not allowed", req.Method), http.StatusNotImplemented)
+		j.handleRequest(w, req)
 	}
 }
 
@@ -260,6 +457,28 @@ type EdgePath struct {
 	NodeID       string `json:"node_id"`
 	Port         int    `json:"port"`
 	InterfaceURL string `json:"interface_url"`
+	AppName      string `json:"app_name,omitempty"`
+	// Prefix is the part of the request path which is not sent to the webapp
+	Prefix string `json:"-"`
+}
+
+// ErrPathTraversal is returned for the interface paths with a dot-dot segment, they could leave the allowed paths
+var ErrPathTraversal = errors.New("invalid path: dot-dot segments are not allowed")
+
+// HasDotDotSegment returns true if a segment of the path is .., also once more percent-decoded,
+// since the webapp may decode the path again
+func HasDotDotSegment(interfaceURL string) bool {
+	for _, segment := range strings.FieldsFunc(interfaceURL, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if segment == ".." {
+			return true
+		}
+
+		if decoded, err := url.PathUnescape(segment); err == nil && decoded != segment && HasDotDotSegment(decoded) {
+			return true
+		}
+	}
+
+	return false
 }
 
 type TransparentForward struct {
@@ -267,7 +486,19 @@ type TransparentForward struct {
 	Payload  string   `json:"payload"`
 }
 
+// ParseEdgePath parses /<prefix>/<nodeId>/<port>/<path>, or /app/<appName>/<path> for the routing by app name
 func ParseEdgePath(req *http.Request) (*EdgePath, error) {
+	return parseEdgePath(req, AppPathPrefix)
+}
+
+// newEdgePathParser returns the parser of the edge paths routing by app name under the prefix, none if it is empty
+func newEdgePathParser(appPathPrefix string) func(*http.Request) (*EdgePath, error) {
+	return func(req *http.Request) (*EdgePath, error) {
+		return parseEdgePath(req, appPathPrefix)
+	}
+}
+
+func parseEdgePath(req *http.Request, appPathPrefix string) (*EdgePath, error) {
 	path := req.URL.Path
 	parts := strings.Split(path, "/")
 
@@ -277,6 +508,8 @@ func ParseEdgePath(req *http.Request) (*EdgePath, error) {
 			Port:         0,
 			InterfaceURL: "",
 		}, nil
+	} else if appPathPrefix != "" && parts[1] == appPathPrefix {
+		return parseAppPath(parts)
 	} else if len(parts) < 4 {
 		return nil, fmt.Errorf("invalid path format: expected at least 4 parts, got %d", len(parts))
 	}
@@ -300,13 +533,89 @@ func ParseEdgePath(req *http.Request) (*EdgePath, error) {
 		return nil, fmt.Errorf("failed to decode interfaceURL: %w", err)
 	}
 
+	if HasDotDotSegment(decodedInterfaceURL) {
+		return nil, ErrPathTraversal
+	}
+
 	return &EdgePath{
 		NodeID:       decodedNodeID,
 		Port:         decodedPort,
 		InterfaceURL: decodedInterfaceURL,
+		Prefix:       strings.Join(parts[:4], "/"),
 	}, nil
 }
 
+// parseAppPath parses /app/<appName>/<path>, the node and the port are resolved later by resolveAppNode
+func parseAppPath(parts []string) (*EdgePath, error) {
+	decodedAppName, err := url.QueryUnescape(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode appName: %w", err)
+	}
+
+	if decodedAppName == "" {
+		return nil, errors.New("invalid path format: empty appName")
+	}
+
+	decodedInterfaceURL, err := url.QueryUnescape(strings.Join(parts[3:], "/"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode interfaceURL: %w", err)
+	}
+
+	if HasDotDotSegment(decodedInterfaceURL) {
+		return nil, ErrPathTraversal
+	}
+
+	return &EdgePath{
+		AppName:      decodedAppName,
+		InterfaceURL: decodedInterfaceURL,
+		Prefix:       strings.Join(parts[:3], "/"),
+	}, nil
+}
+
+// resolveAppNode picks the node serving the request if the path is routed by app name,
+// the port of the app is set first, so the policy, the auth and the rate limit check the port reached
+func (j *TransparentProxy) resolveAppNode(pathInfo *EdgePath, identity string) error {
+	if pathInfo.AppName == "" {
+		return nil
+	}
+
+	port, err := j.appPort(pathInfo.AppName)
+	if err != nil {
+		return err
+	}
+	pathInfo.Port = port
+
+	appPeers := j.config.Store.GetAppPeers(pathInfo.AppName)
+	candidates := make([]string, 0, len(appPeers))
+	for nodeID := range appPeers {
+		candidates = append(candidates, nodeID)
+	}
+
+	// if all the nodes are denied, one is still picked so the policy check returns a 403
+	if allowed := j.allowedNodes(candidates, pathInfo, identity); len(allowed) > 0 {
+		candidates = allowed
+	}
+	candidates = j.balancer.filterBusy(j.breakers.filterOpen(candidates))
+
+	nodeID, err := j.balancer.pick(pathInfo.AppName, candidates)
+	if err != nil {
+		return err
+	}
+	pathInfo.NodeID = nodeID
+
+	return nil
+}
+
+// appPort returns the port reached when the app is routed by name, see Config.AppPorts
+func (j *TransparentProxy) appPort(appName string) (int, error) {
+	port, ok := j.config.AppPorts[appName]
+	if !ok {
+		return 0, fmt.Errorf("%w '%s'", errNoAppPort, appName)
+	}
+
+	return port, nil
+}
+
 func (j *TransparentProxy) handleRequest(w http.ResponseWriter, req *http.Request) {
 	defer req.Body.Close()
 
@@ -317,9 +626,7 @@ func (j *TransparentProxy) handleRequest(w http.ResponseWriter, req *http.Reques
 		return
 	}
 
-	j.logger.Info("handle", "NodeID", pathInfo.NodeID, "Port", pathInfo.Port, "InterfaceURL", pathInfo.InterfaceURL)
-
-	// TODO verify NodeID by whitelist
+	j.logger.Info("handleRequest", "RequestID", RequestID(req), "NodeID", pathInfo.NodeID, "Port", pathInfo.Port, "InterfaceURL", pathInfo.InterfaceURL)
 
 	if req.Method == "GET" && pathInfo.NodeID == "" {
 		data := &GetResponse{
@@ -339,89 +646,147 @@ func (j *TransparentProxy) handleRequest(w http.ResponseWriter, req *http.Reques
 		return
 	}
 
-	clientHost := j.config.Store.GetRelayHost()
-	// query node in PeerStore
-	appPeer := j.config.Store.GetAppPeer(pathInfo.NodeID)
-	if appPeer == nil {
-		http.Error(w, "Failed to find node", http.StatusServiceUnavailable)
+	retry, err := newRetryState(j.config.Retry, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 
 		return
 	}
 
-	//targetRelayInfo, err := peer.AddrInfoFromString(fmt.Sprintf("%s/p2p/%s/p2p-circuit/p2p/%s", j.config.Store.GetRelayHost().Addrs()[0].String(), j.config.Store.GetRelayHost().ID().String(), pathInfo.NodeID))
-	if appPeer.Relay != "" {
-		targetRelayInfo, err := peer.AddrInfoFromString(fmt.Sprintf("%s/p2p-circuit/p2p/%s", appPeer.Relay, pathInfo.NodeID))
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+	var resp *http.Response
+	for {
+		var status int
+		retry.attempts++
+		retry.tried[pathInfo.NodeID] = true
+
+		// the circuit of a failing node is open, the request fails fast without dialing it
+		allowed, retryAfter := j.breakers.allow(pathInfo.NodeID)
+		if allowed {
+			resp, status, err = j.forward(req, pathInfo, retry.body(req))
+			j.recordBreaker(req, pathInfo.NodeID, resp, err)
+		} else {
+			status, err = http.StatusServiceUnavailable, fmt.Errorf("circuit breaker of node %s is open", pathInfo.NodeID)
 		}
-		clientHost.Peerstore().AddAddrs(targetRelayInfo.ID, targetRelayInfo.Addrs, peerstore.RecentlyConnectedAddrTTL)
-	} else if appPeer.Addr != "" {
-		addrInfo, err := peer.AddrInfoFromString(fmt.Sprintf("%s/p2p/%s", appPeer.Addr, pathInfo.NodeID))
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		if err == nil {
+			break
+		}
+
+		j.logger.Warn("handleRequest", "NodeID", pathInfo.NodeID, "attempt", retry.attempts, "err", err.Error())
+
+		if !j.prepareRetry(req, pathInfo, retry, status != http.StatusBadGateway) {
+			retry.setAttemptsHeader(w)
+			if !allowed {
+				setRetryAfter(w, retryAfter)
+			}
+			http.Error(w, err.Error(), status)
+
 			return
 		}
-		clientHost.Peerstore().AddAddrs(addrInfo.ID, addrInfo.Addrs, peerstore.RecentlyConnectedAddrTTL)
-	} else {
-		http.Error(w, "Failed to find addr of node", http.StatusServiceUnavailable)
-		return
 	}
+	defer resp.Body.Close()
+	defer j.balancer.release(pathInfo.NodeID)
+
+	retry.setAttemptsHeader(w)
+	if err := j.writeVerifiedResponse(w, req, resp, pathInfo.NodeID); err != nil {
+		j.logger.Warn("handleRequest", "RequestID", RequestID(req), "err", err.Error())
+	}
+}
 
-	tr := &http.Transport{}
-	tr.RegisterProtocol("libp2p", p2phttp.NewTransport(clientHost, p2phttp.ProtocolOption(application.ProtoTagEcApp)))
-	client := &http.Client{Transport: tr}
+// recordBreaker counts the outcome of a forward in the circuit breaker of the node,
+// a request canceled by the client is not a failure of the node
+func (j *TransparentProxy) recordBreaker(req *http.Request, nodeID string, resp *http.Response, err error) {
+	switch {
+	case err != nil && req.Context().Err() != nil:
+		j.breakers.cancel(nodeID)
+	case err != nil:
+		j.breakers.record(nodeID, false)
+	default:
+		j.breakers.record(nodeID, !isNodeFailure(resp))
+	}
+}
 
-	targetURL := fmt.Sprintf("libp2p://%s%s", pathInfo.NodeID, TransparentForwardUrl)
-	request, err := http.NewRequest(req.Method, targetURL, req.Body)
+// forward sends the request to the edge node through the libp2p stream,
+// it returns the http status code to respond with on failure
+func (j *TransparentProxy) forward(req *http.Request, pathInfo *EdgePath, body io.Reader) (*http.Response, int, error) {
+	clientHost := j.config.Store.GetRelayHost()
+	if status, err := j.addAppPeerAddrs(clientHost, pathInfo.NodeID); err != nil {
+		return nil, status, err
+	}
+
+	client := j.clients.get(clientHost)
+
+	targetURL := fmt.Sprintf("http://%s%s", pathInfo.NodeID, TransparentForwardUrl)
+	if req.URL.RawQuery != "" {
+		targetURL += "?" + req.URL.RawQuery
+	}
+	request, err := http.NewRequestWithContext(req.Context(), req.Method, targetURL, body)
 	if err != nil {
-		http.Error(w, "Failed to create p2p request", http.StatusInternalServerError)
-		return
+		return nil, http.StatusInternalServerError, errors.New("Failed to create p2p request")
 	}
+	request.ContentLength = req.ContentLength
+	request.Trailer = req.Trailer
 	// forward headers
-	for key, values := range req.Header {
-		for _, value := range values {
-			request.Header.Add(key, value)
-		}
-	}
+	CopyHeader(request.Header, req.Header)
+	request.Header.Set("X-Forwarded-NodeID", pathInfo.NodeID)
+
+	// the span lasts until the body of the response is read from the stream
+	ctx, span := tracer.Start(req.Context(), "proxy.forward",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("edge.node_id", pathInfo.NodeID),
+			attribute.Int("edge.port", pathInfo.Port),
+			attribute.String("edge.interface", pathInfo.InterfaceURL),
+		),
+	)
+	request = request.WithContext(ctx)
+	InjectTraceContext(ctx, request.Header)
+
+	j.balancer.acquire(pathInfo.NodeID)
 
 	// do forward
+	start := time.Now()
 	resp, err := client.Do(request)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadGateway)
-	}
-	defer resp.Body.Close()
+		j.balancer.release(pathInfo.NodeID)
+		endSpan(span, err)
 
-	for key, value := range resp.Header {
-		if key != "Access-Control-Allow-Origin" {
-			w.Header().Set(key, value[0])
-		}
+		return nil, http.StatusBadGateway, err
 	}
-	w.WriteHeader(resp.StatusCode)
-	if resp.Header.Get("Content-Type") == "text/event-stream" {
-		reader := bufio.NewReader(resp.Body)
-		for {
-			line, err := reader.ReadBytes('\n')
-			if err != nil {
-				if err == io.EOF {
-					j.logger.Debug("handleRequest", "msg", "SSE stream closed by server")
-					return
-				}
-				j.logger.Warn("handleRequest", "err", fmt.Sprintf("Error reading SSE stream: %v\n", err))
-				return
-			}
+	j.balancer.observeLatency(pathInfo.NodeID, time.Since(start))
+	j.balancer.observeLoad(pathInfo.NodeID, resp.Header)
 
-			_, err = w.Write(line)
-			if err != nil {
-				j.logger.Warn("handleRequest", "err", fmt.Sprintf("Error writing to client: %v\n", err))
-				return
-			}
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	resp.Body = &spanBody{ReadCloser: resp.Body, span: span}
+
+	return resp, http.StatusOK, nil
+}
+
+// addAppPeerAddrs queries the node in PeerStore and adds its relay or direct address to the client host,
+// it returns the http status code to respond with on failure
+func (j *TransparentProxy) addAppPeerAddrs(clientHost host.Host, nodeID string) (int, error) {
+	appPeer := j.config.Store.GetAppPeer(nodeID)
+	if appPeer == nil {
+		return http.StatusServiceUnavailable, errors.New("Failed to find node")
+	}
 
-			w.(http.Flusher).Flush()
+	//targetRelayInfo, err := peer.AddrInfoFromString(fmt.Sprintf("%s/p2p/%s/p2p-circuit/p2p/%s", j.config.Store.GetRelayHost().Addrs()[0].String(), j.config.Store.GetRelayHost().ID().String(), pathInfo.NodeID))
+	if appPeer.Relay != "" {
+		targetRelayInfo, err := peer.AddrInfoFromString(fmt.Sprintf("%s/p2p-circuit/p2p/%s", appPeer.Relay, nodeID))
+		if err != nil {
+			return http.StatusInternalServerError, err
 		}
+		clientHost.Peerstore().AddAddrs(targetRelayInfo.ID, targetRelayInfo.Addrs, peerstore.RecentlyConnectedAddrTTL)
+	} else if appPeer.Addr != "" {
+		addrInfo, err := peer.AddrInfoFromString(fmt.Sprintf("%s/p2p/%s", appPeer.Addr, nodeID))
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		clientHost.Peerstore().AddAddrs(addrInfo.ID, addrInfo.Addrs, peerstore.RecentlyConnectedAddrTTL)
 	} else {
-		io.Copy(w, resp.Body)
+		return http.StatusServiceUnavailable, errors.New("Failed to find addr of node")
 	}
+
+	return http.StatusOK, nil
 }
 
 type GetResponse struct {
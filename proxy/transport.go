@@ -0,0 +1,237 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/EdgeMatrixChain/edge-matrix-core/core/application"
+	gostream "github.com/libp2p/go-libp2p-gostream"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	DefaultMaxConnsPerNode     = 64
+	DefaultMaxIdleConnsPerNode = 16
+	DefaultIdleConnTimeout     = 90 * time.Second
+)
+
+// TransportConfig defines the pool of libp2p streams to the edge nodes
+type TransportConfig struct {
+	// MaxConnsPerNode limits the number of streams to a node, requests wait for a free stream above it
+	MaxConnsPerNode int
+	// MaxIdleConnsPerNode is the number of idle streams kept open to a node
+	MaxIdleConnsPerNode int
+	// IdleConnTimeout is how long an idle stream is kept open
+	IdleConnTimeout time.Duration
+}
+
+func DefaultTransportConfig() *TransportConfig {
+	return &TransportConfig{
+		MaxConnsPerNode:     DefaultMaxConnsPerNode,
+		MaxIdleConnsPerNode: DefaultMaxIdleConnsPerNode,
+		IdleConnTimeout:     DefaultIdleConnTimeout,
+	}
+}
+
+// nodeSlots are the streams open to a node
+type nodeSlots struct {
+	slots chan struct{}
+	// users is the number of streams holding or waiting for a slot
+	users int
+}
+
+// nodeStreams limits the streams open to each node, the pooled http streams and the websocket streams
+// share the limit
+type nodeStreams struct {
+	max int
+
+	lock  sync.Mutex
+	nodes map[peer.ID]*nodeSlots
+}
+
+func newNodeStreams(max int) *nodeStreams {
+	return &nodeStreams{
+		max:   max,
+		nodes: make(map[peer.ID]*nodeSlots),
+	}
+}
+
+// acquire waits for a free stream to the node, the returned func releases it and can be called more than once
+func (s *nodeStreams) acquire(ctx context.Context, nodeID peer.ID) (func(), error) {
+	if s.max <= 0 {
+		return func() {}, nil
+	}
+
+	s.lock.Lock()
+	node, ok := s.nodes[nodeID]
+	if !ok {
+		node = &nodeSlots{slots: make(chan struct{}, s.max)}
+		s.nodes[nodeID] = node
+	}
+	node.users++
+	s.lock.Unlock()
+
+	select {
+	case node.slots <- struct{}{}:
+		return sync.OnceFunc(func() {
+			<-node.slots
+			s.leave(nodeID, node)
+		}), nil
+	case <-ctx.Done():
+		s.leave(nodeID, node)
+
+		return nil, ctx.Err()
+	}
+}
+
+// leave forgets the slots of the node once no stream uses them
+func (s *nodeStreams) leave(nodeID peer.ID, node *nodeSlots) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if node.users--; node.users == 0 {
+		delete(s.nodes, nodeID)
+	}
+}
+
+// streamConn releases the slot of its stream when it is closed
+type streamConn struct {
+	net.Conn
+	release func()
+}
+
+func (c *streamConn) Close() error {
+	defer c.release()
+
+	return c.Conn.Close()
+}
+
+// newP2PTransport returns an http transport whose connections are libp2p streams to the node
+// named by the host of the url, e.g. http://<nodeID>/transparent_forward.
+// The streams are kept alive and reused by the following requests to the same node.
+func newP2PTransport(clientHost host.Host, config *TransportConfig, streams *nodeStreams) *http.Transport {
+	return &http.Transport{
+		DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+			nodeID, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+
+			peerID, err := peer.Decode(nodeID)
+			if err != nil {
+				return nil, fmt.Errorf("invalid node id %s: %w", nodeID, err)
+			}
+
+			release, err := streams.acquire(ctx, peerID)
+			if err != nil {
+				return nil, err
+			}
+
+			conn, err := dialNode(ctx, clientHost, peerID)
+			if err != nil {
+				release()
+
+				return nil, err
+			}
+
+			return &streamConn{Conn: conn, release: release}, nil
+		},
+		MaxConnsPerHost:     config.MaxConnsPerNode,
+		MaxIdleConnsPerHost: config.MaxIdleConnsPerNode,
+		IdleConnTimeout:     config.IdleConnTimeout,
+		// the body is forwarded as sent by the node, without decompressing it
+		DisableCompression: true,
+	}
+}
+
+// dialNode opens a libp2p stream to the node in a span
+func dialNode(ctx context.Context, clientHost host.Host, nodeID peer.ID) (net.Conn, error) {
+	ctx, span := tracer.Start(ctx, "libp2p.dial",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("edge.node_id", nodeID.String()),
+			attribute.String("libp2p.protocol", string(application.ProtoTagEcApp)),
+		),
+	)
+
+	conn, err := gostream.Dial(ctx, clientHost, nodeID, application.ProtoTagEcApp)
+	endSpan(span, err)
+
+	return conn, err
+}
+
+// p2pClients holds one long-lived client per relay host
+type p2pClients struct {
+	config  *TransportConfig
+	streams *nodeStreams
+
+	lock    sync.Mutex
+	clients map[peer.ID]*http.Client
+}
+
+func newP2PClients(config *TransportConfig) *p2pClients {
+	if config == nil {
+		config = DefaultTransportConfig()
+	}
+
+	return &p2pClients{
+		config:  config,
+		streams: newNodeStreams(config.MaxConnsPerNode),
+		clients: make(map[peer.ID]*http.Client),
+	}
+}
+
+// get returns the client of the host, it is created on the first call
+func (c *p2pClients) get(clientHost host.Host) *http.Client {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	client, ok := c.clients[clientHost.ID()]
+	if !ok {
+		client = &http.Client{
+			Transport: newP2PTransport(clientHost, c.config, c.streams),
+			// redirects are passed back to the caller
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+		c.clients[clientHost.ID()] = client
+	}
+
+	return client
+}
+
+// dialStream opens a stream to the node outside of the pool, e.g. for a websocket session.
+// It counts against MaxConnsPerNode until it is closed.
+func (c *p2pClients) dialStream(ctx context.Context, clientHost host.Host, nodeID peer.ID) (net.Conn, error) {
+	release, err := c.streams.acquire(ctx, nodeID)
+	if err != nil {
+		return nil, fmt.Errorf("no free stream to node %s: %w", nodeID, err)
+	}
+
+	conn, err := dialNode(ctx, clientHost, nodeID)
+	if err != nil {
+		release()
+
+		return nil, err
+	}
+
+	return &streamConn{Conn: conn, release: release}, nil
+}
+
+// close closes the idle streams of all the clients
+func (c *p2pClients) close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, client := range c.clients {
+		client.CloseIdleConnections()
+	}
+}
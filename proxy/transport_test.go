@@ -0,0 +1,238 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/EdgeMatrixChain/edge-matrix-core/core/application"
+	"github.com/libp2p/go-libp2p"
+	gostream "github.com/libp2p/go-libp2p-gostream"
+	p2phttp "github.com/libp2p/go-libp2p-http"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingListener counts the accepted streams
+type countingListener struct {
+	net.Listener
+	accepted int32
+}
+
+func (l *countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		atomic.AddInt32(&l.accepted, 1)
+	}
+
+	return conn, err
+}
+
+func (l *countingListener) count() int {
+	return int(atomic.LoadInt32(&l.accepted))
+}
+
+func helloHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(`{"message":"hello"}`))
+}
+
+// newForwardHosts starts an edge host serving the handler over libp2p
+// and a relay host knowing its addresses
+func newForwardHosts(tb testing.TB, handler http.HandlerFunc) (host.Host, host.Host, *countingListener) {
+	tb.Helper()
+
+	edgeHost, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(tb, err)
+
+	relayHost, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(tb, err)
+
+	listener, err := gostream.Listen(edgeHost, application.ProtoTagEcApp)
+	require.NoError(tb, err)
+
+	counter := &countingListener{Listener: listener}
+
+	srv := &http.Server{Handler: handler}
+	go srv.Serve(counter) //nolint:errcheck
+
+	tb.Cleanup(func() {
+		srv.Close()
+		relayHost.Close()
+		edgeHost.Close()
+	})
+
+	relayHost.Peerstore().AddAddrs(edgeHost.ID(), edgeHost.Addrs(), time.Hour)
+
+	return edgeHost, relayHost, counter
+}
+
+func benchmarkForward(b *testing.B, url string, client func() *http.Client) {
+	b.Helper()
+	b.ReportAllocs()
+	b.SetParallelism(8)
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			resp, err := client().Get(url)
+			if err != nil {
+				b.Error(err)
+
+				return
+			}
+
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+	})
+}
+
+// newPerRequestClient returns the client handleRequest built for every forward before the streams were pooled,
+// it is the baseline of BenchmarkForward
+func newPerRequestClient(clientHost host.Host) *http.Client {
+	tr := &http.Transport{}
+	tr.RegisterProtocol("libp2p", p2phttp.NewTransport(clientHost, p2phttp.ProtocolOption(application.ProtoTagEcApp)))
+
+	return &http.Client{Transport: tr}
+}
+
+// BenchmarkForward compares the transport built for every request with the pooled transport reusing the streams.
+// Run it with: go test -run xxx -bench BenchmarkForward -benchtime 3s ./proxy/
+func BenchmarkForward(b *testing.B) {
+	edgeHost, relayHost, _ := newForwardHosts(b, helloHandler)
+	nodeID := edgeHost.ID().String()
+
+	b.Run("per-request", func(b *testing.B) {
+		benchmarkForward(b, fmt.Sprintf("libp2p://%s%s", nodeID, TransparentForwardUrl), func() *http.Client {
+			return newPerRequestClient(relayHost)
+		})
+	})
+
+	b.Run("pooled", func(b *testing.B) {
+		clients := newP2PClients(DefaultTransportConfig())
+		defer clients.close()
+
+		benchmarkForward(b, fmt.Sprintf("http://%s%s", nodeID, TransparentForwardUrl), func() *http.Client {
+			return clients.get(relayHost)
+		})
+	})
+}
+
+func getBody(t *testing.T, client *http.Client, url string) string {
+	t.Helper()
+
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return string(body)
+}
+
+func TestP2PTransportReusesStreams(t *testing.T) {
+	edgeHost, relayHost, counter := newForwardHosts(t, helloHandler)
+
+	clients := newP2PClients(nil)
+	defer clients.close()
+
+	url := fmt.Sprintf("http://%s%s", edgeHost.ID(), TransparentForwardUrl)
+
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, `{"message":"hello"}`, getBody(t, clients.get(relayHost), url))
+	}
+
+	// the sequential requests share one stream
+	assert.Equal(t, 1, counter.count())
+
+	// the client of a host is created once
+	assert.Same(t, clients.get(relayHost), clients.get(relayHost))
+}
+
+func TestP2PTransportMaxConnsPerNode(t *testing.T) {
+	var (
+		lock     sync.Mutex
+		inFlight int
+		peak     int
+	)
+
+	edgeHost, relayHost, counter := newForwardHosts(t, func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		inFlight++
+		if inFlight > peak {
+			peak = inFlight
+		}
+		lock.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		lock.Lock()
+		inFlight--
+		lock.Unlock()
+
+		helloHandler(w, r)
+	})
+
+	clients := newP2PClients(&TransportConfig{MaxConnsPerNode: 2, MaxIdleConnsPerNode: 2, IdleConnTimeout: time.Minute})
+	defer clients.close()
+
+	url := fmt.Sprintf("http://%s%s", edgeHost.ID(), TransparentForwardUrl)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			resp, err := clients.get(relayHost).Get(url)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}()
+	}
+
+	wg.Wait()
+
+	// the requests above the limit waited for a free stream
+	assert.Equal(t, 2, peak)
+	assert.LessOrEqual(t, counter.count(), 2)
+}
+
+func TestP2PTransportRedirectNotFollowed(t *testing.T) {
+	edgeHost, relayHost, _ := newForwardHosts(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/elsewhere", http.StatusFound)
+	})
+
+	clients := newP2PClients(nil)
+	defer clients.close()
+
+	resp, err := clients.get(relayHost).Get(fmt.Sprintf("http://%s%s", edgeHost.ID(), TransparentForwardUrl))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "/elsewhere", resp.Header.Get("Location"))
+}
+
+func TestP2PTransportInvalidNodeID(t *testing.T) {
+	_, relayHost, _ := newForwardHosts(t, helloHandler)
+
+	clients := newP2PClients(nil)
+	defer clients.close()
+
+	_, err := clients.get(relayHost).Get("http://not-a-node" + TransparentForwardUrl)
+	assert.ErrorContains(t, err, "invalid node id not-a-node")
+}
@@ -0,0 +1,333 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrUpstreamNotDeclared is returned for the requests to a port which is not declared in the service map
+var ErrUpstreamNotDeclared = errors.New("no upstream service is declared for the port")
+
+// ErrUpstreamPathDenied is returned for the requests to a path outside the prefixes of the service
+var ErrUpstreamPathDenied = errors.New("the path is not allowed by the upstream service")
+
+// UpstreamConfig declares a local service reachable through the transparent forward of the edge node
+type UpstreamConfig struct {
+	// Name identifies the service in the logs, the health checks and the metrics
+	Name string
+	// Port is the port requested by the relay, the requests routed by app name request the app port
+	Port int
+	// Target is the url of the service: http://host:port, https://host:port or unix:///path/to/socket
+	Target string
+	// CAFile holds the CAs of an https target, the system CAs are used if empty
+	CAFile string
+	// PathPrefixes are the allowed paths, all the paths are allowed if empty
+	PathPrefixes []string
+	// Timeout is the maximum time to wait for the response headers, unlimited if 0
+	Timeout time.Duration
+	// Headers are set on the requests to the service
+	Headers map[string]string
+	// HealthPath is checked by the health checker, the default health path is used if empty
+	HealthPath string
+	// Concurrency limits the requests served at once by the service, unlimited if nil
+	Concurrency *ConcurrencyConfig
+}
+
+// Upstream is a declared service and its client
+type Upstream struct {
+	config *UpstreamConfig
+	// base is the url of the requests, http://<name> for a unix socket
+	base       *url.URL
+	socketPath string
+	tlsConfig  *tls.Config
+	client     *http.Client
+	limiter    *ConcurrencyLimiter
+}
+
+// Upstreams is the service map of the edge node, by port.
+// Only the declared services are reachable through the transparent forward.
+// A service is addressed by name through the relay, which maps the app name to the port
+// with --app-route-port before it checks the policy, the keys and the rate limits on that port,
+// so the edge only resolves ports: a name resolved here would reach a port the relay didn't check.
+type Upstreams struct {
+	services map[int]*Upstream
+}
+
+// NewUpstreams checks the declared services and builds their clients
+func NewUpstreams(configs []*UpstreamConfig) (*Upstreams, error) {
+	u := &Upstreams{services: make(map[int]*Upstream, len(configs))}
+	names := make(map[string]bool, len(configs))
+
+	for _, config := range configs {
+		if config.Name == "" {
+			return nil, errors.New("an upstream service has no name")
+		}
+
+		if names[config.Name] {
+			return nil, fmt.Errorf("upstream service %s is already declared", config.Name)
+		}
+		names[config.Name] = true
+
+		if _, ok := u.services[config.Port]; ok {
+			return nil, fmt.Errorf("upstream service %s: port %d is already declared", config.Name, config.Port)
+		}
+
+		upstream, err := newUpstream(config)
+		if err != nil {
+			return nil, fmt.Errorf("upstream service %s: %w", config.Name, err)
+		}
+
+		u.services[config.Port] = upstream
+	}
+
+	return u, nil
+}
+
+func newUpstream(config *UpstreamConfig) (*Upstream, error) {
+	target, err := url.Parse(config.Target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target: %w", err)
+	}
+
+	upstream := &Upstream{config: config, base: target}
+	if config.Concurrency != nil && config.Concurrency.MaxConcurrent > 0 {
+		upstream.limiter = NewConcurrencyLimiter(config.Name, config.Concurrency)
+	}
+
+	switch target.Scheme {
+	case "http":
+	case "https":
+		upstream.tlsConfig = &tls.Config{ServerName: target.Hostname(), MinVersion: tls.VersionTLS12}
+
+		if config.CAFile != "" {
+			caPEM, err := os.ReadFile(config.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA file: %w", err)
+			}
+
+			rootCAs := x509.NewCertPool()
+			if !rootCAs.AppendCertsFromPEM(caPEM) {
+				return nil, fmt.Errorf("no certificate found in %s", config.CAFile)
+			}
+			upstream.tlsConfig.RootCAs = rootCAs
+		}
+	case "unix":
+		upstream.socketPath = target.Path
+		if upstream.socketPath == "" {
+			return nil, errors.New("the unix target has no socket path")
+		}
+		// the host of the requests is only used in the Host header
+		upstream.base = &url.URL{Scheme: "http", Host: config.Name}
+	default:
+		return nil, fmt.Errorf("unsupported target scheme '%s', expected http, https or unix", target.Scheme)
+	}
+
+	if target.Scheme != "unix" && target.Host == "" {
+		return nil, errors.New("the target has no host")
+	}
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           upstream.dialContext,
+		TLSClientConfig:       upstream.tlsConfig,
+		ResponseHeaderTimeout: config.Timeout,
+		MaxIdleConnsPerHost:   DefaultMaxIdleConnsPerNode,
+		IdleConnTimeout:       DefaultIdleConnTimeout,
+		// the body is forwarded as sent by the service, without decompressing it
+		DisableCompression: true,
+	}
+	if upstream.socketPath != "" {
+		transport.Proxy = nil
+	}
+
+	upstream.client = &http.Client{
+		Transport: transport,
+		// redirects are passed back to the caller
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return upstream, nil
+}
+
+func (u *Upstream) dialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{}
+	if u.socketPath != "" {
+		return dialer.DialContext(ctx, "unix", u.socketPath)
+	}
+
+	return dialer.DialContext(ctx, network, addr)
+}
+
+// Resolve returns the service of the port of the edge path, if the path is allowed
+func (u *Upstreams) Resolve(edgePath *EdgePath) (*Upstream, error) {
+	upstream, ok := u.services[edgePath.Port]
+	if !ok {
+		return nil, ErrUpstreamNotDeclared
+	}
+
+	if !upstream.allowPath("/" + edgePath.InterfaceURL) {
+		return nil, ErrUpstreamPathDenied
+	}
+
+	return upstream, nil
+}
+
+// sorted returns the declared services sorted by port
+func (u *Upstreams) sorted() []*Upstream {
+	ports := make([]int, 0, len(u.services))
+	for port := range u.services {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+
+	upstreams := make([]*Upstream, 0, len(ports))
+	for _, port := range ports {
+		upstreams = append(upstreams, u.services[port])
+	}
+
+	return upstreams
+}
+
+// Names returns the names of the declared services, sorted by port
+func (u *Upstreams) Names() []string {
+	names := make([]string, 0, len(u.services))
+	for _, upstream := range u.sorted() {
+		names = append(names, upstream.config.Name)
+	}
+
+	return names
+}
+
+// HealthTargets returns the health urls of the services, the services without a health path are not checked
+func (u *Upstreams) HealthTargets(defaultPath string) []*HealthTarget {
+	targets := make([]*HealthTarget, 0, len(u.services))
+	for _, upstream := range u.sorted() {
+		path := upstream.config.HealthPath
+		if path == "" {
+			path = defaultPath
+		}
+		if path == "" {
+			continue
+		}
+
+		targets = append(targets, &HealthTarget{
+			Name:   upstream.config.Name,
+			URL:    upstream.URL(path, ""),
+			Client: upstream.client,
+		})
+	}
+
+	return targets
+}
+
+// Limiters returns the concurrency limiters of the services which are limited
+func (u *Upstreams) Limiters() []*ConcurrencyLimiter {
+	limiters := make([]*ConcurrencyLimiter, 0, len(u.services))
+	for _, upstream := range u.sorted() {
+		if upstream.limiter != nil {
+			limiters = append(limiters, upstream.limiter)
+		}
+	}
+
+	return limiters
+}
+
+// Close closes the idle connections to the services
+func (u *Upstreams) Close() {
+	for _, upstream := range u.services {
+		upstream.client.CloseIdleConnections()
+	}
+}
+
+// allowPath returns true if the path is under one of the prefixes of the service,
+// the paths with a dot-dot segment are rejected so they can't leave a prefix
+func (u *Upstream) allowPath(path string) bool {
+	if HasDotDotSegment(path) {
+		return false
+	}
+
+	if len(u.config.PathPrefixes) == 0 {
+		return true
+	}
+
+	for _, prefix := range u.config.PathPrefixes {
+		prefix = "/" + strings.TrimPrefix(prefix, "/")
+		if path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Name returns the name of the service
+func (u *Upstream) Name() string {
+	return u.config.Name
+}
+
+// Limiter returns the concurrency limiter of the service, nil if it is unlimited
+func (u *Upstream) Limiter() *ConcurrencyLimiter {
+	return u.limiter
+}
+
+// Client returns the client of the service, it keeps the connections alive
+func (u *Upstream) Client() *http.Client {
+	return u.client
+}
+
+// URL returns the url of the path on the service, under the path of the target
+func (u *Upstream) URL(path string, rawQuery string) string {
+	target := *u.base
+	target.Path = strings.TrimSuffix(target.Path, "/") + "/" + strings.TrimPrefix(path, "/")
+	target.RawPath = ""
+	target.RawQuery = rawQuery
+
+	return target.String()
+}
+
+// SetHeaders sets the headers declared for the service on the request
+func (u *Upstream) SetHeaders(header http.Header) {
+	for key, value := range u.config.Headers {
+		header.Set(key, value)
+	}
+}
+
+// DialUpgrade opens a raw connection to the service for a websocket session,
+// the dial is canceled with the context or after upgradeDialTimeout
+func (u *Upstream) DialUpgrade(ctx context.Context) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, upgradeDialTimeout)
+	defer cancel()
+
+	if u.socketPath != "" {
+		return u.dialContext(ctx, "unix", "")
+	}
+
+	addr := u.base.Host
+	if u.base.Port() == "" {
+		if u.tlsConfig != nil {
+			addr = net.JoinHostPort(u.base.Hostname(), "443")
+		} else {
+			addr = net.JoinHostPort(u.base.Hostname(), "80")
+		}
+	}
+
+	if u.tlsConfig != nil {
+		dialer := &tls.Dialer{Config: u.tlsConfig}
+
+		return dialer.DialContext(ctx, "tcp", addr)
+	}
+
+	return u.dialContext(ctx, "tcp", addr)
+}
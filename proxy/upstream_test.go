@@ -0,0 +1,263 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUpstreamsErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		configs  []*UpstreamConfig
+		expected string
+	}{
+		{
+			name:     "no name",
+			configs:  []*UpstreamConfig{{Port: 9527, Target: "http://127.0.0.1:9527"}},
+			expected: "an upstream service has no name",
+		},
+		{
+			name: "duplicate port",
+			configs: []*UpstreamConfig{
+				{Name: "llm", Port: 9527, Target: "http://127.0.0.1:9527"},
+				{Name: "dashboard", Port: 9527, Target: "http://127.0.0.1:8080"},
+			},
+			expected: "upstream service dashboard: port 9527 is already declared",
+		},
+		{
+			name: "duplicate name",
+			configs: []*UpstreamConfig{
+				{Name: "llm", Port: 9527, Target: "http://127.0.0.1:9527"},
+				{Name: "llm", Port: 8080, Target: "http://127.0.0.1:8080"},
+			},
+			expected: "upstream service llm is already declared",
+		},
+		{
+			name:     "unsupported scheme",
+			configs:  []*UpstreamConfig{{Name: "llm", Port: 9527, Target: "ftp://127.0.0.1"}},
+			expected: "unsupported target scheme 'ftp'",
+		},
+		{
+			name:     "no host",
+			configs:  []*UpstreamConfig{{Name: "llm", Port: 9527, Target: "http:///v1"}},
+			expected: "the target has no host",
+		},
+		{
+			name:     "no socket path",
+			configs:  []*UpstreamConfig{{Name: "llm", Port: 9527, Target: "unix://"}},
+			expected: "the unix target has no socket path",
+		},
+		{
+			name:     "missing CA file",
+			configs:  []*UpstreamConfig{{Name: "llm", Port: 9527, Target: "https://127.0.0.1", CAFile: "missing.pem"}},
+			expected: "failed to read CA file",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewUpstreams(test.configs)
+			assert.ErrorContains(t, err, test.expected)
+		})
+	}
+}
+
+func TestUpstreamsResolve(t *testing.T) {
+	upstreams, err := NewUpstreams([]*UpstreamConfig{
+		{Name: "llm", Port: 9527, Target: "http://127.0.0.1:9527", PathPrefixes: []string{"/v1/", "health"}},
+		{Name: "dashboard", Port: 8080, Target: "http://127.0.0.1:8080"},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		port         int
+		interfaceURL string
+		expected     string
+		err          error
+	}{
+		{"declared path", 9527, "v1/chat/completions", "llm", nil},
+		{"prefix without trailing slash", 9527, "health", "llm", nil},
+		{"path under the prefix", 9527, "health/live", "llm", nil},
+		{"any path without prefixes", 8080, "admin/users", "dashboard", nil},
+		{"undeclared port", 22, "v1/chat/completions", "", ErrUpstreamNotDeclared},
+		{"routed by app name without a port", 0, "v1/chat/completions", "", ErrUpstreamNotDeclared},
+		{"path outside the prefixes", 9527, "admin", "", ErrUpstreamPathDenied},
+		{"prefix of a segment", 9527, "v1beta/models", "", ErrUpstreamPathDenied},
+		{"healthz is not health", 9527, "healthz", "", ErrUpstreamPathDenied},
+		{"dot-dot", 9527, "v1/../admin", "", ErrUpstreamPathDenied},
+		{"dot-dot without prefixes", 8080, "../etc/passwd", "", ErrUpstreamPathDenied},
+		{"encoded dot-dot", 9527, "v1/%2e%2e/admin", "", ErrUpstreamPathDenied},
+		{"double encoded dot-dot", 9527, "v1/%252e%252e/admin", "", ErrUpstreamPathDenied},
+		{"backslash dot-dot", 9527, `v1\..\admin`, "", ErrUpstreamPathDenied},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			upstream, err := upstreams.Resolve(&EdgePath{Port: test.port, InterfaceURL: test.interfaceURL})
+			if test.err != nil {
+				assert.ErrorIs(t, err, test.err)
+				assert.Nil(t, upstream)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, upstream.Name())
+		})
+	}
+}
+
+func TestUpstreamURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   string
+		path     string
+		rawQuery string
+		expected string
+	}{
+		{"root target", "http://127.0.0.1:9527", "v1/models", "", "http://127.0.0.1:9527/v1/models"},
+		{"query", "http://127.0.0.1:9527", "v1/models", "limit=10&after=a", "http://127.0.0.1:9527/v1/models?limit=10&after=a"},
+		{"under the target path", "https://127.0.0.1:8443/api", "v1/models", "", "https://127.0.0.1:8443/api/v1/models"},
+		{"target path with a trailing slash", "http://127.0.0.1:9527/api/", "/v1/models", "", "http://127.0.0.1:9527/api/v1/models"},
+		{"empty path", "http://127.0.0.1:9527/api", "", "", "http://127.0.0.1:9527/api/"},
+		{"target query is replaced", "http://127.0.0.1:9527/api?debug=1", "v1", "stream=true", "http://127.0.0.1:9527/api/v1?stream=true"},
+		{"escaped path", "http://127.0.0.1:9527", "files/a b", "", "http://127.0.0.1:9527/files/a%20b"},
+		{"unix socket", "unix:///run/llm/api.sock", "v1/models", "", "http://llm/v1/models"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			upstreams, err := NewUpstreams([]*UpstreamConfig{{Name: "llm", Port: 9527, Target: test.target}})
+			require.NoError(t, err)
+
+			upstream, err := upstreams.Resolve(&EdgePath{Port: 9527})
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, upstream.URL(test.path, test.rawQuery))
+		})
+	}
+}
+
+// getUpstream sends a GET to the path on the upstream service and returns the body of the response
+func getUpstream(t *testing.T, upstream *Upstream, path string) (string, error) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, upstream.URL(path, ""), nil)
+	require.NoError(t, err)
+	upstream.SetHeaders(req.Header)
+
+	resp, err := upstream.Client().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return string(body), nil
+}
+
+// echoHandler writes the path, the host and the token header of the request
+func echoHandler(w http.ResponseWriter, r *http.Request) {
+	_, _ = io.WriteString(w, r.URL.Path+" "+r.Host+" "+r.Header.Get("X-Token"))
+}
+
+func TestUpstreamTargets(t *testing.T) {
+	t.Run("unix socket", func(t *testing.T) {
+		socketPath := filepath.Join(t.TempDir(), "api.sock")
+
+		listener, err := net.Listen("unix", socketPath)
+		require.NoError(t, err)
+
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(echoHandler))
+		srv.Listener = listener
+		srv.Start()
+		defer srv.Close()
+
+		upstreams, err := NewUpstreams([]*UpstreamConfig{{
+			Name:    "llm",
+			Port:    9527,
+			Target:  "unix://" + socketPath,
+			Headers: map[string]string{"X-Token": "secret"},
+		}})
+		require.NoError(t, err)
+		defer upstreams.Close()
+
+		upstream, err := upstreams.Resolve(&EdgePath{Port: 9527})
+		require.NoError(t, err)
+
+		body, err := getUpstream(t, upstream, "v1/models")
+		require.NoError(t, err)
+		assert.Equal(t, "/v1/models llm secret", body)
+
+		conn, err := upstream.DialUpgrade(context.Background())
+		require.NoError(t, err)
+		conn.Close()
+	})
+
+	t.Run("https with a CA file", func(t *testing.T) {
+		ca := newTestCA(t)
+
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(echoHandler))
+		srv.TLS = &tls.Config{Certificates: []tls.Certificate{ca.issueKeyPair(t, &x509.Certificate{
+			Subject:     pkix.Name{CommonName: "dashboard"},
+			IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		})}}
+		srv.StartTLS()
+		defer srv.Close()
+
+		caFile := filepath.Join(t.TempDir(), "ca.pem")
+		require.NoError(t, os.WriteFile(caFile, ca.pem, 0600))
+
+		upstreams, err := NewUpstreams([]*UpstreamConfig{
+			{Name: "dashboard", Port: 8443, Target: srv.URL, CAFile: caFile},
+			{Name: "system-cas", Port: 8444, Target: srv.URL},
+		})
+		require.NoError(t, err)
+		defer upstreams.Close()
+
+		upstream, err := upstreams.Resolve(&EdgePath{Port: 8443})
+		require.NoError(t, err)
+
+		body, err := getUpstream(t, upstream, "home")
+		require.NoError(t, err)
+		assert.Equal(t, "/home "+srv.Listener.Addr().String()+" ", body)
+
+		conn, err := upstream.DialUpgrade(context.Background())
+		require.NoError(t, err)
+		conn.Close()
+
+		// the certificate of the service isn't trusted without its CA
+		upstream, err = upstreams.Resolve(&EdgePath{Port: 8444})
+		require.NoError(t, err)
+
+		_, err = getUpstream(t, upstream, "home")
+		assert.ErrorContains(t, err, "certificate signed by unknown authority")
+
+		_, err = upstream.DialUpgrade(context.Background())
+		assert.ErrorContains(t, err, "certificate signed by unknown authority")
+	})
+
+	t.Run("CA file without certificate", func(t *testing.T) {
+		caFile := filepath.Join(t.TempDir(), "ca.pem")
+		require.NoError(t, os.WriteFile(caFile, []byte("not a certificate"), 0600))
+
+		_, err := NewUpstreams([]*UpstreamConfig{{Name: "dashboard", Port: 8443, Target: "https://127.0.0.1:8443", CAFile: caFile}})
+		assert.ErrorContains(t, err, "no certificate found in "+caFile)
+	})
+}
@@ -0,0 +1,397 @@
+package proxy
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/go-hclog"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// usagePrefix is the prefix of the usage records, followed by their time and a sequence number
+var usagePrefix = []byte("usage/")
+
+const (
+	// usageQueueSize is the number of records waiting to be written
+	usageQueueSize = 4096
+	// usageQueueTimeout is how long a record waits for room in a full queue before it is dropped
+	usageQueueTimeout = 100 * time.Millisecond
+	// usageBatchSize is the maximum number of records written at once
+	usageBatchSize = 256
+	// usagePruneInterval is the interval between two removals of the records past the retention
+	usagePruneInterval = time.Hour
+)
+
+// UsageConfig defines the usage records of the transparent proxy
+type UsageConfig struct {
+	// DBPath is the directory of the usage records
+	DBPath string
+	// Retention is how long the records are kept, 0 keeps them forever
+	Retention time.Duration
+}
+
+// UsageRecord is the usage of a request forwarded to a node, it's the unit of the billing
+type UsageRecord struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id"`
+	// Key is the principal of the API key or the client certificate, the API key itself is never stored
+	Key              string  `json:"key"`
+	NodeID           string  `json:"node_id"`
+	Port             int     `json:"port"`
+	Path             string  `json:"path"`
+	Status           int     `json:"status"`
+	BytesIn          int64   `json:"bytes_in"`
+	BytesOut         int64   `json:"bytes_out"`
+	DurationMs       float64 `json:"duration_ms"`
+	TTFBMs           float64 `json:"ttfb_ms"`
+	PromptTokens     int64   `json:"prompt_tokens,omitempty"`
+	CompletionTokens int64   `json:"completion_tokens,omitempty"`
+}
+
+// UsageQuery selects and groups the usage records of a report
+type UsageQuery struct {
+	From time.Time
+	To   time.Time
+	// Window groups the records by period, 0 groups all the records of the range together
+	Window time.Duration
+	// Key and NodeID filter the records if set
+	Key    string
+	NodeID string
+}
+
+// UsageAggregate is the usage of a key on a node during a window
+type UsageAggregate struct {
+	WindowStart      time.Time
+	Key              string
+	NodeID           string
+	Requests         int64
+	Errors           int64
+	BytesIn          int64
+	BytesOut         int64
+	DurationMs       float64
+	TTFBMs           float64
+	PromptTokens     int64
+	CompletionTokens int64
+}
+
+// UsageMeter writes a usage record for every request forwarded to a node, in an append-only db
+type UsageMeter struct {
+	logger    hclog.Logger
+	db        *leveldb.DB
+	seq       uint64
+	retention time.Duration
+
+	records chan *UsageRecord
+	closeCh chan struct{}
+	wg      sync.WaitGroup
+}
+
+func NewUsageMeter(logger hclog.Logger, config *UsageConfig) (*UsageMeter, error) {
+	db, err := leveldb.OpenFile(config.DBPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open usage db: %w", err)
+	}
+
+	m := &UsageMeter{
+		logger:    logger.Named("usage"),
+		db:        db,
+		retention: config.Retention,
+		records:   make(chan *UsageRecord, usageQueueSize),
+		closeCh:   make(chan struct{}),
+	}
+
+	m.wg.Add(1)
+	go m.run()
+
+	return m, nil
+}
+
+// Close writes the pending records and closes the db
+func (m *UsageMeter) Close() {
+	if m == nil {
+		return
+	}
+
+	close(m.closeCh)
+	m.wg.Wait()
+
+	if err := m.db.Close(); err != nil {
+		m.logger.Error("failed to close usage db", "err", err)
+	}
+}
+
+func (m *UsageMeter) run() {
+	defer m.wg.Done()
+
+	var pruneCh <-chan time.Time
+	if m.retention > 0 {
+		m.prune(time.Now())
+
+		ticker := time.NewTicker(usagePruneInterval)
+		defer ticker.Stop()
+		pruneCh = ticker.C
+	}
+
+	for {
+		select {
+		case record := <-m.records:
+			m.write(record)
+		case now := <-pruneCh:
+			m.prune(now)
+		case <-m.closeCh:
+			for {
+				select {
+				case record := <-m.records:
+					m.write(record)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// write appends the record and the other queued records in a single batch
+func (m *UsageMeter) write(record *UsageRecord) {
+	batch := new(leveldb.Batch)
+	m.put(batch, record)
+
+collect:
+	for batch.Len() < usageBatchSize {
+		select {
+		case next := <-m.records:
+			m.put(batch, next)
+		default:
+			break collect
+		}
+	}
+
+	if err := m.db.Write(batch, nil); err != nil {
+		m.logger.Error("failed to write usage records", "records", batch.Len(), "err", err)
+	}
+}
+
+func (m *UsageMeter) put(batch *leveldb.Batch, record *UsageRecord) {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+
+	batch.Put(usageKey(record.Time, atomic.AddUint64(&m.seq, 1)), value)
+}
+
+// prune deletes the records older than the retention
+func (m *UsageMeter) prune(now time.Time) {
+	iter := m.db.NewIterator(&util.Range{Start: usagePrefix, Limit: usageKey(now.Add(-m.retention), 0)}, nil)
+	defer iter.Release()
+
+	pruned := 0
+	batch := new(leveldb.Batch)
+
+	for iter.Next() {
+		batch.Delete(append([]byte{}, iter.Key()...))
+
+		if batch.Len() >= usageBatchSize {
+			if err := m.db.Write(batch, nil); err != nil {
+				m.logger.Error("failed to prune usage records", "err", err)
+
+				return
+			}
+			pruned += batch.Len()
+			batch.Reset()
+		}
+	}
+
+	if err := m.db.Write(batch, nil); err != nil {
+		m.logger.Error("failed to prune usage records", "err", err)
+
+		return
+	}
+	pruned += batch.Len()
+
+	if pruned > 0 {
+		m.logger.Info("usage records pruned", "records", pruned, "retention", m.retention)
+	}
+}
+
+// usageKey orders the records by time, the sequence number keeps the records of the same nanosecond
+func usageKey(t time.Time, seq uint64) []byte {
+	key := make([]byte, len(usagePrefix)+16)
+	copy(key, usagePrefix)
+	binary.BigEndian.PutUint64(key[len(usagePrefix):], uint64(t.UnixNano()))
+	binary.BigEndian.PutUint64(key[len(usagePrefix)+8:], seq)
+
+	return key
+}
+
+// record queues the record. When the queue is full, it waits briefly for the writer,
+// then the record is dropped and logged, so the requests are not held by a stalled db.
+func (m *UsageMeter) record(record *UsageRecord) {
+	select {
+	case <-m.closeCh:
+		return
+	default:
+	}
+
+	select {
+	case m.records <- record:
+		return
+	default:
+	}
+
+	timer := time.NewTimer(usageQueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case m.records <- record:
+	case <-m.closeCh:
+	case <-timer.C:
+		metrics.IncrCounter([]string{proxyMetrics, "usage_dropped"}, 1)
+		m.logger.Warn("usage record dropped, the queue is full", "RequestID", record.RequestID,
+			"key", record.Key, "NodeID", record.NodeID, "status", record.Status)
+	}
+}
+
+// Handler writes the usage record of the requests routed to a node.
+// It is safe to call on a nil UsageMeter, nothing is recorded.
+func (m *UsageMeter) Handler(next http.Handler) http.Handler {
+	if m == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record := &UsageRecord{
+			Time:      time.Now().UTC(),
+			RequestID: RequestID(r),
+			Path:      r.URL.Path,
+		}
+		recorder := &responseRecorder{ResponseWriter: w}
+		body := &countingBody{ReadCloser: r.Body}
+		r.Body = body
+
+		next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), "Usage", record)))
+
+		// the requests which were not routed to a node are not billed
+		if record.NodeID == "" || IsPreflightRequest(r) {
+			return
+		}
+
+		record.Status = recorder.status
+		if record.Status == 0 {
+			record.Status = http.StatusOK
+		}
+		record.BytesIn = body.bytes
+		record.BytesOut = recorder.bytes
+		record.DurationMs = float64(time.Since(record.Time).Microseconds()) / 1000
+		if !recorder.firstByte.IsZero() {
+			record.TTFBMs = float64(recorder.firstByte.Sub(record.Time).Microseconds()) / 1000
+		}
+
+		m.record(record)
+	})
+}
+
+// setUsageTarget records the target node of the request in its usage record
+func setUsageTarget(r *http.Request, nodeID string, port int) {
+	if record, ok := r.Context().Value("Usage").(*UsageRecord); ok {
+		record.NodeID = nodeID
+		record.Port = port
+	}
+}
+
+// setUsagePrincipal records the principal billed for the request in its usage record
+func setUsagePrincipal(r *http.Request, principal string) {
+	if record, ok := r.Context().Value("Usage").(*UsageRecord); ok {
+		record.Key = principal
+	}
+}
+
+// setUsageTokens records the tokens reported by an OpenAI-compatible webapp in the usage record
+func setUsageTokens(r *http.Request, usage *OpenAIUsage) {
+	if record, ok := r.Context().Value("Usage").(*UsageRecord); ok {
+		record.PromptTokens = usage.PromptTokens
+		record.CompletionTokens = usage.CompletionTokens
+	}
+}
+
+// Report aggregates the usage records of the query by window, key and node
+func (m *UsageMeter) Report(query *UsageQuery) ([]*UsageAggregate, error) {
+	iterRange := util.BytesPrefix(usagePrefix)
+	if !query.From.IsZero() {
+		iterRange.Start = usageKey(query.From, 0)
+	}
+	if !query.To.IsZero() {
+		iterRange.Limit = usageKey(query.To, 0)
+	}
+
+	iter := m.db.NewIterator(iterRange, nil)
+	defer iter.Release()
+
+	aggregates := make(map[string]*UsageAggregate)
+
+	for iter.Next() {
+		record := &UsageRecord{}
+		if err := json.Unmarshal(iter.Value(), record); err != nil {
+			continue
+		}
+
+		if (query.Key != "" && record.Key != query.Key) || (query.NodeID != "" && record.NodeID != query.NodeID) {
+			continue
+		}
+
+		windowStart := query.From
+		if query.Window > 0 {
+			windowStart = record.Time.Truncate(query.Window)
+		}
+
+		id := fmt.Sprintf("%d/%s/%s", windowStart.UnixNano(), record.Key, record.NodeID)
+		aggregate, ok := aggregates[id]
+		if !ok {
+			aggregate = &UsageAggregate{WindowStart: windowStart, Key: record.Key, NodeID: record.NodeID}
+			aggregates[id] = aggregate
+		}
+
+		aggregate.Requests++
+		if record.Status >= http.StatusInternalServerError {
+			aggregate.Errors++
+		}
+		aggregate.BytesIn += record.BytesIn
+		aggregate.BytesOut += record.BytesOut
+		aggregate.DurationMs += record.DurationMs
+		aggregate.TTFBMs += record.TTFBMs
+		aggregate.PromptTokens += record.PromptTokens
+		aggregate.CompletionTokens += record.CompletionTokens
+	}
+
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to read usage records: %w", err)
+	}
+
+	report := make([]*UsageAggregate, 0, len(aggregates))
+	for _, aggregate := range aggregates {
+		report = append(report, aggregate)
+	}
+
+	sort.Slice(report, func(i, k int) bool {
+		a, b := report[i], report[k]
+		if !a.WindowStart.Equal(b.WindowStart) {
+			return a.WindowStart.Before(b.WindowStart)
+		}
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+
+		return a.NodeID < b.NodeID
+	})
+
+	return report, nil
+}
@@ -0,0 +1,180 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestUsageMeter(t *testing.T, retention time.Duration) *UsageMeter {
+	t.Helper()
+
+	m, err := NewUsageMeter(hclog.NewNullLogger(), &UsageConfig{DBPath: t.TempDir(), Retention: retention})
+	require.NoError(t, err)
+	t.Cleanup(m.Close)
+
+	return m
+}
+
+func TestUsageReport(t *testing.T) {
+	m := newTestUsageMeter(t, 0)
+
+	day := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	records := []*UsageRecord{
+		{Time: day.Add(time.Hour), Key: "key:a", NodeID: "node-1", Status: 200, BytesIn: 10, BytesOut: 100, DurationMs: 5, TTFBMs: 1, PromptTokens: 3, CompletionTokens: 7},
+		{Time: day.Add(2 * time.Hour), Key: "key:a", NodeID: "node-1", Status: 502, BytesIn: 20, BytesOut: 200, DurationMs: 15, TTFBMs: 2},
+		{Time: day.Add(3 * time.Hour), Key: "key:a", NodeID: "node-2", Status: 200, BytesOut: 50},
+		{Time: day.Add(25 * time.Hour), Key: "key:b", NodeID: "node-1", Status: 200, BytesOut: 30},
+		// outside of the range
+		{Time: day.Add(-time.Hour), Key: "key:a", NodeID: "node-1", Status: 200},
+		{Time: day.Add(48 * time.Hour), Key: "key:a", NodeID: "node-1", Status: 200},
+	}
+	for _, record := range records {
+		m.write(record)
+	}
+
+	report, err := m.Report(&UsageQuery{From: day, To: day.Add(48 * time.Hour)})
+	require.NoError(t, err)
+	require.Len(t, report, 3)
+
+	// all the range is a single window, sorted by key and node
+	assert.Equal(t, &UsageAggregate{
+		WindowStart: day, Key: "key:a", NodeID: "node-1",
+		Requests: 2, Errors: 1, BytesIn: 30, BytesOut: 300, DurationMs: 20, TTFBMs: 3, PromptTokens: 3, CompletionTokens: 7,
+	}, report[0])
+	assert.Equal(t, "node-2", report[1].NodeID)
+	assert.Equal(t, "key:b", report[2].Key)
+
+	// by day
+	report, err = m.Report(&UsageQuery{From: day, To: day.Add(48 * time.Hour), Window: 24 * time.Hour})
+	require.NoError(t, err)
+	require.Len(t, report, 3)
+	assert.Equal(t, day, report[0].WindowStart)
+	assert.Equal(t, day, report[1].WindowStart)
+	assert.Equal(t, day.Add(24*time.Hour), report[2].WindowStart)
+
+	// filtered
+	report, err = m.Report(&UsageQuery{From: day, To: day.Add(48 * time.Hour), Key: "key:a", NodeID: "node-1"})
+	require.NoError(t, err)
+	require.Len(t, report, 1)
+	assert.Equal(t, int64(2), report[0].Requests)
+
+	// an open range
+	report, err = m.Report(&UsageQuery{NodeID: "node-1", Window: time.Hour})
+	require.NoError(t, err)
+	assert.Len(t, report, 5)
+}
+
+func TestUsageSameNanosecond(t *testing.T) {
+	m := newTestUsageMeter(t, 0)
+
+	now := time.Now().UTC()
+	m.write(&UsageRecord{Time: now, Key: "key:a", NodeID: "node-1"})
+	m.write(&UsageRecord{Time: now, Key: "key:a", NodeID: "node-1"})
+
+	report, err := m.Report(&UsageQuery{})
+	require.NoError(t, err)
+	require.Len(t, report, 1)
+	assert.Equal(t, int64(2), report[0].Requests)
+}
+
+func TestUsagePrune(t *testing.T) {
+	m := newTestUsageMeter(t, 24*time.Hour)
+
+	now := time.Now().UTC()
+	for i := 0; i < usageBatchSize+10; i++ {
+		m.write(&UsageRecord{Time: now.Add(-48 * time.Hour).Add(time.Duration(i)), Key: "key:old", NodeID: "node-1"})
+	}
+	m.write(&UsageRecord{Time: now.Add(-time.Hour), Key: "key:new", NodeID: "node-1"})
+
+	m.prune(now)
+
+	report, err := m.Report(&UsageQuery{})
+	require.NoError(t, err)
+	require.Len(t, report, 1)
+	assert.Equal(t, "key:new", report[0].Key)
+}
+
+func TestUsageHandler(t *testing.T) {
+	m := newTestUsageMeter(t, 0)
+
+	handler := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/routed" {
+			setUsageTarget(r, "node-1", 9527)
+			setUsagePrincipal(r, "key:a")
+		}
+
+		_, _ = io.Copy(io.Discard, r.Body)
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hello"))
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/routed", strings.NewReader("body")))
+	// a request which was not routed to a node is not recorded
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var report []*UsageAggregate
+	assert.Eventually(t, func() bool {
+		report, _ = m.Report(&UsageQuery{})
+
+		return len(report) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	require.Len(t, report, 1)
+	assert.Equal(t, "key:a", report[0].Key)
+	assert.Equal(t, "node-1", report[0].NodeID)
+	assert.Equal(t, int64(4), report[0].BytesIn)
+	assert.Equal(t, int64(5), report[0].BytesOut)
+}
+
+func TestUsageQueueFull(t *testing.T) {
+	// the writer isn't started, so the queue is never drained
+	m := &UsageMeter{
+		logger:  hclog.NewNullLogger(),
+		records: make(chan *UsageRecord, 1),
+		closeCh: make(chan struct{}),
+	}
+
+	m.record(&UsageRecord{RequestID: "1"})
+
+	start := time.Now()
+	m.record(&UsageRecord{RequestID: "2"})
+
+	// the record waited for the writer, then it was dropped
+	assert.GreaterOrEqual(t, time.Since(start), usageQueueTimeout)
+	assert.Len(t, m.records, 1)
+
+	// a record waiting for room is queued when the writer catches up
+	go func() {
+		time.Sleep(usageQueueTimeout / 4)
+		<-m.records
+	}()
+
+	m.record(&UsageRecord{RequestID: "3"})
+	require.Len(t, m.records, 1)
+	assert.Equal(t, "3", (<-m.records).RequestID)
+
+	// nothing is queued after close
+	close(m.closeCh)
+	m.record(&UsageRecord{RequestID: "4"})
+	assert.Empty(t, m.records)
+}
+
+func TestUsageNilMeter(t *testing.T) {
+	var m *UsageMeter
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := m.Handler(next)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil).WithContext(context.Background()))
+
+	m.Close()
+}
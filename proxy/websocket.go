@@ -0,0 +1,213 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+const EdgeWsUrl = "/edge_ws/"
+
+const (
+	// upgradeDialTimeout is the maximum time to open the connection of a websocket session
+	upgradeDialTimeout = 10 * time.Second
+	// upgradeHandshakeTimeout is the maximum time for the backend to answer the upgrade request
+	upgradeHandshakeTimeout = 30 * time.Second
+)
+
+var errHijackNotSupported = errors.New("response writer does not support hijacking")
+
+// IsWebSocketUpgrade returns true if the request asks to switch to the websocket protocol
+func IsWebSocketUpgrade(r *http.Request) bool {
+	return headerContainsToken(r.Header, "Connection", "upgrade") &&
+		strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// headerContainsToken checks if a comma separated header contains the token (case-insensitive)
+func headerContainsToken(header http.Header, name string, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, s := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// ServeUpgrade writes the upgrade request to the backend connection.
+// If the backend switches protocols, the client connection is hijacked and
+// bytes are copied in both directions until either side closes the connection,
+// otherwise the backend response is written back to the client as is.
+// handshakeDone, if not nil, is called once the backend answered the upgrade request.
+func ServeUpgrade(w http.ResponseWriter, req *http.Request, backend net.Conn, handshakeDone func()) error {
+	defer backend.Close()
+
+	// a backend which doesn't answer doesn't hold the handshake forever
+	_ = backend.SetDeadline(time.Now().Add(upgradeHandshakeTimeout))
+
+	if err := req.Write(backend); err != nil {
+		http.Error(w, "Failed to write upgrade request", http.StatusBadGateway)
+
+		return fmt.Errorf("failed to write upgrade request: %w", err)
+	}
+
+	backendReader := bufio.NewReader(backend)
+
+	resp, err := http.ReadResponse(backendReader, req)
+	if err != nil {
+		http.Error(w, "Failed to read upgrade response", http.StatusBadGateway)
+
+		return fmt.Errorf("failed to read upgrade response: %w", err)
+	}
+
+	if handshakeDone != nil {
+		handshakeDone()
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		defer resp.Body.Close()
+
+		for key, values := range resp.Header {
+			for _, value := range values {
+				w.Header().Add(key, value)
+			}
+		}
+		w.WriteHeader(resp.StatusCode)
+		_, err = io.Copy(w, resp.Body)
+
+		return err
+	}
+
+	// the session has no deadline once the protocol is switched
+	_ = backend.SetDeadline(time.Time{})
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, errHijackNotSupported.Error(), http.StatusInternalServerError)
+
+		return errHijackNotSupported
+	}
+
+	clientConn, clientBuf, err := hijacker.Hijack()
+	if err != nil {
+		return err
+	}
+	defer clientConn.Close()
+
+	resp.Body = nil
+	if err := resp.Write(clientConn); err != nil {
+		return fmt.Errorf("failed to write upgrade response: %w", err)
+	}
+
+	errCh := make(chan error, 2)
+
+	// clientBuf and backendReader may already hold bytes received after the handshake
+	go func() {
+		_, copyErr := io.Copy(backend, clientBuf)
+		errCh <- copyErr
+	}()
+	go func() {
+		_, copyErr := io.Copy(clientConn, backendReader)
+		errCh <- copyErr
+	}()
+
+	return <-errCh
+}
+
+// DialUpgradeBackend opens a raw connection to the http(s) server of the target url,
+// the dial is canceled with the context or after upgradeDialTimeout
+func DialUpgradeBackend(ctx context.Context, targetURL *url.URL) (net.Conn, error) {
+	addr := targetURL.Host
+	if targetURL.Port() == "" {
+		if targetURL.Scheme == "https" {
+			addr = net.JoinHostPort(targetURL.Hostname(), "443")
+		} else {
+			addr = net.JoinHostPort(targetURL.Hostname(), "80")
+		}
+	}
+
+	dialer := &net.Dialer{Timeout: upgradeDialTimeout}
+
+	if targetURL.Scheme == "https" {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: targetURL.Hostname()}}
+
+		return tlsDialer.DialContext(ctx, "tcp", addr)
+	}
+
+	return dialer.DialContext(ctx, "tcp", addr)
+}
+
+// handleWs tunnels a websocket session through the libp2p stream to the edge node
+func (j *TransparentProxy) handleWs(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	if !IsWebSocketUpgrade(req) {
+		http.Error(w, "websocket upgrade required", http.StatusBadRequest)
+
+		return
+	}
+
+	pathInfo, ok := req.Context().Value("EdgePath").(*EdgePath)
+	if !ok || pathInfo.NodeID == "" {
+		http.Error(w, "Invalid edge path", http.StatusBadRequest)
+
+		return
+	}
+
+	j.logger.Info("handleWs", "NodeID", pathInfo.NodeID, "Port", pathInfo.Port, "InterfaceURL", pathInfo.InterfaceURL)
+
+	clientHost := j.config.Store.GetRelayHost()
+	if status, err := j.addAppPeerAddrs(clientHost, pathInfo.NodeID); err != nil {
+		http.Error(w, err.Error(), status)
+
+		return
+	}
+
+	nodeID, err := peer.Decode(pathInfo.NodeID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	j.balancer.acquire(pathInfo.NodeID)
+	defer j.balancer.release(pathInfo.NodeID)
+
+	// the session waits at most upgradeDialTimeout for a free stream to the node
+	dialCtx, cancel := context.WithTimeout(req.Context(), upgradeDialTimeout)
+	defer cancel()
+
+	stream, err := j.clients.dialStream(dialCtx, clientHost, nodeID)
+	if err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, context.DeadlineExceeded) {
+			status = http.StatusServiceUnavailable
+		}
+		http.Error(w, err.Error(), status)
+
+		return
+	}
+
+	outReq := req.Clone(req.Context())
+	query := req.URL.Query()
+	query.Del("access_token")
+	outReq.URL = &url.URL{Path: TransparentForwardUrl, RawQuery: query.Encode()}
+	outReq.Host = pathInfo.NodeID
+	outReq.RequestURI = ""
+
+	if err := ServeUpgrade(w, outReq, stream, nil); err != nil {
+		j.logger.Warn("handleWs", "err", err.Error())
+	}
+}
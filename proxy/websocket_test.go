@@ -0,0 +1,176 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/EdgeMatrixChain/edge-matrix-core/core/application"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoUpgradeHandler switches the requests bearing "Bearer good" to an echo protocol
+func echoUpgradeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer good" || r.URL.Query().Has("access_token") {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+
+		return
+	}
+
+	conn, buf, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	_, _ = buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+	_ = buf.Flush()
+
+	_, _ = io.Copy(conn, buf)
+}
+
+// dialUpgrade writes a websocket handshake to the server and returns its response
+func dialUpgrade(t *testing.T, addr string, target string, header string) (net.Conn, *bufio.Reader, *http.Response) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	_, err = fmt.Fprintf(conn, "GET %s HTTP/1.1\r\nHost: %s\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n%s\r\n", target, addr, header)
+	require.NoError(t, err)
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+
+	return conn, reader, resp
+}
+
+// assertEcho checks that the session echoes a message
+func assertEcho(t *testing.T, conn net.Conn, reader *bufio.Reader, message string) {
+	t.Helper()
+
+	_, err := conn.Write([]byte(message))
+	require.NoError(t, err)
+
+	received := make([]byte, len(message))
+	_, err = io.ReadFull(reader, received)
+	require.NoError(t, err)
+	assert.Equal(t, message, string(received))
+}
+
+func TestServeUpgrade(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(echoUpgradeHandler))
+	defer backend.Close()
+
+	backendURL, err := url.Parse(backend.URL)
+	require.NoError(t, err)
+
+	var handshakes int32
+
+	front := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := DialUpgradeBackend(r.Context(), backendURL)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		outReq := r.Clone(r.Context())
+		outReq.URL = backendURL
+		outReq.RequestURI = ""
+
+		_ = ServeUpgrade(w, outReq, conn, func() { atomic.AddInt32(&handshakes, 1) })
+	}))
+	defer front.Close()
+
+	addr := front.Listener.Addr().String()
+
+	// the session is bridged once the backend switched protocols
+	conn, reader, resp := dialUpgrade(t, addr, "/chat", "Authorization: Bearer good\r\n")
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "websocket", resp.Header.Get("Upgrade"))
+	assertEcho(t, conn, reader, "ping")
+	assertEcho(t, conn, reader, "pong")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&handshakes))
+
+	// a refused handshake is passed back to the client
+	_, _, resp = dialUpgrade(t, addr, "/chat", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "Unauthorized\n", string(body))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&handshakes))
+}
+
+func TestDialUpgradeBackendCanceled(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = DialUpgradeBackend(ctx, &url.URL{Scheme: "http", Host: listener.Addr().String()})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestHandleWs(t *testing.T) {
+	edgeHost, relayHost, counter := newForwardHosts(t, echoUpgradeHandler)
+	nodeID := edgeHost.ID().String()
+
+	j := newTestProxy(&Config{Store: &testStore{
+		relayHost: relayHost,
+		appPeers:  map[string]*application.AppPeer{nodeID: {Addr: edgeHost.Addrs()[0].String()}},
+		keys:      map[string]bool{"good": true},
+	}})
+	j.clients = newP2PClients(&TransportConfig{MaxConnsPerNode: 1})
+
+	relay := httptest.NewServer(j.bearerMiddlewareFactory(ParseEdgePath)(http.HandlerFunc(j.handleWs)))
+	defer relay.Close()
+
+	addr := relay.Listener.Addr().String()
+	target := EdgeWsUrl + nodeID + "/9527/chat"
+
+	// a handshake without a valid bearer is refused at the relay
+	_, _, resp := dialUpgrade(t, addr, target+"?access_token=bad", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, 0, counter.count())
+
+	// the access token of a browser is checked by the relay and not sent to the node
+	conn, reader, resp := dialUpgrade(t, addr, target+"?access_token=good", "")
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assertEcho(t, conn, reader, "ping")
+
+	// the second session waits for the stream of the first one
+	second, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer second.Close()
+
+	_, err = fmt.Fprintf(second, "GET %s HTTP/1.1\r\nHost: %s\r\nConnection: Upgrade\r\nUpgrade: websocket\r\nAuthorization: Bearer good\r\n\r\n", target, addr)
+	require.NoError(t, err)
+
+	secondReader := bufio.NewReader(second)
+	require.NoError(t, second.SetReadDeadline(time.Now().Add(200*time.Millisecond)))
+	_, err = secondReader.Peek(1)
+	require.Error(t, err)
+	assert.Equal(t, 1, counter.count())
+
+	conn.Close()
+
+	require.NoError(t, second.SetReadDeadline(time.Now().Add(5*time.Second)))
+	resp, err = http.ReadResponse(secondReader, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assertEcho(t, second, secondReader, "pong")
+	assert.Equal(t, 2, counter.count())
+}
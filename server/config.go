@@ -1,9 +1,13 @@
 package server
 
 import (
+	"github.com/EdgeMatrixChain/edge-matrix-computing/agent"
 	"github.com/EdgeMatrixChain/edge-matrix-computing/config"
+	"github.com/EdgeMatrixChain/edge-matrix-computing/proxy"
+	"github.com/EdgeMatrixChain/edge-matrix-computing/supervisor"
 	"github.com/EdgeMatrixChain/edge-matrix-core/core/network"
 	"net"
+	"time"
 
 	"github.com/hashicorp/go-hclog"
 
@@ -29,6 +33,7 @@ type Config struct {
 	MaxSlots           uint64
 
 	Telemetry   *Telemetry
+	Tracing     *Tracing
 	EdgeNetwork *network.Config
 
 	DataDir string
@@ -52,6 +57,32 @@ type Config struct {
 	AppNoAuth   bool
 	AppNoAgent  bool
 	AuthUrl     string
+	AuthCache   *agent.AuthCacheConfig
+
+	AuthBackend  string
+	AuthKeysFile string
+	AuthJWT      *agent.JWTConfig
+
+	AccessLog *proxy.AccessLogConfig
+
+	// Upstreams is the service map of the transparent forward, any port of the app host is reachable if empty
+	Upstreams []*proxy.UpstreamConfig
+	// Health defines the health checks of the upstream services, disabled if no health path is set
+	Health *proxy.HealthConfig
+	// Concurrency limits the requests to the app when no upstream service is declared, unlimited if nil
+	Concurrency *proxy.ConcurrencyConfig
+	// Apps are the local app processes launched and supervised by the edge node
+	Apps []*supervisor.ProcessConfig
+}
+
+// Tracing holds the config details for the OpenTelemetry spans
+type Tracing struct {
+	// Exporter is otlp or stdout, the spans are not exported if empty
+	Exporter string
+	// Endpoint is the host:port of the OTLP/HTTP collector, localhost:4318 if empty
+	Endpoint    string
+	Insecure    bool
+	SampleRatio float64
 }
 
 // Telemetry holds the config details for metric services
@@ -70,4 +101,19 @@ type JSONRPC struct {
 type TransparentProxyConfig struct {
 	ProxyAddr                *net.TCPAddr
 	AccessControlAllowOrigin []string
+	BalancePolicy            proxy.BalancePolicy
+	AppPathPrefix            string
+	AppPorts                 map[string]int
+	Retry                    *proxy.RetryConfig
+	PolicyFile               string
+	HostDomain               string
+	RateLimit                *proxy.RateLimitConfig
+	TLS                      *proxy.TLSConfig
+	Transport                *proxy.TransportConfig
+	StreamContentTypes       []string
+	OpenAI                   *proxy.OpenAIConfig
+	Breaker                  *proxy.BreakerConfig
+	UsageMetering            bool
+	UsageRetention           time.Duration
+	Jobs                     *proxy.JobsConfig
 }
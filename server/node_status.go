@@ -0,0 +1,187 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/EdgeMatrixChain/edge-matrix-computing/proxy"
+	"github.com/EdgeMatrixChain/edge-matrix-computing/supervisor"
+	"github.com/EdgeMatrixChain/edge-matrix-computing/versioning"
+	"github.com/EdgeMatrixChain/edge-matrix-core/core/application"
+)
+
+const (
+	procLoadAvgPath = "/proc/loadavg"
+	procMemInfoPath = "/proc/meminfo"
+)
+
+// nodeStatus is the signed response of the /alive handler of the edge node
+type nodeStatus struct {
+	Time      string            `json:"time"`
+	Version   string            `json:"version"`
+	StartedAt time.Time         `json:"started_at"`
+	Uptime    int64             `json:"uptime"`
+	Mode      RunningModeType   `json:"mode"`
+	AppName   string            `json:"app_name"`
+	AppOrigin string            `json:"app_origin"`
+	State     proxy.HealthState `json:"state"`
+	// Upstreams is the health of the checked upstream services
+	Upstreams []proxy.UpstreamHealth `json:"upstreams,omitempty"`
+	// InFlight is the number of requests forwarded to the upstream services, Queued the number waiting for a slot
+	InFlight int64                      `json:"in_flight"`
+	Queued   int                        `json:"queued"`
+	Apps     []supervisor.ProcessStatus `json:"apps,omitempty"`
+	Host     *hostResources             `json:"host"`
+}
+
+// hostResources are the resources of the host of the edge node, the values which can't be read are 0
+type hostResources struct {
+	CPUs int `json:"cpus"`
+	// LoadAvg is the load average over 1, 5 and 15 minutes
+	LoadAvg      []float64 `json:"load_avg,omitempty"`
+	MemTotal     uint64    `json:"mem_total"`
+	MemAvailable uint64    `json:"mem_available"`
+	// DiskTotal and DiskFree are the size and free space of the filesystem of the data dir
+	DiskTotal uint64 `json:"disk_total"`
+	DiskFree  uint64 `json:"disk_free"`
+}
+
+// nodeStatus returns the status of the edge node
+func (s *Server) nodeStatus() *nodeStatus {
+	now := time.Now()
+
+	return &nodeStatus{
+		Time:      now.String(),
+		Version:   versioning.Version,
+		StartedAt: s.startedAt.UTC(),
+		Uptime:    int64(now.Sub(s.startedAt).Seconds()),
+		Mode:      s.runningMode,
+		AppName:   s.config.AppName,
+		AppOrigin: s.getBoundAppOrigin(),
+		State:     s.health.State(),
+		Upstreams: s.health.Status(),
+		InFlight:  atomic.LoadInt64(&s.inFlight),
+		Queued:    s.queued(),
+		Apps:      s.supervisor.Status(),
+		Host:      readHostResources(s.config.DataDir),
+	}
+}
+
+// aliveHandler returns the handler of /alive, the status is written by writeSigned so it is signed by the node
+func (s *Server) aliveHandler(writeSigned func(w http.ResponseWriter, data []byte)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		resp, _ := json.Marshal(s.nodeStatus())
+		writeSigned(w, resp)
+	}
+}
+
+// queued returns the number of requests waiting in the queues of the upstream services
+func (s *Server) queued() int {
+	if s.upstreams == nil {
+		_, queued := s.appLimiter.InFlight()
+
+		return queued
+	}
+
+	queued := 0
+	for _, limiter := range s.upstreams.Limiters() {
+		_, limiterQueued := limiter.InFlight()
+		queued += limiterQueued
+	}
+
+	return queued
+}
+
+// setBoundAppOrigin sets the app origin bound by the app agent on the endpoint, and keeps it for the status
+func (s *Server) setBoundAppOrigin(endpoint *application.Endpoint, appOrigin string) {
+	endpoint.SetAppOrigin(appOrigin)
+	s.appOrigin.Store(appOrigin)
+}
+
+func (s *Server) getBoundAppOrigin() string {
+	appOrigin, _ := s.appOrigin.Load().(string)
+
+	return appOrigin
+}
+
+func readHostResources(dataDir string) *hostResources {
+	resources := &hostResources{
+		CPUs:    runtime.NumCPU(),
+		LoadAvg: readLoadAvg(procLoadAvgPath),
+	}
+	resources.MemTotal, resources.MemAvailable = readMemInfo(procMemInfoPath)
+	resources.DiskTotal, resources.DiskFree = diskUsage(dataDir)
+
+	return resources
+}
+
+// readLoadAvg returns the load averages of the file in the format of /proc/loadavg, nil if it can't be read
+func readLoadAvg(path string) []float64 {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) < 3 {
+		return nil
+	}
+
+	loadAvg := make([]float64, 0, 3)
+	for _, field := range fields[:3] {
+		load, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil
+		}
+		loadAvg = append(loadAvg, load)
+	}
+
+	return loadAvg
+}
+
+// readMemInfo returns the total and available memory in bytes from the file in the format of /proc/meminfo,
+// the values which can't be read are 0
+func readMemInfo(path string) (uint64, uint64) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0
+	}
+	defer file.Close()
+
+	var total, available uint64
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// the lines are "MemTotal:       16318164 kB"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		if len(fields) > 2 && fields[2] == "kB" {
+			value *= 1024
+		}
+
+		switch fields[0] {
+		case "MemTotal:":
+			total = value
+		case "MemAvailable:":
+			available = value
+		}
+	}
+
+	return total, available
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadLoadAvg(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		expected []float64
+	}{
+		{"loadavg", "loadavg", []float64{0.43, 0.31, 0.34}},
+		{"less than 3 averages", "loadavg_short", nil},
+		{"malformed average", "loadavg_malformed", nil},
+		{"missing file", "missing", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, readLoadAvg(filepath.Join("testdata", test.file)))
+		})
+	}
+}
+
+func TestReadMemInfo(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		total     uint64
+		available uint64
+	}{
+		{"meminfo in kB", "meminfo", 6147400 * 1024, 5417704 * 1024},
+		{"meminfo in bytes", "meminfo_bytes", 6147400, 5417704},
+		// the malformed lines are skipped
+		{"malformed lines", "meminfo_malformed", 6147400 * 1024, 0},
+		{"missing file", "missing", 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			total, available := readMemInfo(filepath.Join("testdata", test.file))
+			assert.Equal(t, test.total, total)
+			assert.Equal(t, test.available, available)
+		})
+	}
+}
+
+func TestAliveHandlerSigned(t *testing.T) {
+	s := &Server{
+		logger:      hclog.NewNullLogger(),
+		config:      &Config{AppName: "llm", DataDir: t.TempDir()},
+		startedAt:   time.Now().Add(-time.Minute),
+		runningMode: RunningModeEdge,
+	}
+	s.appOrigin.Store("https://llm.example.com")
+
+	var signed [][]byte
+	writeSigned := func(w http.ResponseWriter, data []byte) {
+		signed = append(signed, data)
+		_, _ = w.Write(data)
+	}
+
+	w := httptest.NewRecorder()
+	s.aliveHandler(writeSigned).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/alive", nil))
+
+	// the whole body is the status written by the signer
+	require.Len(t, signed, 1)
+	assert.Equal(t, signed[0], w.Body.Bytes())
+
+	var status nodeStatus
+	require.NoError(t, json.Unmarshal(signed[0], &status))
+	assert.Equal(t, "llm", status.AppName)
+	assert.Equal(t, "https://llm.example.com", status.AppOrigin)
+	assert.Equal(t, RunningModeEdge, status.Mode)
+	assert.GreaterOrEqual(t, status.Uptime, int64(60))
+	require.NotNil(t, status.Host)
+	assert.Positive(t, status.Host.CPUs)
+}
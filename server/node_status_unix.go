@@ -0,0 +1,15 @@
+//go:build !windows
+
+package server
+
+import "syscall"
+
+// diskUsage returns the size and the space available to the node of the filesystem of the path
+func diskUsage(path string) (uint64, uint64) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, 0
+	}
+
+	return uint64(stat.Blocks) * uint64(stat.Bsize), uint64(stat.Bavail) * uint64(stat.Bsize)
+}
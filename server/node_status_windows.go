@@ -0,0 +1,8 @@
+//go:build windows
+
+package server
+
+// diskUsage is not reported on windows
+func diskUsage(path string) (uint64, uint64) {
+	return 0, 0
+}
@@ -27,6 +27,9 @@ const (
 	System_PeersStatus_FullMethodName      = "/v1.System/PeersStatus"
 	System_RelayStatus_FullMethodName      = "/v1.System/RelayStatus"
 	System_RelayConnections_FullMethodName = "/v1.System/RelayConnections"
+	System_ProxyPolicy_FullMethodName      = "/v1.System/ProxyPolicy"
+	System_ProxyBreakers_FullMethodName    = "/v1.System/ProxyBreakers"
+	System_UsageReport_FullMethodName      = "/v1.System/UsageReport"
 	System_Subscribe_FullMethodName        = "/v1.System/Subscribe"
 	System_BlockByNumber_FullMethodName    = "/v1.System/BlockByNumber"
 	System_Export_FullMethodName           = "/v1.System/Export"
@@ -50,6 +53,12 @@ type SystemClient interface {
 	RelayStatus(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*Peer, error)
 	// RelayConnection returns the info of connections
 	RelayConnections(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*RelayConnectionsCount, error)
+	// ProxyPolicy returns the active NodeID policy of the transparent proxy
+	ProxyPolicy(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ProxyPolicyResponse, error)
+	// ProxyBreakers returns the circuit breakers of the nodes which are open or have failures
+	ProxyBreakers(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ProxyBreakersResponse, error)
+	// UsageReport aggregates the usage records of the transparent proxy by window, key and node
+	UsageReport(ctx context.Context, in *UsageReportRequest, opts ...grpc.CallOption) (*UsageReportResponse, error)
 	// Subscribe subscribes to blockchain events
 	Subscribe(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (System_SubscribeClient, error)
 	// Export returns blockchain data
@@ -129,6 +138,33 @@ func (c *systemClient) RelayConnections(ctx context.Context, in *emptypb.Empty,
 	return out, nil
 }
 
+func (c *systemClient) ProxyPolicy(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ProxyPolicyResponse, error) {
+	out := new(ProxyPolicyResponse)
+	err := c.cc.Invoke(ctx, System_ProxyPolicy_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *systemClient) ProxyBreakers(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ProxyBreakersResponse, error) {
+	out := new(ProxyBreakersResponse)
+	err := c.cc.Invoke(ctx, System_ProxyBreakers_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *systemClient) UsageReport(ctx context.Context, in *UsageReportRequest, opts ...grpc.CallOption) (*UsageReportResponse, error) {
+	out := new(UsageReportResponse)
+	err := c.cc.Invoke(ctx, System_UsageReport_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *systemClient) Subscribe(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (System_SubscribeClient, error) {
 	stream, err := c.cc.NewStream(ctx, &System_ServiceDesc.Streams[0], System_Subscribe_FullMethodName, opts...)
 	if err != nil {
@@ -220,6 +256,12 @@ type SystemServer interface {
 	RelayStatus(context.Context, *emptypb.Empty) (*Peer, error)
 	// RelayConnection returns the info of connections
 	RelayConnections(context.Context, *emptypb.Empty) (*RelayConnectionsCount, error)
+	// ProxyPolicy returns the active NodeID policy of the transparent proxy
+	ProxyPolicy(context.Context, *emptypb.Empty) (*ProxyPolicyResponse, error)
+	// ProxyBreakers returns the circuit breakers of the nodes which are open or have failures
+	ProxyBreakers(context.Context, *emptypb.Empty) (*ProxyBreakersResponse, error)
+	// UsageReport aggregates the usage records of the transparent proxy by window, key and node
+	UsageReport(context.Context, *UsageReportRequest) (*UsageReportResponse, error)
 	// Subscribe subscribes to blockchain events
 	Subscribe(*emptypb.Empty, System_SubscribeServer) error
 	// Export returns blockchain data
@@ -254,6 +296,15 @@ func (UnimplementedSystemServer) RelayStatus(context.Context, *emptypb.Empty) (*
 func (UnimplementedSystemServer) RelayConnections(context.Context, *emptypb.Empty) (*RelayConnectionsCount, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RelayConnections not implemented")
 }
+func (UnimplementedSystemServer) ProxyPolicy(context.Context, *emptypb.Empty) (*ProxyPolicyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ProxyPolicy not implemented")
+}
+func (UnimplementedSystemServer) ProxyBreakers(context.Context, *emptypb.Empty) (*ProxyBreakersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ProxyBreakers not implemented")
+}
+func (UnimplementedSystemServer) UsageReport(context.Context, *UsageReportRequest) (*UsageReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UsageReport not implemented")
+}
 func (UnimplementedSystemServer) Subscribe(*emptypb.Empty, System_SubscribeServer) error {
 	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
 }
@@ -402,6 +453,60 @@ func _System_RelayConnections_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _System_ProxyPolicy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SystemServer).ProxyPolicy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: System_ProxyPolicy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SystemServer).ProxyPolicy(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _System_ProxyBreakers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SystemServer).ProxyBreakers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: System_ProxyBreakers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SystemServer).ProxyBreakers(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _System_UsageReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UsageReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SystemServer).UsageReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: System_UsageReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SystemServer).UsageReport(ctx, req.(*UsageReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _System_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(emptypb.Empty)
 	if err := stream.RecvMsg(m); err != nil {
@@ -497,6 +602,18 @@ var System_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RelayConnections",
 			Handler:    _System_RelayConnections_Handler,
 		},
+		{
+			MethodName: "ProxyPolicy",
+			Handler:    _System_ProxyPolicy_Handler,
+		},
+		{
+			MethodName: "ProxyBreakers",
+			Handler:    _System_ProxyBreakers_Handler,
+		},
+		{
+			MethodName: "UsageReport",
+			Handler:    _System_UsageReport_Handler,
+		},
 		{
 			MethodName: "BlockByNumber",
 			Handler:    _System_BlockByNumber_Handler,
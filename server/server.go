@@ -1,7 +1,6 @@
 package server
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
 	appAgent "github.com/EdgeMatrixChain/edge-matrix-computing/agent"
@@ -9,6 +8,7 @@ import (
 	"github.com/EdgeMatrixChain/edge-matrix-computing/miner"
 	minerProto "github.com/EdgeMatrixChain/edge-matrix-computing/miner/proto"
 	"github.com/EdgeMatrixChain/edge-matrix-computing/proxy"
+	"github.com/EdgeMatrixChain/edge-matrix-computing/supervisor"
 	"github.com/EdgeMatrixChain/edge-matrix-computing/telepool"
 	"github.com/EdgeMatrixChain/edge-matrix-computing/versioning"
 	"github.com/EdgeMatrixChain/edge-matrix-core/core/application"
@@ -18,22 +18,24 @@ import (
 	"github.com/EdgeMatrixChain/edge-matrix-core/core/relay"
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/multiformats/go-multiaddr"
-	"io"
 	"net"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/EdgeMatrixChain/edge-matrix-computing/server/proto"
 	"github.com/EdgeMatrixChain/edge-matrix-core/core/helper/common"
 	"github.com/EdgeMatrixChain/edge-matrix-core/core/network"
 	"github.com/EdgeMatrixChain/edge-matrix-core/core/secrets"
+	"github.com/armon/go-metrics"
 	"github.com/hashicorp/go-hclog"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"google.golang.org/grpc"
 )
 
@@ -76,6 +78,9 @@ type Server struct {
 	// app peers syncer
 	appPeerSyncer application.Syncer
 
+	// the app peers known to the syncer, by NodeID and app name
+	appPeers *proxy.AppPeerIndex
+
 	// application syncer Client
 	syncAppPeerClient application.SyncAppPeerClient
 
@@ -92,12 +97,48 @@ type Server struct {
 	telepool *telepool.TelegramPool
 
 	// edge matrix auth agent
-	authAgent *appAgent.AuthAgent
+	authenticator appAgent.Authenticator
+
+	accessLogger *proxy.AccessLogger
+
+	tracerProvider *sdktrace.TracerProvider
+
+	// signs the responses of the transparent forward
+	responseSigner *proxy.ResponseSigner
+
+	// the services reachable through the transparent forward, any port of the app host if nil
+	upstreams *proxy.Upstreams
+
+	// checks the upstream services, nil if no health path is set
+	health *proxy.HealthChecker
+
+	// limits the requests to the app when no upstream service is declared, nil if unlimited
+	appLimiter *proxy.ConcurrencyLimiter
+
+	// runs the local app processes, nil if none is declared
+	supervisor *supervisor.Supervisor
+
+	// the requests forwarded to the upstream services
+	inFlight int64
+
+	// the app origin bound by the app agent
+	appOrigin atomic.Value
+
+	startedAt time.Time
+
+	// prometheus server
+	prometheusServer *http.Server
+
+	// dumps the in-memory metrics on SIGUSR1
+	metricsSignal *metrics.InmemSignal
+
+	closeCh chan struct{}
 }
 
+// AuthBearer returns the result of the authenticator and the apiToken sent to the node.
+// A rejected bearer or a failed check returns false, so the relay answers 401 instead of forwarding the request.
 func (s *Server) AuthBearer(bearer string, nodeId string, port int) (bool, string) {
-	//TODO implement me
-	ok, apiKey, err := s.authAgent.AuthBearer(bearer, nodeId, port)
+	ok, apiKey, err := s.authenticator.AuthBearer(bearer, nodeId, port)
 	if err != nil {
 		s.logger.Error("AuthBearer failed", "err", err.Error())
 
@@ -107,11 +148,11 @@ func (s *Server) AuthBearer(bearer string, nodeId string, port int) (bool, strin
 		s.logger.Warn("AuthBearer failed", "result", ok)
 	}
 
-	return true, apiKey
+	return ok, apiKey
 }
 
 func (s *Server) ValidateBearer(bearer string) bool {
-	result, err := s.appAgent.ValidateApiKey(bearer)
+	result, err := s.authenticator.ValidateBearer(bearer)
 	if err != nil {
 		return false
 	}
@@ -120,7 +161,42 @@ func (s *Server) ValidateBearer(bearer string) bool {
 }
 
 func (s *Server) GetAppPeer(id string) *application.AppPeer {
-	return s.appPeerSyncer.GetAppPeer(id)
+	return s.appPeers.GetAppPeer(id)
+}
+
+// GetAppPeers returns the app peers advertising the app name, keyed by NodeID
+func (s *Server) GetAppPeers(appName string) map[string]*application.AppPeer {
+	return s.appPeers.GetAppPeers(appName)
+}
+
+// ListAppPeers returns all the app peers known to the relay, keyed by NodeID
+func (s *Server) ListAppPeers() map[string]*application.AppPeer {
+	return s.appPeers.ListAppPeers()
+}
+
+func (s *Server) ResolveAppPeerID(label string) (string, bool) {
+	return s.appPeers.ResolveNodeID(label)
+}
+
+// peerstoreNodeIDs returns the NodeIDs of the peers known to the relay and edge hosts,
+// they are the candidates of the app peer index
+func (s *Server) peerstoreNodeIDs() []string {
+	hosts := make([]host.Host, 0, 2)
+	if s.relayServer != nil {
+		hosts = append(hosts, s.relayServer.GetHost())
+	}
+	if s.edgeNetwork != nil {
+		hosts = append(hosts, s.edgeNetwork.GetHost())
+	}
+
+	nodeIDs := make([]string, 0)
+	for _, h := range hosts {
+		for _, id := range h.Peerstore().Peers() {
+			nodeIDs = append(nodeIDs, id.String())
+		}
+	}
+
+	return nodeIDs
 }
 
 func (s *Server) GetRelayHost() host.Host {
@@ -240,8 +316,43 @@ func getEdgePath(r *http.Request) *proxy.EdgePath {
 	return edgePath
 }
 
+// setupAuthenticator sets up the auth backend chosen in the config.
+// Only the hub is cached: the static keys and the JWTs are checked locally, and a cached entry
+// would outlive the expiry of the key or the token.
+func (s *Server) setupAuthenticator() error {
+	var authenticator appAgent.Authenticator
+
+	switch s.config.AuthBackend {
+	case "", appAgent.AuthBackendHub:
+		authenticator = appAgent.NewHubAuthenticator(appAgent.NewAuthAgent(s.config.AuthUrl), s.appAgent)
+
+		if s.config.AuthCache != nil && s.config.AuthCache.TTL > 0 {
+			authenticator = appAgent.NewAuthCache(authenticator, s.config.AuthCache)
+		}
+	case appAgent.AuthBackendStatic:
+		static, err := appAgent.NewStaticAuthenticator(s.config.AuthKeysFile)
+		if err != nil {
+			return err
+		}
+		authenticator = static
+	case appAgent.AuthBackendJWT:
+		jwtAuth, err := appAgent.NewJWTAuthenticator(s.config.AuthJWT)
+		if err != nil {
+			return err
+		}
+		authenticator = jwtAuth
+	default:
+		return fmt.Errorf("unknown auth backend '%s'", s.config.AuthBackend)
+	}
+
+	s.authenticator = authenticator
+	s.logger.Info("auth backend", "backend", s.config.AuthBackend)
+
+	return nil
+}
+
 // NewServer creates a new Minimal server, using the passed in configuration
-func NewServer(config *Config) (*Server, error) {
+func NewServer(config *Config) (_ *Server, err error) {
 	logger, logErr := newLoggerFromConfig(config)
 	if logErr != nil {
 		return nil, fmt.Errorf("could not setup new logger instance, %w", logErr)
@@ -252,7 +363,8 @@ func NewServer(config *Config) (*Server, error) {
 		config:     config,
 		grpcServer: grpc.NewServer(),
 		appAgent:   appAgent.NewAppAgent(fmt.Sprintf("%s:%d", config.AppUrl, config.AppPort)),
-		authAgent:  appAgent.NewAuthAgent(config.AuthUrl),
+		closeCh:    make(chan struct{}),
+		startedAt:  time.Now(),
 	}
 
 	m.logger.Info("Data dir", "path", config.DataDir)
@@ -262,6 +374,32 @@ func NewServer(config *Config) (*Server, error) {
 		return nil, fmt.Errorf("failed to create data directories: %w", err)
 	}
 
+	// the metrics, the spans and the access log are stopped if the server fails to start
+	defer func() {
+		if err != nil {
+			m.closeMetrics()
+			m.closeDataDogProfiler()
+			m.closeTracing()
+			_ = m.accessLogger.Close()
+		}
+	}()
+
+	// start telemetry
+	if err := m.setupTelemetry(); err != nil {
+		return nil, fmt.Errorf("failed to set up telemetry: %w", err)
+	}
+
+	if config.Telemetry.PrometheusAddr != nil {
+		m.prometheusServer = m.startPrometheusServer(config.Telemetry.PrometheusAddr)
+	}
+
+	go m.collectMetrics()
+
+	// Set up OpenTelemetry tracing
+	if err := m.setupTracing(); err != nil {
+		return nil, fmt.Errorf("failed to set up tracing: %w", err)
+	}
+
 	// Set up datadog profiler
 	if err := m.enableDataDogProfiler(); err != nil {
 		m.logger.Error("DataDog profiler setup failed", "err", err.Error())
@@ -272,6 +410,34 @@ func NewServer(config *Config) (*Server, error) {
 		return nil, fmt.Errorf("failed to set up the secrets manager: %w", err)
 	}
 
+	// Set up the access log
+	accessLogger, err := proxy.NewAccessLogger(config.AccessLog)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up the access log: %w", err)
+	}
+	m.accessLogger = accessLogger
+
+	// Set up the auth backend
+	if err := m.setupAuthenticator(); err != nil {
+		return nil, fmt.Errorf("failed to set up the auth backend: %w", err)
+	}
+
+	// Set up the local app processes, they are launched once the edge application is set up
+	if len(m.config.Apps) > 0 {
+		appSupervisor, supervisorErr := supervisor.NewSupervisor(logger, m.config.Apps)
+		if supervisorErr != nil {
+			return nil, fmt.Errorf("failed to set up the app processes: %w", supervisorErr)
+		}
+		m.supervisor = appSupervisor
+
+		// the app processes are stopped if the server fails to start
+		defer func() {
+			if err != nil {
+				m.supervisor.Close()
+			}
+		}()
+	}
+
 	var endpointHost host.Host
 
 	if m.config.RunningMode == cmdConfig.DefaultRunningMode {
@@ -336,6 +502,33 @@ func NewServer(config *Config) (*Server, error) {
 
 		endpoint.SetSigner(proof.NewEIP155Signer(crypto.AllForksEnabled.At(0), uint64(m.config.GenesisConfig.NetworkId)))
 
+		responseSigner, signerErr := proxy.NewResponseSigner(endpointHost)
+		if signerErr != nil {
+			return nil, fmt.Errorf("failed to create the response signer: %w", signerErr)
+		}
+		m.responseSigner = responseSigner
+
+		if len(m.config.Upstreams) > 0 {
+			upstreams, upstreamsErr := proxy.NewUpstreams(m.config.Upstreams)
+			if upstreamsErr != nil {
+				return nil, upstreamsErr
+			}
+			m.upstreams = upstreams
+			m.logger.Info("upstream services declared", "services", strings.Join(upstreams.Names(), ","))
+		}
+
+		if m.upstreams == nil && m.config.Concurrency != nil && m.config.Concurrency.MaxConcurrent > 0 {
+			m.appLimiter = proxy.NewConcurrencyLimiter(legacyUpstreamName, m.config.Concurrency)
+		}
+
+		// the apps are checked once they are ready, so the node is not announced down on start
+		if m.supervisor != nil {
+			m.supervisor.Start()
+			m.supervisor.WaitReady()
+		}
+
+		m.setupHealthChecker()
+
 		// bind app agent
 		if !m.config.AppNoAgent {
 			if err := m.doAppNodeBind(endpointHost.ID().String()); err != nil {
@@ -346,14 +539,12 @@ func NewServer(config *Config) (*Server, error) {
 			if appOriginErr != nil {
 				m.logger.Error("getAppOrigin", "err", appOriginErr.Error())
 			}
-			endpoint.SetAppOrigin(appOrigin)
+			m.setBoundAppOrigin(endpoint, appOrigin)
 		}
 
-		endpoint.AddHandler("/alive", func(w http.ResponseWriter, r *http.Request) {
-			defer r.Body.Close()
-			resp := fmt.Sprintf("{\"time\":\"%s\"}", time.Now().String())
-			w.Write([]byte(resp))
-		})
+		endpoint.AddHandler("/alive", m.aliveHandler(func(w http.ResponseWriter, data []byte) {
+			application.WriteSignedResponse(w, data, endpoint)
+		}))
 
 		endpoint.AddHandler("/idl", func(w http.ResponseWriter, r *http.Request) {
 			defer r.Body.Close()
@@ -382,90 +573,7 @@ func NewServer(config *Config) (*Server, error) {
 
 		})
 
-		endpoint.AddHandler(proxy.TransparentForwardUrl, func(w http.ResponseWriter, r *http.Request) {
-			m.logger.Debug(proxy.TransparentForwardUrl, "RemoteAddr", r.RemoteAddr, "Host", r.Host)
-
-			if !m.config.AppNoAuth && !m.ValidateBearer(getBearer(r)) {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
-
-				return
-			}
-
-			defer r.Body.Close()
-
-			//m.logger.Debug(proxy.TransparentForwardUrl, "body", string(body))
-			edgePath := getEdgePath(r)
-
-			client := &http.Client{}
-			var targetURL = ""
-			if m.config.AppNoAgent {
-				targetURL = fmt.Sprintf("%s:%d/%s", m.config.AppUrl, edgePath.Port, edgePath.InterfaceURL)
-			} else {
-				err, proxyPath := m.appAgent.GetProxyPath()
-				if err != nil {
-					http.Error(w, fmt.Sprintf("%s %s", proxy.TransparentForwardUrl, err.Error()), http.StatusServiceUnavailable)
-
-					return
-				}
-				targetURL = fmt.Sprintf("%s:%d%s/%d/%s", m.config.AppUrl, m.config.AppPort, proxyPath, edgePath.Port, edgePath.InterfaceURL)
-			}
-			m.logger.Debug(proxy.TransparentForwardUrl, "targetURL", targetURL)
-
-			req, reqErr := http.NewRequest(r.Method, targetURL, r.Body)
-			if reqErr != nil {
-				http.Error(w, fmt.Sprintf("%s %s", proxy.TransparentForwardUrl, reqErr.Error()), http.StatusInternalServerError)
-
-				return
-			}
-
-			for key, values := range r.Header {
-				for _, value := range values {
-					req.Header.Add(key, value)
-					m.logger.Debug(proxy.TransparentForwardUrl, key, value)
-				}
-			}
-
-			resp, respErr := client.Do(req)
-			if respErr != nil {
-				http.Error(w, "Failed to connect to target server", http.StatusBadGateway)
-
-				return
-			}
-			defer resp.Body.Close()
-
-			for key, values := range resp.Header {
-				for _, value := range values {
-					w.Header().Add(key, value)
-				}
-			}
-			w.WriteHeader(resp.StatusCode)
-			if resp.Header.Get("Content-Type") == "text/event-stream" {
-				reader := bufio.NewReader(resp.Body)
-				for {
-					line, lineErr := reader.ReadBytes('\n')
-					if lineErr != nil {
-						if lineErr == io.EOF {
-
-							return
-						}
-						m.logger.Warn(proxy.TransparentForwardUrl, "err", fmt.Sprintf("Error reading SSE stream: %v\n", lineErr))
-
-						return
-					}
-
-					_, writeErr := w.Write(line)
-					if writeErr != nil {
-						m.logger.Warn(proxy.TransparentForwardUrl, "err", fmt.Sprintf("Error writing to client: %v\n", writeErr))
-
-						return
-					}
-
-					w.(http.Flusher).Flush()
-				}
-			} else {
-				io.Copy(w, resp.Body)
-			}
-		})
+		endpoint.AddHandler(proxy.TransparentForwardUrl, m.accessLogger.Handler("edge", proxy.TracingHandler("edge.forward", proxy.MetricsHandler("edge", http.HandlerFunc(m.handleTransparentForward)))).ServeHTTP)
 
 		if m.runningMode == RunningModeFull {
 			// setup app status syncer
@@ -484,6 +592,15 @@ func NewServer(config *Config) (*Server, error) {
 			}
 			m.appPeerSyncer = syncer
 
+			// a full node is announced by the app peer sync, the relays route away from its services which are down
+			if m.health != nil {
+				m.logger.Info("the node is announced while its upstream services are down, the requests to them are refused with a 503", "mode", m.runningMode)
+			}
+
+			// index the app peers, so the requests are routed without walking the peerstores
+			m.appPeers = proxy.NewAppPeerIndex(syncer, m.peerstoreNodeIDs)
+			m.appPeers.Start(proxy.DefaultAppPeersRefresh)
+
 			// Setup telegram pool
 			m.telepool = telepool.NewTelegramPool(
 				logger,
@@ -526,7 +643,7 @@ func NewServer(config *Config) (*Server, error) {
 			}
 		} else {
 			// keep edge peer alive
-			if err := m.relayClient.StartAlive(endpoint.SubscribeEvents()); err != nil {
+			if err := m.startAlive(relayAnnouncer(m.relayClient, endpoint)); err != nil {
 				return nil, err
 			}
 
@@ -546,7 +663,7 @@ func NewServer(config *Config) (*Server, error) {
 						if appOriginErr != nil {
 							m.logger.Error("getAppOrigin", "err", appOriginErr.Error())
 						}
-						endpoint.SetAppOrigin(appOrigin)
+						m.setBoundAppOrigin(endpoint, appOrigin)
 
 						m.logger.Info("binding", "NodeID", endpointHost.ID().String(), "AppOrigin", appOrigin)
 					}
@@ -572,6 +689,111 @@ func NewServer(config *Config) (*Server, error) {
 	return m, nil
 }
 
+// legacyUpstreamName is the name of the app at AppUrl when no upstream service is declared
+const legacyUpstreamName = "app"
+
+// setupHealthChecker starts the health checks of the upstream services if a health path is set
+func (s *Server) setupHealthChecker() {
+	if s.config.Health == nil {
+		return
+	}
+
+	var targets []*proxy.HealthTarget
+	if s.upstreams != nil {
+		targets = s.upstreams.HealthTargets(s.config.Health.Path)
+	} else if s.config.Health.Path != "" {
+		targets = []*proxy.HealthTarget{{
+			Name: legacyUpstreamName,
+			URL:  fmt.Sprintf("%s:%d/%s", s.config.AppUrl, s.config.AppPort, strings.TrimPrefix(s.config.Health.Path, "/")),
+		}}
+	}
+
+	if len(targets) == 0 {
+		return
+	}
+
+	s.health = proxy.NewHealthChecker(s.logger, s.config.Health, targets)
+	s.health.Start()
+	s.logger.Info("upstream health checks started", "targets", len(targets), "state", s.health.State())
+}
+
+// aliveRetryInterval is the interval between the attempts to resume an announcement which failed to start
+var aliveRetryInterval = 10 * time.Second
+
+// relayAnnouncer returns the announcements of the edge node to the relays. The relay client announces the node
+// with the events of the subscription it is given, until the subscription is closed, so each announcement has
+// its own subscription and is stopped by closing it.
+func relayAnnouncer(relayClient *relay.RelayClient, endpoint *application.Endpoint) func() (func(), error) {
+	return func() (func(), error) {
+		subscription := endpoint.SubscribeEvents()
+		if err := relayClient.StartAlive(subscription); err != nil {
+			subscription.Close()
+
+			return nil, err
+		}
+
+		return subscription.Close, nil
+	}
+}
+
+// startAlive announces the edge node to the relays while its upstream services are not all down.
+// The announcement is stopped when they go down, so the relays drop the node, and resumed when they are up.
+// A resumed announcement which fails to start is retried every aliveRetryInterval.
+func (s *Server) startAlive(announce func() (func(), error)) error {
+	// stop stops the current announcement, nil while it is stopped
+	var stop func()
+
+	if s.health.State() != proxy.HealthDown {
+		var err error
+		if stop, err = announce(); err != nil {
+			return err
+		}
+	} else {
+		s.logger.Warn("the upstream services are down, the node is announced once they are up")
+	}
+
+	if s.health == nil {
+		return nil
+	}
+
+	retryInterval := aliveRetryInterval
+
+	go func() {
+		retry := time.NewTicker(retryInterval)
+		defer retry.Stop()
+
+		for {
+			select {
+			case <-s.health.Changed():
+			case <-retry.C:
+				if stop != nil || s.health.State() == proxy.HealthDown {
+					continue
+				}
+			case <-s.closeCh:
+				return
+			}
+
+			state := s.health.State()
+
+			switch {
+			case state == proxy.HealthDown && stop != nil:
+				s.logger.Warn("the upstream services are down, stop announcing the node")
+				stop()
+				stop = nil
+			case state != proxy.HealthDown && stop == nil:
+				s.logger.Info("the upstream services are up, announcing the node", "state", state)
+
+				var err error
+				if stop, err = announce(); err != nil {
+					s.logger.Error("StartAlive", "err", err.Error(), "retry", retryInterval)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
 // setupSecretsManager sets up the secrets manager
 func (s *Server) setupSecretsManager() error {
 	secretsManagerConfig := s.config.SecretsManager
@@ -651,6 +873,38 @@ func (s *Server) setupTransparentProxy() error {
 		NetworkName:              s.config.GenesisConfig.Name,
 		Version:                  versioning.Version,
 		AccessControlAllowOrigin: s.config.TransparentProxy.AccessControlAllowOrigin,
+		BalancePolicy:            s.config.TransparentProxy.BalancePolicy,
+		AppPathPrefix:            s.config.TransparentProxy.AppPathPrefix,
+		AppPorts:                 s.config.TransparentProxy.AppPorts,
+		Retry:                    s.config.TransparentProxy.Retry,
+		PolicyFile:               s.config.TransparentProxy.PolicyFile,
+		HostDomain:               s.config.TransparentProxy.HostDomain,
+		RateLimit:                s.config.TransparentProxy.RateLimit,
+		TLS:                      s.config.TransparentProxy.TLS,
+		Transport:                s.config.TransparentProxy.Transport,
+		StreamContentTypes:       s.config.TransparentProxy.StreamContentTypes,
+		OpenAI:                   s.config.TransparentProxy.OpenAI,
+		Breaker:                  s.config.TransparentProxy.Breaker,
+		AccessLogger:             s.accessLogger,
+	}
+
+	// quota counters are kept in the db directory of the data dir
+	if conf.RateLimit != nil {
+		conf.RateLimit.DBPath = filepath.Join(s.config.DataDir, "db", "ratelimit")
+	}
+
+	// so are the usage records
+	if s.config.TransparentProxy.UsageMetering {
+		conf.Usage = &proxy.UsageConfig{
+			DBPath:    filepath.Join(s.config.DataDir, "db", "usage"),
+			Retention: s.config.TransparentProxy.UsageRetention,
+		}
+	}
+
+	// and the async jobs, so they survive a restart
+	if s.config.TransparentProxy.Jobs != nil {
+		conf.Jobs = s.config.TransparentProxy.Jobs
+		conf.Jobs.DBPath = filepath.Join(s.config.DataDir, "db", "jobs")
 	}
 
 	srv, err := proxy.NewTransportProxy(s.logger, conf, s.config.AppNoAuth)
@@ -717,13 +971,43 @@ func (s *Server) Close() {
 		s.syncAppPeerClient.Close()
 	}
 
+	// stop the refresh of the app peer index
+	s.appPeers.Close()
+
 	// close the relayClient
 	if s.relayClient != nil {
 		s.relayClient.Close()
 	}
 
+	// close the transparent proxy
+	if s.edgeProxyServer != nil {
+		s.edgeProxyServer.Close()
+	}
+
+	// stop the health checks
+	s.health.Close()
+
+	// close the connections to the upstream services
+	if s.upstreams != nil {
+		s.upstreams.Close()
+	}
+
+	// stop the local app processes
+	s.supervisor.Close()
+
+	// close the access log
+	if err := s.accessLogger.Close(); err != nil {
+		s.logger.Error("failed to close access log", "err", err.Error())
+	}
+
+	// stop the metrics collector and the prometheus server
+	s.closeMetrics()
+
 	// Close DataDog profiler
 	s.closeDataDogProfiler()
+
+	// flush the spans
+	s.closeTracing()
 }
 
 // Entry is a consensus configuration entry
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"time"
@@ -11,11 +12,22 @@ import (
 	"gopkg.in/DataDog/dd-trace-go.v1/profiler"
 )
 
+const (
+	// relayMetrics is a prefix used for the relay metrics
+	relayMetrics = "relay"
+
+	// metricsCollectInterval is the interval between two reports of the collected gauges
+	metricsCollectInterval = 10 * time.Second
+)
+
 func (s *Server) setupTelemetry() error {
 	inm := metrics.NewInmemSink(10*time.Second, time.Minute)
-	metrics.DefaultInmemSignal(inm)
+	s.metricsSignal = metrics.DefaultInmemSignal(inm)
 
-	promSink, err := prometheus.NewPrometheusSink()
+	// the gauges are set when they change, so they must not expire
+	promSink, err := prometheus.NewPrometheusSinkFrom(prometheus.PrometheusOpts{
+		Expiration: 0,
+	})
 	if err != nil {
 		return err
 	}
@@ -29,6 +41,55 @@ func (s *Server) setupTelemetry() error {
 	return nil
 }
 
+// closeMetrics stops the metrics collector, the prometheus server and the dump of the in-memory metrics
+func (s *Server) closeMetrics() {
+	close(s.closeCh)
+
+	if s.prometheusServer != nil {
+		if err := s.prometheusServer.Shutdown(context.Background()); err != nil {
+			s.logger.Error("Prometheus server shutdown error", "err", err)
+		}
+	}
+
+	if s.metricsSignal != nil {
+		s.metricsSignal.Stop()
+	}
+}
+
+// collectMetrics periodically reports the gauges which are not updated by events:
+// the relay reservations and connections, and the number of app peers
+func (s *Server) collectMetrics() {
+	ticker := time.NewTicker(metricsCollectInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.closeCh:
+			return
+		}
+
+		if s.relayClient != nil {
+			reservations := 0
+			for _, relayPeer := range s.relayClient.RelayPeers() {
+				if relayPeer.Reservation != nil {
+					reservations++
+				}
+			}
+			metrics.SetGauge([]string{relayMetrics, "reservations"}, float32(reservations))
+		}
+
+		if s.relayServer != nil {
+			metrics.SetGauge([]string{relayMetrics, "connections"}, float32(len(s.relayServer.GetHost().Network().Peers())))
+			metrics.SetGauge([]string{relayMetrics, "max_reservations"}, float32(s.relayServer.MaxReservations))
+		}
+
+		if s.appPeerSyncer != nil {
+			metrics.SetGauge([]string{"app", "peers"}, float32(len(s.ListAppPeers())))
+		}
+	}
+}
+
 // enableDataDogProfiler enables DataDog profiler. Enable it by setting DD_ENABLE env var.
 // Additional parameters can be set with env vars (DD_) - https://docs.datadoghq.com/profiler/enabling/go/
 func (s *Server) enableDataDogProfiler() error {
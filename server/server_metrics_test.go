@@ -0,0 +1,97 @@
+package server
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EdgeMatrixChain/edge-matrix-computing/proxy"
+	"github.com/EdgeMatrixChain/edge-matrix-core/core/secrets"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// freeAddr returns a local address which is not listened on
+func freeAddr(t *testing.T) *net.TCPAddr {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	require.NoError(t, listener.Close())
+
+	return listener.Addr().(*net.TCPAddr)
+}
+
+// goroutineStacks returns the stacks of all the goroutines
+func goroutineStacks() string {
+	buf := make([]byte, 1<<20)
+
+	return string(buf[:runtime.Stack(buf, true)])
+}
+
+// assertStopped checks that the prometheus server and the metrics goroutines are stopped
+func assertStopped(t *testing.T, addr *net.TCPAddr) {
+	t.Helper()
+
+	assert.Eventually(t, func() bool {
+		listener, err := net.Listen("tcp", addr.String())
+		if err != nil {
+			return false
+		}
+		listener.Close()
+
+		stacks := goroutineStacks()
+
+		return !strings.Contains(stacks, "(*Server).collectMetrics") && !strings.Contains(stacks, "(*InmemSignal).run")
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestNewServerMetrics(t *testing.T) {
+	prometheusAddr := freeAddr(t)
+
+	// the server fails to start after the metrics are set up
+	_, err := NewServer(&Config{
+		DataDir:        t.TempDir(),
+		LogLevel:       hclog.Off,
+		Telemetry:      &Telemetry{PrometheusAddr: prometheusAddr},
+		SecretsManager: &secrets.SecretsManagerConfig{Type: "missing"},
+	})
+	require.ErrorContains(t, err, "failed to set up the secrets manager")
+
+	assertStopped(t, prometheusAddr)
+
+	// the metrics of the proxy are served by prometheus with the edge prefix
+	s := &Server{logger: hclog.NewNullLogger(), closeCh: make(chan struct{})}
+	s.prometheusServer = s.startPrometheusServer(prometheusAddr)
+
+	proxy.IncrAuthFailure("relay", "invalid")
+	proxy.MetricsHandler("edge", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).
+		ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, proxy.TransparentForwardUrl, nil))
+
+	var body string
+	require.Eventually(t, func() bool {
+		resp, err := http.Get("http://" + prometheusAddr.String() + "/metrics")
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+
+		data, _ := io.ReadAll(resp.Body)
+		body = string(data)
+
+		return resp.StatusCode == http.StatusOK
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Contains(t, body, `edge_proxy_auth_failures{hop="relay",reason="invalid"} 1`)
+	assert.Contains(t, body, `edge_proxy_requests{hop="edge",node_id="",status="200"} 1`)
+	assert.Contains(t, body, `edge_proxy_request_duration_seconds_count{hop="edge",node_id="",status="200"} 1`)
+
+	s.closeMetrics()
+	assertStopped(t, prometheusAddr)
+}
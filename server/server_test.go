@@ -0,0 +1,130 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/EdgeMatrixChain/edge-matrix-computing/proxy"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRelay records the announcements of the node, the next fail announcements fail to start
+type fakeRelay struct {
+	lock    sync.Mutex
+	active  bool
+	starts  int
+	stops   int
+	fail    int
+	overlap bool
+}
+
+func (f *fakeRelay) announce() (func(), error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.fail > 0 {
+		f.fail--
+
+		return nil, errors.New("no relay reachable")
+	}
+
+	// an announcement is only started once the previous one is stopped
+	f.overlap = f.overlap || f.active
+	f.active = true
+	f.starts++
+
+	return func() {
+		f.lock.Lock()
+		defer f.lock.Unlock()
+
+		f.active = false
+		f.stops++
+	}, nil
+}
+
+// waitAnnounced waits until the relay has the announcement state and counts
+func waitAnnounced(t *testing.T, f *fakeRelay, active bool, starts int, stops int) {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		f.lock.Lock()
+		defer f.lock.Unlock()
+
+		return f.active == active && f.starts == starts && f.stops == stops
+	}, 5*time.Second, 5*time.Millisecond)
+}
+
+func TestStartAliveFollowsHealth(t *testing.T) {
+	retryInterval := aliveRetryInterval
+	aliveRetryInterval = 20 * time.Millisecond
+	t.Cleanup(func() { aliveRetryInterval = retryInterval })
+
+	var up atomic.Bool
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer app.Close()
+
+	s := &Server{
+		logger:  hclog.NewNullLogger(),
+		closeCh: make(chan struct{}),
+		health: proxy.NewHealthChecker(hclog.NewNullLogger(),
+			&proxy.HealthConfig{Interval: 5 * time.Millisecond, Timeout: time.Second, Failures: 1},
+			[]*proxy.HealthTarget{{Name: legacyUpstreamName, URL: app.URL + "/healthz"}},
+		),
+	}
+	s.health.Start()
+	defer s.health.Close()
+	defer close(s.closeCh)
+
+	// the node is not announced while its services are down on start
+	relay := &fakeRelay{}
+	require.NoError(t, s.startAlive(relay.announce))
+	waitAnnounced(t, relay, false, 0, 0)
+
+	up.Store(true)
+	waitAnnounced(t, relay, true, 1, 0)
+
+	up.Store(false)
+	waitAnnounced(t, relay, false, 1, 1)
+
+	up.Store(true)
+	waitAnnounced(t, relay, true, 2, 1)
+
+	up.Store(false)
+	waitAnnounced(t, relay, false, 2, 2)
+
+	// a resumed announcement which fails to start is retried while the services are up
+	relay.lock.Lock()
+	relay.fail = 2
+	relay.lock.Unlock()
+
+	up.Store(true)
+	waitAnnounced(t, relay, true, 3, 2)
+
+	relay.lock.Lock()
+	defer relay.lock.Unlock()
+	assert.False(t, relay.overlap)
+	assert.Equal(t, 0, relay.fail)
+}
+
+func TestStartAliveWithoutHealth(t *testing.T) {
+	s := &Server{logger: hclog.NewNullLogger()}
+
+	relay := &fakeRelay{}
+	require.NoError(t, s.startAlive(relay.announce))
+	waitAnnounced(t, relay, true, 1, 0)
+
+	// the announcement fails the start of the node without health checks
+	relay = &fakeRelay{fail: 1}
+	assert.ErrorContains(t, s.startAlive(relay.announce), "no relay reachable")
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/EdgeMatrixChain/edge-matrix-computing/versioning"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const (
+	TracingExporterOTLP   = "otlp"
+	TracingExporterStdout = "stdout"
+)
+
+// tracingShutdownTimeout bounds the time spent flushing the spans on close
+const tracingShutdownTimeout = 5 * time.Second
+
+// setupTracing starts the OpenTelemetry tracer provider.
+// The W3C traceparent is propagated even if no exporter is set.
+func (s *Server) setupTracing() error {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	if s.config.Tracing == nil || s.config.Tracing.Exporter == "" {
+		return nil
+	}
+
+	exporter, err := newTracingExporter(s.config.Tracing)
+	if err != nil {
+		return err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(s.config.Tracing.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName("edge-matrix-computing"),
+			semconv.ServiceVersion(versioning.Version),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	s.tracerProvider = provider
+
+	s.logger.Info("tracing enabled", "exporter", s.config.Tracing.Exporter, "endpoint", s.config.Tracing.Endpoint)
+
+	return nil
+}
+
+// newTracingExporter returns the exporter of the spans of the config
+func newTracingExporter(config *Tracing) (sdktrace.SpanExporter, error) {
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+
+	switch config.Exporter {
+	case TracingExporterOTLP:
+		opts := []otlptracehttp.Option{}
+		if config.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(config.Endpoint))
+		}
+		if config.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+
+		exporter, err = otlptracehttp.New(context.Background(), opts...)
+	case TracingExporterStdout:
+		exporter, err = stdouttrace.New()
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter '%s'", config.Exporter)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not create tracing exporter: %w", err)
+	}
+
+	return exporter, nil
+}
+
+// closeTracing flushes the pending spans
+func (s *Server) closeTracing() {
+	if s.tracerProvider == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+	defer cancel()
+
+	if err := s.tracerProvider.Shutdown(ctx); err != nil {
+		s.logger.Error("failed to close tracing", "err", err.Error())
+	}
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/EdgeMatrixChain/edge-matrix-computing/proxy"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetupTracing(t *testing.T) {
+	s := &Server{logger: hclog.NewNullLogger(), config: &Config{}}
+
+	// the traceparent is propagated without an exporter
+	require.NoError(t, s.setupTracing())
+	assert.Nil(t, s.tracerProvider)
+	assert.Contains(t, otel.GetTextMapPropagator().Fields(), "traceparent")
+	s.closeTracing()
+
+	s.config.Tracing = &Tracing{Exporter: "zipkin"}
+	assert.ErrorContains(t, s.setupTracing(), "unknown tracing exporter 'zipkin'")
+	assert.Nil(t, s.tracerProvider)
+
+	for _, config := range []*Tracing{{Exporter: TracingExporterStdout}, {Exporter: TracingExporterOTLP, Endpoint: "localhost:4318", Insecure: true}} {
+		exporter, err := newTracingExporter(config)
+		require.NoError(t, err, config.Exporter)
+		require.NoError(t, exporter.Shutdown(context.Background()), config.Exporter)
+	}
+}
+
+func TestTransparentForwardTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var received string
+	webapp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("traceparent")
+	}))
+	defer webapp.Close()
+
+	webappURL, err := url.Parse(webapp.URL)
+	require.NoError(t, err)
+
+	s := &Server{
+		logger: hclog.NewNullLogger(),
+		config: &Config{
+			AppNoAuth:        true,
+			AppNoAgent:       true,
+			AppUrl:           "http://" + webappURL.Hostname(),
+			TransparentProxy: &TransparentProxyConfig{},
+		},
+	}
+
+	// the relay sends the traceparent of its forward span
+	ctx, relaySpan := otel.Tracer("relay").Start(context.Background(), "proxy.forward")
+	relaySpan.End()
+
+	r := httptest.NewRequest(http.MethodGet, proxy.TransparentForwardUrl, nil)
+	r.Header.Set("X-Forwarded-EdgePort", webappURL.Port())
+	r.Header.Set("X-Forwarded-Interface", "v1/chat")
+	proxy.InjectTraceContext(ctx, r.Header)
+
+	w := httptest.NewRecorder()
+	proxy.TracingHandler("edge.forward", http.HandlerFunc(s.handleTransparentForward)).ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var edgeSpan sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "edge.forward" {
+			edgeSpan = span
+		}
+	}
+	require.NotNil(t, edgeSpan)
+
+	// the edge span is a child of the relay span, and is the parent of the webapp request
+	assert.Equal(t, relaySpan.SpanContext().TraceID(), edgeSpan.SpanContext().TraceID())
+	assert.Equal(t, relaySpan.SpanContext().SpanID(), edgeSpan.Parent().SpanID())
+	assert.Equal(t, fmt.Sprintf("00-%s-%s-01", edgeSpan.SpanContext().TraceID(), edgeSpan.SpanContext().SpanID()), received)
+
+	for _, attribute := range edgeSpan.Attributes() {
+		if attribute.Key == "http.response.status_code" {
+			assert.Equal(t, strconv.Itoa(http.StatusOK), attribute.Value.Emit())
+		}
+	}
+}
@@ -3,6 +3,9 @@ package server
 import (
 	"context"
 	"fmt"
+	"time"
+
+	"github.com/EdgeMatrixChain/edge-matrix-computing/proxy"
 	"github.com/EdgeMatrixChain/edge-matrix-computing/server/proto"
 	"github.com/EdgeMatrixChain/edge-matrix-core/core/network/common"
 	"github.com/libp2p/go-libp2p/core/peer"
@@ -125,6 +128,124 @@ func (s *systemService) RelayConnections(
 	return resp, nil
 }
 
+// ProxyPolicy implements the 'relay policy' operator service
+func (s *systemService) ProxyPolicy(
+	ctx context.Context,
+	req *empty.Empty,
+) (*proto.ProxyPolicyResponse, error) {
+	resp := &proto.ProxyPolicyResponse{}
+
+	if s.server.edgeProxyServer == nil || s.server.edgeProxyServer.Policy() == nil {
+		return resp, nil
+	}
+
+	policy, file, loadedAt := s.server.edgeProxyServer.Policy().Policy()
+
+	resp.Enabled = true
+	resp.File = file
+	resp.Default = string(policy.Default)
+	resp.LoadedAt = loadedAt.Unix()
+
+	for _, rule := range policy.Rules {
+		ports := make([]int64, 0, len(rule.Ports))
+		for _, port := range rule.Ports {
+			ports = append(ports, int64(port))
+		}
+
+		resp.Rules = append(resp.Rules, &proto.ProxyPolicyRule{
+			Name:     rule.Name,
+			Action:   string(rule.Action),
+			NodeId:   rule.NodeID,
+			Ports:    ports,
+			Path:     rule.Path,
+			Identity: rule.Identity,
+		})
+	}
+
+	return resp, nil
+}
+
+// ProxyBreakers implements the 'relay breakers' operator service
+func (s *systemService) ProxyBreakers(
+	ctx context.Context,
+	req *empty.Empty,
+) (*proto.ProxyBreakersResponse, error) {
+	resp := &proto.ProxyBreakersResponse{}
+
+	if s.server.edgeProxyServer == nil || s.server.edgeProxyServer.Breakers() == nil {
+		return resp, nil
+	}
+
+	resp.Enabled = true
+
+	for _, status := range s.server.edgeProxyServer.Breakers().Status() {
+		breaker := &proto.ProxyBreaker{
+			NodeId:              status.NodeID,
+			State:               string(status.State),
+			ConsecutiveFailures: int64(status.ConsecutiveFailures),
+			Requests:            int64(status.Requests),
+			Failures:            int64(status.Failures),
+		}
+		if !status.OpenedAt.IsZero() {
+			breaker.OpenedAt = status.OpenedAt.Unix()
+			breaker.RetryAt = status.RetryAt.Unix()
+		}
+
+		resp.Breakers = append(resp.Breakers, breaker)
+	}
+
+	return resp, nil
+}
+
+// UsageReport implements the 'usage report' operator service
+func (s *systemService) UsageReport(
+	ctx context.Context,
+	req *proto.UsageReportRequest,
+) (*proto.UsageReportResponse, error) {
+	resp := &proto.UsageReportResponse{}
+
+	if s.server.edgeProxyServer == nil || s.server.edgeProxyServer.Usage() == nil {
+		return resp, nil
+	}
+
+	query := &proxy.UsageQuery{
+		Window: time.Duration(req.Window) * time.Second,
+		Key:    req.Key,
+		NodeID: req.NodeId,
+	}
+	if req.From != 0 {
+		query.From = time.Unix(req.From, 0)
+	}
+	if req.To != 0 {
+		query.To = time.Unix(req.To, 0)
+	}
+
+	report, err := s.server.edgeProxyServer.Usage().Report(query)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Enabled = true
+
+	for _, aggregate := range report {
+		resp.Aggregates = append(resp.Aggregates, &proto.UsageAggregate{
+			WindowStart:      aggregate.WindowStart.Unix(),
+			Key:              aggregate.Key,
+			NodeId:           aggregate.NodeID,
+			Requests:         aggregate.Requests,
+			Errors:           aggregate.Errors,
+			BytesIn:          aggregate.BytesIn,
+			BytesOut:         aggregate.BytesOut,
+			DurationMs:       aggregate.DurationMs,
+			TtfbMs:           aggregate.TTFBMs,
+			PromptTokens:     aggregate.PromptTokens,
+			CompletionTokens: aggregate.CompletionTokens,
+		})
+	}
+
+	return resp, nil
+}
+
 // PeersRelayList implements the 'peers relaylist' operator service
 func (s *systemService) PeersRelayList(
 	ctx context.Context,
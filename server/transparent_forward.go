@@ -0,0 +1,212 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+
+	"github.com/EdgeMatrixChain/edge-matrix-computing/proxy"
+)
+
+// handleTransparentForward forwards the request received from the relay proxy to the local webapp
+func (s *Server) handleTransparentForward(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debug(proxy.TransparentForwardUrl, "RequestID", proxy.RequestID(r), "RemoteAddr", r.RemoteAddr, "Host", r.Host)
+
+	edgePath := getEdgePath(r)
+	proxy.SetRequestTarget(r, edgePath.NodeID, edgePath.Port)
+
+	if !s.config.AppNoAuth && !s.ValidateBearer(getBearer(r)) {
+		proxy.IncrAuthFailure("edge", "invalid")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+
+		return
+	}
+
+	principal := r.Header.Get(proxy.ClientIdentityHeader)
+	if principal == "" {
+		principal = proxy.BearerPrincipal(getBearer(r))
+	}
+	proxy.SetRequestPrincipal(r, principal)
+
+	defer r.Body.Close()
+
+	//s.logger.Debug(proxy.TransparentForwardUrl, "body", string(body))
+
+	// the interface path is joined to the url of the webapp, it must not leave it
+	if proxy.HasDotDotSegment(edgePath.InterfaceURL) {
+		http.Error(w, fmt.Sprintf("%s %s", proxy.TransparentForwardUrl, proxy.ErrPathTraversal.Error()), http.StatusBadRequest)
+
+		return
+	}
+
+	// requests routed by app name are sent to the default port of the app
+	if edgePath.Port == 0 {
+		edgePath.Port = int(s.config.AppPort)
+	}
+
+	client := &http.Client{
+		// redirects are passed back to the caller
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	var targetURL string
+
+	// only the declared services are reachable when the service map is set
+	upstream, err := s.resolveUpstream(edgePath)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%s %s", proxy.TransparentForwardUrl, err.Error()), http.StatusForbidden)
+
+		return
+	}
+
+	// the relays route away from a node whose service is down
+	upstreamName, limiter := legacyUpstreamName, s.appLimiter
+	if upstream != nil {
+		upstreamName, limiter = upstream.Name(), upstream.Limiter()
+	}
+	if s.health.IsDown(upstreamName) {
+		w.Header().Set(proxy.EdgeHealthHeader, string(proxy.HealthDown))
+		http.Error(w, fmt.Sprintf("%s the upstream service is down", proxy.TransparentForwardUrl), http.StatusServiceUnavailable)
+
+		return
+	}
+
+	// the requests over the concurrency limit of the service wait for a slot in its queue
+	release, position, err := limiter.Acquire(r.Context())
+	if err != nil {
+		s.logger.Warn(proxy.TransparentForwardUrl, "RequestID", proxy.RequestID(r), "upstream", upstreamName, "position", position, "err", err.Error())
+		limiter.WriteRejection(w, position, err)
+
+		return
+	}
+	// a websocket session releases its slot once the backend answered the handshake
+	release = sync.OnceFunc(release)
+	defer release()
+	limiter.SetLoadHeader(w.Header())
+
+	atomic.AddInt64(&s.inFlight, 1)
+	defer atomic.AddInt64(&s.inFlight, -1)
+
+	if upstream != nil {
+		targetURL = upstream.URL(edgePath.InterfaceURL, r.URL.RawQuery)
+		client = upstream.Client()
+	} else {
+		if targetURL, err = s.getForwardTargetURL(edgePath); err != nil {
+			http.Error(w, fmt.Sprintf("%s %s", proxy.TransparentForwardUrl, err.Error()), http.StatusServiceUnavailable)
+
+			return
+		}
+		if r.URL.RawQuery != "" {
+			targetURL += "?" + r.URL.RawQuery
+		}
+	}
+	s.logger.Debug(proxy.TransparentForwardUrl, "targetURL", targetURL)
+
+	if proxy.IsWebSocketUpgrade(r) {
+		s.forwardUpgrade(w, r, targetURL, upstream, release)
+
+		return
+	}
+
+	req, reqErr := http.NewRequest(r.Method, targetURL, r.Body)
+	if reqErr != nil {
+		http.Error(w, fmt.Sprintf("%s %s", proxy.TransparentForwardUrl, reqErr.Error()), http.StatusInternalServerError)
+
+		return
+	}
+	req.ContentLength = r.ContentLength
+	req.Trailer = r.Trailer
+
+	proxy.CopyHeader(req.Header, r.Header)
+	if upstream != nil {
+		upstream.SetHeaders(req.Header)
+	}
+	proxy.InjectTraceContext(r.Context(), req.Header)
+	for key, values := range req.Header {
+		for _, value := range values {
+			s.logger.Debug(proxy.TransparentForwardUrl, key, value)
+		}
+	}
+
+	resp, respErr := client.Do(req)
+	if respErr != nil {
+		http.Error(w, "Failed to connect to target server", http.StatusBadGateway)
+
+		return
+	}
+	defer resp.Body.Close()
+
+	if err := s.responseSigner.ForwardResponse(w, r, resp, s.config.TransparentProxy.StreamContentTypes); err != nil {
+		s.logger.Warn(proxy.TransparentForwardUrl, "RequestID", proxy.RequestID(r), "err", err.Error())
+	}
+}
+
+// resolveUpstream returns the declared service of the edge path, nil if there is no service map
+func (s *Server) resolveUpstream(edgePath *proxy.EdgePath) (*proxy.Upstream, error) {
+	if s.upstreams == nil {
+		return nil, nil
+	}
+
+	upstream, err := s.upstreams.Resolve(edgePath)
+	if err != nil {
+		s.logger.Warn(proxy.TransparentForwardUrl, "Port", edgePath.Port, "InterfaceURL", edgePath.InterfaceURL, "err", err.Error())
+
+		return nil, err
+	}
+
+	return upstream, nil
+}
+
+// getForwardTargetURL builds the url of the local webapp for the edge path
+func (s *Server) getForwardTargetURL(edgePath *proxy.EdgePath) (string, error) {
+	if s.config.AppNoAgent {
+		return fmt.Sprintf("%s:%d/%s", s.config.AppUrl, edgePath.Port, edgePath.InterfaceURL), nil
+	}
+
+	err, proxyPath := s.appAgent.GetProxyPath()
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s:%d%s/%d/%s", s.config.AppUrl, s.config.AppPort, proxyPath, edgePath.Port, edgePath.InterfaceURL), nil
+}
+
+// forwardUpgrade bridges a websocket session between the relay stream and the local webapp,
+// handshakeDone is called once the webapp answered the handshake
+func (s *Server) forwardUpgrade(w http.ResponseWriter, r *http.Request, targetURL string, upstream *proxy.Upstream, handshakeDone func()) {
+	target, err := url.Parse(targetURL)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%s %s", proxy.TransparentForwardUrl, err.Error()), http.StatusInternalServerError)
+
+		return
+	}
+
+	var backend net.Conn
+	if upstream != nil {
+		backend, err = upstream.DialUpgrade(r.Context())
+	} else {
+		backend, err = proxy.DialUpgradeBackend(r.Context(), target)
+	}
+	if err != nil {
+		http.Error(w, "Failed to connect to target server", http.StatusBadGateway)
+
+		return
+	}
+
+	outReq := r.Clone(r.Context())
+	outReq.URL = target
+	outReq.Host = target.Host
+	outReq.RequestURI = ""
+	if upstream != nil {
+		upstream.SetHeaders(outReq.Header)
+	}
+
+	if err := proxy.ServeUpgrade(w, outReq, backend, handshakeDone); err != nil {
+		s.logger.Warn(proxy.TransparentForwardUrl, "err", fmt.Sprintf("Error bridging websocket: %v", err))
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/EdgeMatrixChain/edge-matrix-computing/proxy"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransparentForwardUpstreams(t *testing.T) {
+	webapp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.URL.RequestURI()+" "+r.Header.Get("X-Token"))
+	}))
+	defer webapp.Close()
+
+	upstreams, err := proxy.NewUpstreams([]*proxy.UpstreamConfig{{
+		Name:         "llm",
+		Port:         9527,
+		Target:       webapp.URL + "/api",
+		PathPrefixes: []string{"/v1/"},
+		Headers:      map[string]string{"X-Token": "secret"},
+	}})
+	require.NoError(t, err)
+	defer upstreams.Close()
+
+	s := &Server{
+		logger: hclog.NewNullLogger(),
+		config: &Config{
+			AppNoAuth:        true,
+			AppNoAgent:       true,
+			AppUrl:           "http://127.0.0.1",
+			TransparentProxy: &TransparentProxyConfig{},
+		},
+		upstreams: upstreams,
+	}
+
+	tests := []struct {
+		name         string
+		port         string
+		interfaceURL string
+		query        string
+		code         int
+		body         string
+	}{
+		{"declared path", "9527", "v1/models", "limit=1", http.StatusOK, "/api/v1/models?limit=1 secret"},
+		{"undeclared port", "22", "v1/models", "", http.StatusForbidden, proxy.ErrUpstreamNotDeclared.Error()},
+		{"routed by app name without a port", "", "v1/models", "", http.StatusForbidden, proxy.ErrUpstreamNotDeclared.Error()},
+		{"path outside the prefixes", "9527", "admin", "", http.StatusForbidden, proxy.ErrUpstreamPathDenied.Error()},
+		{"dot-dot", "9527", "v1/../admin", "", http.StatusBadRequest, proxy.ErrPathTraversal.Error()},
+		{"encoded dot-dot", "9527", "v1/%2e%2e/admin", "", http.StatusBadRequest, proxy.ErrPathTraversal.Error()},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// the relay sends the query in the url of the forward
+			r := httptest.NewRequest(http.MethodGet, proxy.TransparentForwardUrl+"?"+test.query, nil)
+			r.Header.Set("X-Forwarded-EdgePort", test.port)
+			r.Header.Set("X-Forwarded-Interface", test.interfaceURL)
+
+			w := httptest.NewRecorder()
+			s.handleTransparentForward(w, r)
+
+			assert.Equal(t, test.code, w.Code)
+			assert.Contains(t, w.Body.String(), test.body)
+		})
+	}
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package supervisor
+
+import (
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// setProcessGroup starts the process in a new process group
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// interruptProcess kills the process tree, a console process of another group can't be interrupted on windows
+func interruptProcess(cmd *exec.Cmd) error {
+	return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+}
+
+// killProcessGroup does nothing on windows, the tree of an exited process can't be found
+func killProcessGroup(cmd *exec.Cmd) error {
+	return nil
+}
@@ -0,0 +1,402 @@
+package supervisor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/go-hclog"
+)
+
+type ProcessState string
+
+const (
+	ProcessStarting ProcessState = "starting"
+	ProcessReady    ProcessState = "ready"
+	ProcessExited   ProcessState = "exited"
+	ProcessStopped  ProcessState = "stopped"
+)
+
+const (
+	DefaultReadyTimeout = time.Minute
+	DefaultStopTimeout  = 10 * time.Second
+
+	// minBackoff is the delay before the first restart of a crashed process, doubled on each crash
+	minBackoff = time.Second
+	// maxBackoff is the maximum delay before a restart, a process running longer than it restarts after minBackoff
+	maxBackoff = time.Minute
+	// probeInterval is the interval of the readiness probes of a starting process
+	probeInterval = 500 * time.Millisecond
+	// maxLogLine is the longest line of output logged at once
+	maxLogLine = 16 << 10
+)
+
+// supervisorMetrics is a prefix used for the supervisor metrics
+const supervisorMetrics = "app"
+
+// ProcessConfig declares a local app process run by the edge node
+type ProcessConfig struct {
+	Name    string
+	Command string
+	Args    []string
+	// Env is added to the environment of the edge node, KEY=VALUE
+	Env []string
+	Dir string
+	// ReadyURL is probed until the process is ready: an http(s) url which responds with a 2xx or 3xx,
+	// or tcp://host:port which accepts connections. The process is ready once started if empty.
+	ReadyURL string
+	// ReadyTimeout is how long the edge node waits for the process to be ready on start
+	ReadyTimeout time.Duration
+	// StopTimeout is how long the process has to exit after an interrupt before it is killed
+	StopTimeout time.Duration
+}
+
+// ProcessStatus is the state of a supervised process
+type ProcessStatus struct {
+	Name      string       `json:"name"`
+	State     ProcessState `json:"state"`
+	Pid       int          `json:"pid,omitempty"`
+	Restarts  int          `json:"restarts"`
+	StartedAt time.Time    `json:"started_at"`
+	LastError string       `json:"last_error,omitempty"`
+}
+
+// process is a supervised app process, restarted with backoff when it exits
+type process struct {
+	logger hclog.Logger
+	config *ProcessConfig
+
+	lock   sync.Mutex
+	status ProcessStatus
+	// ready is closed the first time the process is ready
+	ready     chan struct{}
+	readyOnce sync.Once
+}
+
+// Supervisor launches the app processes of the edge node and keeps them running until it is closed
+type Supervisor struct {
+	logger    hclog.Logger
+	processes []*process
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewSupervisor(logger hclog.Logger, configs []*ProcessConfig) (*Supervisor, error) {
+	logger = logger.Named("supervisor")
+	names := make(map[string]bool, len(configs))
+	processes := make([]*process, 0, len(configs))
+
+	for _, config := range configs {
+		if config.Name == "" || config.Command == "" {
+			return nil, errors.New("an app process needs a name and a command")
+		}
+
+		if names[config.Name] {
+			return nil, fmt.Errorf("app process %s is declared twice", config.Name)
+		}
+		names[config.Name] = true
+
+		if config.ReadyURL != "" {
+			if _, err := url.Parse(config.ReadyURL); err != nil {
+				return nil, fmt.Errorf("app process %s: invalid ready url: %w", config.Name, err)
+			}
+		}
+
+		processes = append(processes, &process{
+			logger: logger.Named(config.Name),
+			config: config,
+			status: ProcessStatus{Name: config.Name, State: ProcessStopped},
+			ready:  make(chan struct{}),
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Supervisor{
+		logger:    logger,
+		processes: processes,
+		ctx:       ctx,
+		cancel:    cancel,
+	}, nil
+}
+
+// Start launches the processes
+func (s *Supervisor) Start() {
+	for _, p := range s.processes {
+		s.wg.Add(1)
+
+		go func(p *process) {
+			defer s.wg.Done()
+			p.run(s.ctx)
+		}(p)
+	}
+}
+
+// WaitReady waits for the processes to be ready, each at most its ready timeout.
+// The processes are waited for at once, so the node waits at most the longest ready timeout.
+func (s *Supervisor) WaitReady() {
+	var wg sync.WaitGroup
+
+	for _, p := range s.processes {
+		timeout := p.config.ReadyTimeout
+		if timeout <= 0 {
+			timeout = DefaultReadyTimeout
+		}
+
+		wg.Add(1)
+
+		go func(p *process, timeout time.Duration) {
+			defer wg.Done()
+
+			timer := time.NewTimer(timeout)
+			defer timer.Stop()
+
+			select {
+			case <-p.ready:
+				s.logger.Info("app process ready", "app", p.config.Name)
+			case <-timer.C:
+				s.logger.Warn("app process not ready", "app", p.config.Name, "timeout", timeout)
+			case <-s.ctx.Done():
+			}
+		}(p, timeout)
+	}
+
+	wg.Wait()
+}
+
+// Status returns the state of the processes, in the order of the config
+func (s *Supervisor) Status() []ProcessStatus {
+	if s == nil {
+		return nil
+	}
+
+	status := make([]ProcessStatus, 0, len(s.processes))
+	for _, p := range s.processes {
+		p.lock.Lock()
+		status = append(status, p.status)
+		p.lock.Unlock()
+	}
+
+	return status
+}
+
+// Close interrupts the processes and waits for them to exit, they are killed after their stop timeout
+func (s *Supervisor) Close() {
+	if s == nil {
+		return
+	}
+
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (p *process) setState(state ProcessState, change func(status *ProcessStatus)) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.status.State = state
+	if change != nil {
+		change(&p.status)
+	}
+}
+
+// restartBackoff returns the delay before the restart of a process which exited after running for ranFor,
+// the previous delay is doubled up to maxBackoff, it is minBackoff before the first restart
+func restartBackoff(previous time.Duration, ranFor time.Duration) time.Duration {
+	// a process which ran for a while is restarted quickly
+	if previous <= 0 || ranFor > maxBackoff {
+		return minBackoff
+	}
+
+	if previous*2 > maxBackoff {
+		return maxBackoff
+	}
+
+	return previous * 2
+}
+
+// run starts the process and restarts it with backoff until the context is done
+func (p *process) run(ctx context.Context) {
+	var backoff time.Duration
+
+	for {
+		startedAt := time.Now()
+		err := p.runOnce(ctx)
+
+		if ctx.Err() != nil {
+			p.setState(ProcessStopped, func(status *ProcessStatus) {
+				status.Pid = 0
+			})
+			p.logger.Info("app process stopped")
+
+			return
+		}
+
+		backoff = restartBackoff(backoff, time.Since(startedAt))
+
+		p.setState(ProcessExited, func(status *ProcessStatus) {
+			status.Pid = 0
+			status.Restarts++
+			if err != nil {
+				status.LastError = err.Error()
+			}
+		})
+		metrics.IncrCounterWithLabels([]string{supervisorMetrics, "restarts"}, 1, []metrics.Label{{Name: "app", Value: p.config.Name}})
+		p.logger.Warn("app process exited, restarting", "err", err, "backoff", backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			p.setState(ProcessStopped, nil)
+
+			return
+		}
+	}
+}
+
+// runOnce starts the process and waits for it to exit.
+// When the context is done, the process group is interrupted, then killed after the stop timeout.
+// The processes left in the group are killed once the process exited.
+func (p *process) runOnce(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, p.config.Command, p.config.Args...)
+	cmd.Dir = p.config.Dir
+	cmd.Env = append(os.Environ(), p.config.Env...)
+	cmd.Stdout = &logWriter{logger: p.logger, stream: "stdout"}
+	cmd.Stderr = &logWriter{logger: p.logger, stream: "stderr"}
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return interruptProcess(cmd)
+	}
+	cmd.WaitDelay = p.config.StopTimeout
+	if cmd.WaitDelay <= 0 {
+		cmd.WaitDelay = DefaultStopTimeout
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	p.setState(ProcessStarting, func(status *ProcessStatus) {
+		status.Pid = cmd.Process.Pid
+		status.StartedAt = time.Now().UTC()
+	})
+	p.logger.Info("app process started", "pid", cmd.Process.Pid, "command", p.config.Command)
+
+	probeCtx, cancelProbe := context.WithCancel(ctx)
+	defer cancelProbe()
+
+	go p.probe(probeCtx)
+
+	err := cmd.Wait()
+	cmd.Stdout.(*logWriter).flush()
+	cmd.Stderr.(*logWriter).flush()
+
+	if killErr := killProcessGroup(cmd); killErr != nil {
+		p.logger.Warn("failed to kill the process group", "err", killErr)
+	}
+
+	return err
+}
+
+// probe marks the process ready once its ready url responds
+func (p *process) probe(ctx context.Context) {
+	ticker := time.NewTicker(probeInterval)
+	defer ticker.Stop()
+
+	for {
+		if p.config.ReadyURL == "" || p.isReady(ctx) {
+			p.setState(ProcessReady, nil)
+			p.readyOnce.Do(func() { close(p.ready) })
+
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *process) isReady(ctx context.Context) bool {
+	target, err := url.Parse(p.config.ReadyURL)
+	if err != nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, probeInterval)
+	defer cancel()
+
+	if target.Scheme == "tcp" {
+		conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", target.Host)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+
+		return true
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.ReadyURL, nil)
+	if err != nil {
+		return false
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusBadRequest
+}
+
+// logWriter writes the output of a process to the logger, line by line
+type logWriter struct {
+	logger hclog.Logger
+	stream string
+	buf    bytes.Buffer
+}
+
+func (w *logWriter) Write(b []byte) (int, error) {
+	w.buf.Write(b)
+
+	for {
+		line, err := w.buf.ReadBytes('\n')
+		if err != nil {
+			// not a complete line yet, a long line is logged in parts
+			w.buf.Reset()
+			w.buf.Write(line)
+			if w.buf.Len() >= maxLogLine {
+				w.flush()
+			}
+
+			return len(b), nil
+		}
+
+		w.log(line)
+	}
+}
+
+func (w *logWriter) flush() {
+	if w.buf.Len() > 0 {
+		w.log(w.buf.Bytes())
+		w.buf.Reset()
+	}
+}
+
+func (w *logWriter) log(line []byte) {
+	w.logger.Info(string(bytes.TrimRight(line, "\r\n")), "stream", w.stream)
+}
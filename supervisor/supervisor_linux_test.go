@@ -0,0 +1,66 @@
+//go:build linux
+
+package supervisor
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// isRunning returns true if the process exists and is not a zombie
+func isRunning(pid int) bool {
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return false
+	}
+
+	// the state follows the command name in parentheses
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+
+	return len(fields) > 0 && fields[0] != "Z"
+}
+
+func TestSupervisorStopsProcessGroup(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+
+	// the background child of sh ignores the interrupt, it is killed with the process group
+	s, err := NewSupervisor(hclog.NewNullLogger(), []*ProcessConfig{{
+		Name:        "app",
+		Command:     "sh",
+		Args:        []string{"-c", "sleep 60 & echo $! > " + pidFile + "; wait"},
+		StopTimeout: time.Second,
+	}})
+	require.NoError(t, err)
+
+	s.Start()
+	s.WaitReady()
+
+	var childPid int
+
+	require.Eventually(t, func() bool {
+		content, err := os.ReadFile(pidFile)
+		if err != nil {
+			return false
+		}
+
+		childPid, err = strconv.Atoi(strings.TrimSpace(string(content)))
+
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	require.True(t, isRunning(childPid))
+
+	s.Close()
+
+	assert.Eventually(t, func() bool {
+		return !isRunning(childPid)
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, ProcessStopped, s.Status()[0].State)
+}
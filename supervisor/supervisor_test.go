@@ -0,0 +1,131 @@
+package supervisor
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestartBackoff(t *testing.T) {
+	tests := []struct {
+		previous time.Duration
+		ranFor   time.Duration
+		expected time.Duration
+	}{
+		// the first restart
+		{0, time.Millisecond, minBackoff},
+		// doubled on each crash
+		{time.Second, time.Millisecond, 2 * time.Second},
+		{16 * time.Second, 5 * time.Second, 32 * time.Second},
+		// up to the maximum
+		{32 * time.Second, time.Millisecond, maxBackoff},
+		{maxBackoff, time.Millisecond, maxBackoff},
+		// a process which ran for a while is restarted quickly
+		{maxBackoff, 2 * maxBackoff, minBackoff},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, restartBackoff(test.previous, test.ranFor), "previous %s, ran for %s", test.previous, test.ranFor)
+	}
+}
+
+func TestWaitReadyAtOnce(t *testing.T) {
+	s, err := NewSupervisor(hclog.NewNullLogger(), []*ProcessConfig{
+		{Name: "llm", Command: "llm", ReadyTimeout: 300 * time.Millisecond},
+		{Name: "embeddings", Command: "embeddings", ReadyTimeout: 300 * time.Millisecond},
+		{Name: "tts", Command: "tts", ReadyTimeout: 300 * time.Millisecond},
+		{Name: "ready", Command: "ready", ReadyTimeout: time.Hour},
+	})
+	require.NoError(t, err)
+
+	// the processes are not started, only the last one is ready
+	close(s.processes[3].ready)
+
+	started := time.Now()
+	s.WaitReady()
+
+	// the node waits for the longest ready timeout, not for the sum of them
+	elapsed := time.Since(started)
+	assert.GreaterOrEqual(t, elapsed, 300*time.Millisecond)
+	assert.Less(t, elapsed, 600*time.Millisecond)
+
+	// the wait ends when the supervisor is closed
+	s.processes[3].ready = make(chan struct{})
+	s.Close()
+
+	started = time.Now()
+	s.WaitReady()
+	assert.Less(t, time.Since(started), 300*time.Millisecond)
+}
+
+// newTestLogWriter returns a logWriter and the logged lines with their stream
+func newTestLogWriter(t *testing.T) (*logWriter, func() [][2]string) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	logger := hclog.New(&hclog.LoggerOptions{Output: &buf, JSONFormat: true})
+
+	lines := func() [][2]string {
+		logged := make([][2]string, 0)
+		for _, entry := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if entry == "" {
+				continue
+			}
+
+			fields := map[string]interface{}{}
+			require.NoError(t, json.Unmarshal([]byte(entry), &fields))
+			logged = append(logged, [2]string{fields["@message"].(string), fields["stream"].(string)})
+		}
+
+		return logged
+	}
+
+	return &logWriter{logger: logger, stream: "stdout"}, lines
+}
+
+func TestLogWriterLines(t *testing.T) {
+	w, lines := newTestLogWriter(t)
+
+	for _, chunk := range []string{"hel", "lo\nwor", "ld\r\n", "one\ntwo\n", "partial"} {
+		n, err := w.Write([]byte(chunk))
+		require.NoError(t, err)
+		assert.Equal(t, len(chunk), n)
+	}
+
+	// the partial line is logged on flush
+	assert.Equal(t, [][2]string{{"hello", "stdout"}, {"world", "stdout"}, {"one", "stdout"}, {"two", "stdout"}}, lines())
+
+	w.flush()
+	assert.Equal(t, [2]string{"partial", "stdout"}, lines()[4])
+
+	// an empty flush logs nothing
+	w.flush()
+	assert.Len(t, lines(), 5)
+}
+
+func TestLogWriterLongLine(t *testing.T) {
+	w, lines := newTestLogWriter(t)
+
+	half := strings.Repeat("a", maxLogLine/2)
+	_, err := w.Write([]byte(half))
+	require.NoError(t, err)
+	assert.Empty(t, lines())
+
+	// a line without end is logged in parts of maxLogLine
+	_, err = w.Write([]byte(half))
+	require.NoError(t, err)
+
+	_, err = w.Write([]byte("bc\n"))
+	require.NoError(t, err)
+
+	logged := lines()
+	require.Len(t, logged, 2)
+	assert.True(t, logged[0][0] == half+half, "the first part is the %d bytes of the line", maxLogLine)
+	assert.Equal(t, "bc", logged[1][0])
+}
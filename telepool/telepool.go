@@ -1,6 +1,7 @@
 package telepool
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -8,10 +9,15 @@ import (
 	"github.com/EdgeMatrixChain/edge-matrix-core/core/application/proof"
 	"github.com/EdgeMatrixChain/edge-matrix-core/core/network"
 	"github.com/EdgeMatrixChain/edge-matrix-core/core/types"
+	"github.com/armon/go-metrics"
 	"github.com/hashicorp/go-hclog"
 	"github.com/libp2p/go-libp2p/core/host"
 	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/libp2p/go-libp2p/core/peerstore"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // indicates origin of a transaction
@@ -30,6 +36,8 @@ var (
 	ErrOversizedData    = errors.New("oversized data")
 )
 
+var tracer = otel.Tracer("github.com/EdgeMatrixChain/edge-matrix-computing/telepool")
+
 // EdgeCallPrecompile is and address of edge call precompile
 var EdgeCallPrecompile = types.StringToAddress("0x3001")
 
@@ -145,12 +153,37 @@ func NewTelegramPool(
 		shutdownCh:   make(chan struct{}),
 	}
 
+	metrics.SetGauge([]string{txPoolMetrics, "slots_max"}, float32(config.MaxSlots))
+
 	return pool
 }
 
 // AddTele adds a new telegram to the pool (sent from json-RPC/gRPC endpoints)
 // and broadcasts it to the network (if enabled).
 func (p *TelegramPool) AddTele(tele *types.Telegram) (string, error) {
+	ctx, span := tracer.Start(context.Background(), "telepool.AddTele")
+	defer span.End()
+
+	// the slots are held while the edge call is in flight
+	slots := slotsRequired(tele)
+	p.gauge.increase(slots)
+	metrics.SetGauge([]string{txPoolMetrics, "slots_used"}, float32(p.gauge.read()))
+
+	defer func() {
+		p.gauge.decrease(slots)
+		metrics.SetGauge([]string{txPoolMetrics, "slots_used"}, float32(p.gauge.read()))
+	}()
+
+	respString, err := p.addTele(ctx, tele)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return respString, err
+}
+
+func (p *TelegramPool) addTele(ctx context.Context, tele *types.Telegram) (string, error) {
 	resp := &proof.EdgeResponse{}
 	if tele.To != nil && *tele.To == EdgeCallPrecompile {
 		input := tele.Input
@@ -158,6 +191,10 @@ func (p *TelegramPool) AddTele(tele *types.Telegram) (string, error) {
 		if err := json.Unmarshal(input, &call); err != nil {
 			return "", err
 		}
+		trace.SpanFromContext(ctx).SetAttributes(
+			attribute.String("edge.node_id", call.PeerId),
+			attribute.String("edge.endpoint", call.Endpoint),
+		)
 		relayHost := p.store.GetRelayHost()
 
 		relayAddr, addr := p.getAppPeerAddr(call.PeerId)
@@ -169,7 +206,13 @@ func (p *TelegramPool) AddTele(tele *types.Telegram) (string, error) {
 			}
 		}
 
+		_, callSpan := tracer.Start(ctx, "libp2p.call", trace.WithSpanKind(trace.SpanKindClient))
 		respBuf, callErr := application.Call(relayHost, application.ProtoTagEcApp, call)
+		if callErr != nil {
+			callSpan.RecordError(callErr)
+			callSpan.SetStatus(codes.Error, callErr.Error())
+		}
+		callSpan.End()
 		if callErr != nil {
 			return "", callErr
 		}